@@ -0,0 +1,409 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// Event types
+const (
+	EventRunStart = "run_start"
+	EventPull     = "pull"
+	EventPush     = "push"
+	EventRunEnd   = "run_end"
+)
+
+// Outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied" // Rejected by the image policy before pulling
+)
+
+// genesisHash is the previous hash of the first entry in a log
+var genesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// maxLineSize bounds a single audit entry when reading a log
+const maxLineSize = 1024 * 1024
+
+// Entry is one record of the audit log. Hash covers every other field,
+// including PrevHash, so each entry commits to the whole log before it.
+type Entry struct {
+	Seq        int64     `json:"seq"`
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	RunID      string    `json:"run_id"`
+	User       string    `json:"user,omitempty"`
+	Host       string    `json:"host,omitempty"`
+	ConfigHash string    `json:"config_hash,omitempty"` // sha256 of the effective configuration
+	Image      string    `json:"image,omitempty"`
+	Canonical  string    `json:"canonical_image,omitempty"`
+	Digest     string    `json:"digest,omitempty"`
+	Registry   string    `json:"registry,omitempty"` // Registry API endpoint the image came from or went to
+	Outcome    string    `json:"outcome,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Warmup     bool      `json:"warmup,omitempty"` // Unmeasured benchmark pull
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+// Head records the last entry of a log. It is kept next to the log so
+// removing entries from the end is detected.
+type Head struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// HeadFile returns the name of the head file belonging to a log
+func HeadFile(filename string) string {
+	return filename + ".head"
+}
+
+// LockFile returns the name of the file locked by the run appending to a log
+func LockFile(filename string) string {
+	return filename + ".lock"
+}
+
+// Log appends hash-chained entries to an audit log file
+type Log struct {
+	filename string
+	runID    string
+	user     string
+	host     string
+	head     Head
+	lock     *os.File
+	err      error // First write error; the chain cannot be continued after it
+}
+
+// Open locks an audit log for the run, verifies it and prepares to append to it.
+// A new log is started when neither the log nor its head file exist. A head left
+// one entry behind by an interrupted Append is repaired. The lock is held until Close.
+func Open(filename string) (*Log, error) {
+	lock, err := security.SecureOpenFileRW(LockFile(filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit lock: %w", err)
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("failed to lock audit log: %w", err)
+	}
+
+	head, behind, err := verify(filename)
+	if err == nil && behind {
+		err = writeHead(filename, head)
+	}
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	runID := make([]byte, 8)
+	if _, err := rand.Read(runID); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("failed to generate run id: %w", err)
+	}
+
+	return &Log{
+		filename: filename,
+		runID:    hex.EncodeToString(runID),
+		user:     currentUser(),
+		host:     hostname(),
+		head:     head,
+		lock:     lock,
+	}, nil
+}
+
+// Close releases the lock of the log
+func (l *Log) Close() error {
+	return l.lock.Close()
+}
+
+// RunID returns the ID recorded with every entry of this run
+func (l *Log) RunID() string {
+	return l.runID
+}
+
+// Append chains entry to the log and writes it together with the new head.
+// Sequence, time, run, user, host and hashes are filled in by the log.
+// After a failed write every later Append returns the same error.
+func (l *Log) Append(entry Entry) error {
+	if l.err != nil {
+		return l.err
+	}
+	if err := l.append(entry); err != nil {
+		l.err = err
+		return err
+	}
+	return nil
+}
+
+// append chains and writes a single entry
+func (l *Log) append(entry Entry) error {
+	entry.Seq = l.head.Seq + 1
+	entry.Time = time.Now().UTC()
+	entry.StartedAt = entry.StartedAt.UTC()
+	entry.FinishedAt = entry.FinishedAt.UTC()
+	entry.RunID = l.runID
+	entry.User = l.user
+	entry.Host = l.host
+	entry.PrevHash = l.head.Hash
+	if entry.PrevHash == "" {
+		entry.PrevHash = genesisHash
+	}
+
+	hash, err := entryHash(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	if err := security.SecureAppendFile(l.filename, append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	head := Head{Seq: entry.Seq, Hash: entry.Hash}
+	if err := writeHead(l.filename, head); err != nil {
+		return err
+	}
+	l.head = head
+	return nil
+}
+
+// writeHead replaces the head file of a log
+func writeHead(filename string, head Head) error {
+	data, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit head: %w", err)
+	}
+	if err := security.WriteFileAtomic(HeadFile(filename), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit head: %w", err)
+	}
+	return nil
+}
+
+// Verify checks the hash chain of an audit log against its head file and
+// returns the head. Modified, reordered, inserted or removed entries are
+// reported as errors. A missing log and head is an empty, valid log.
+// A log one entry ahead of its head, as left by an interrupted Append,
+// is valid and its last entry is returned.
+func Verify(filename string) (Head, error) {
+	head, _, err := verify(filename)
+	return head, err
+}
+
+// verify checks an audit log and returns its last entry and whether the head file is one entry behind it
+func verify(filename string) (Head, bool, error) {
+	head, headErr := readHead(filename)
+	file, _, err := security.SecureOpenFile(filename)
+	if err != nil {
+		if errors.Is(headErr, fs.ErrNotExist) && !fileExists(filename) {
+			return Head{}, false, nil
+		}
+		return Head{}, false, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	missingHead := errors.Is(headErr, fs.ErrNotExist)
+	if headErr != nil && !missingHead {
+		return Head{}, false, fmt.Errorf("failed to read audit head: %w", headErr)
+	}
+	if missingHead {
+		// Only an Append interrupted before the first head was written leaves no head
+		head = Head{Hash: genesisHash}
+	}
+
+	last := Head{Hash: genesisHash}
+	var previous Head
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			return Head{}, false, fmt.Errorf("audit log entry %d: unexpected empty line", last.Seq+1)
+		}
+
+		var entry Entry
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&entry); err != nil {
+			return Head{}, false, fmt.Errorf("audit log entry %d: %w", last.Seq+1, err)
+		}
+
+		if entry.Seq != last.Seq+1 {
+			return Head{}, false, fmt.Errorf("audit log entry %d: sequence %d out of order", last.Seq+1, entry.Seq)
+		}
+		if entry.PrevHash != last.Hash {
+			return Head{}, false, fmt.Errorf("audit log entry %d: previous hash does not match", entry.Seq)
+		}
+		want, err := entryHash(entry)
+		if err != nil {
+			return Head{}, false, err
+		}
+		if entry.Hash != want {
+			return Head{}, false, fmt.Errorf("audit log entry %d: hash does not match its contents", entry.Seq)
+		}
+
+		previous, last = last, Head{Seq: entry.Seq, Hash: entry.Hash}
+	}
+	if err := scanner.Err(); err != nil {
+		return Head{}, false, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	switch {
+	case last == head:
+		return head, false, nil
+	case last.Seq == head.Seq+1 && previous == head:
+		return last, true, nil
+	case missingHead:
+		return Head{}, false, fmt.Errorf("failed to read audit head: %w", headErr)
+	}
+	return Head{}, false, fmt.Errorf("audit log ends at entry %d but head records entry %d: log was truncated or head was modified",
+		last.Seq, head.Seq)
+}
+
+// entryHash computes the hash of an entry with its Hash field cleared
+func entryHash(entry Entry) (string, error) {
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readHead loads the head file of a log
+func readHead(filename string) (Head, error) {
+	if !fileExists(HeadFile(filename)) {
+		return Head{}, fs.ErrNotExist
+	}
+
+	data, err := security.SecureReadFile(HeadFile(filename))
+	if err != nil {
+		return Head{}, err
+	}
+
+	var head Head
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&head); err != nil {
+		return Head{}, fmt.Errorf("invalid audit head: %w", err)
+	}
+	return head, nil
+}
+
+// fileExists reports whether filename exists, without following a final symlink
+func fileExists(filename string) bool {
+	_, err := os.Lstat(filename)
+	return err == nil
+}
+
+// HashConfig returns the sha256 of a value's JSON encoding, used to record the effective configuration
+func HashConfig(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// currentUser returns the name of the user running the tool
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "uid:" + strconv.Itoa(os.Getuid())
+}
+
+// hostname returns the host name, or an empty string if it is unavailable
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// RecordPull appends the pull entry of a result
+func (l *Log) RecordPull(result types.PullResult) error {
+	return l.recordPull(result, false)
+}
+
+// RecordWarmupPull appends the pull entry of a benchmark warmup iteration, marked as a warmup
+func (l *Log) RecordWarmupPull(result types.PullResult) error {
+	return l.recordPull(result, true)
+}
+
+// recordPull appends a pull entry
+func (l *Log) recordPull(result types.PullResult, warmup bool) error {
+	outcome := OutcomeFailure
+	if result.Success {
+		outcome = OutcomeSuccess
+	} else if len(result.PolicyViolations) > 0 && result.Attempts == 0 {
+		outcome = OutcomeDenied
+	}
+
+	return l.Append(Entry{
+		Event:      EventPull,
+		Image:      result.Image,
+		Canonical:  result.CanonicalImage,
+		Digest:     result.Digest,
+		Registry:   registry.ImageHost(result.CanonicalImage),
+		Outcome:    outcome,
+		Error:      result.Error,
+		Warmup:     warmup,
+		StartedAt:  result.StartedAt,
+		FinishedAt: result.FinishedAt,
+	})
+}
+
+// RecordPushes appends a push entry for every result where a push was attempted
+func (l *Log) RecordPushes(results []types.PullResult) error {
+	for _, result := range results {
+		if result.Push == nil {
+			continue
+		}
+		outcome := OutcomeFailure
+		if result.Push.Success {
+			outcome = OutcomeSuccess
+		}
+		err := l.Append(Entry{
+			Event:     EventPush,
+			Image:     result.Push.Target,
+			Canonical: result.CanonicalImage,
+			Digest:    result.Digest,
+			Registry:  registry.ImageHost(result.Push.Target),
+			Outcome:   outcome,
+			Error:     result.Push.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
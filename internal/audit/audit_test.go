@@ -0,0 +1,289 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// writeLog creates an audit log with a run of two pulls and returns its path
+func writeLog(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := security.SetPathPolicy(security.PathPolicy{AllowedRoots: []string{dir}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = security.SetPathPolicy(security.DefaultPathPolicy()) })
+
+	filename := filepath.Join(dir, "audit.log")
+	for run := 0; run < 2; run++ {
+		log, err := Open(filename)
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		if err := log.Append(Entry{Event: EventRunStart, ConfigHash: "sha256:abc"}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		results := []types.PullResult{
+			{Image: "nginx", CanonicalImage: "docker.io/library/nginx:latest", Success: true,
+				Digest: "sha256:" + strings.Repeat("a", 64), StartedAt: time.Now(), FinishedAt: time.Now()},
+			{Image: "ghcr.io/org/app:1", CanonicalImage: "ghcr.io/org/app:1", Error: "not found", Attempts: 4},
+		}
+		for _, result := range results {
+			if err := log.RecordPull(result); err != nil {
+				t.Fatalf("RecordPull() error = %v", err)
+			}
+		}
+		if err := log.RecordPushes(results); err != nil {
+			t.Fatalf("RecordPushes() error = %v", err)
+		}
+		if err := log.Append(Entry{Event: EventRunEnd, Outcome: OutcomeFailure}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		if err := log.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+	return filename
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(t *testing.T, filename string)
+		wantErr string
+	}{
+		{
+			name:   "untouched log",
+			tamper: func(t *testing.T, filename string) {},
+		},
+		{
+			name: "modified entry",
+			tamper: func(t *testing.T, filename string) {
+				rewrite(t, filename, func(lines []string) []string {
+					lines[1] = strings.Replace(lines[1], `"outcome":"success"`, `"outcome":"failure"`, 1)
+					return lines
+				})
+			},
+			wantErr: "hash does not match",
+		},
+		{
+			name: "removed entry",
+			tamper: func(t *testing.T, filename string) {
+				rewrite(t, filename, func(lines []string) []string {
+					return append(lines[:2], lines[3:]...)
+				})
+			},
+			wantErr: "out of order",
+		},
+		{
+			name: "truncated log",
+			tamper: func(t *testing.T, filename string) {
+				rewrite(t, filename, func(lines []string) []string {
+					return lines[:len(lines)-2]
+				})
+			},
+			wantErr: "truncated",
+		},
+		{
+			name: "removed head",
+			tamper: func(t *testing.T, filename string) {
+				if err := os.Remove(HeadFile(filename)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "audit head",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeLog(t)
+			tt.tamper(t, filename)
+
+			head, err := Verify(filename)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				if head.Seq != 8 {
+					t.Errorf("Verify() head seq = %d, want 8", head.Seq)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenRefusesTamperedLog(t *testing.T) {
+	filename := writeLog(t)
+	rewrite(t, filename, func(lines []string) []string {
+		return lines[:len(lines)-1]
+	})
+
+	if _, err := Open(filename); err == nil {
+		t.Error("Open() appended to a truncated log")
+	}
+}
+
+func TestOpenRecoversInterruptedAppend(t *testing.T) {
+	filename := writeLog(t)
+	full, err := Verify(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash after the last entry was written but before its head
+	previous := lineHead(t, filename, -2)
+	setHead(t, filename, previous)
+
+	head, err := Verify(filename)
+	if err != nil || head != full {
+		t.Fatalf("Verify() = %+v, %v; want %+v", head, err, full)
+	}
+
+	log, err := Open(filename)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := log.Append(Entry{Event: EventRunStart}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	log.Close()
+	if head, err := Verify(filename); err != nil || head.Seq != full.Seq+1 {
+		t.Errorf("Verify() after recovery = %+v, %v", head, err)
+	}
+
+	// Two entries ahead is not an interrupted append
+	setHead(t, filename, previous)
+	if _, err := Open(filename); err == nil || !strings.Contains(err.Error(), "head records entry") {
+		t.Errorf("Open() error = %v, want head mismatch", err)
+	}
+}
+
+func TestOpenRecoversMissingFirstHead(t *testing.T) {
+	filename := writeLog(t)
+	rewrite(t, filename, func(lines []string) []string { return lines[:1] })
+	if err := os.Remove(HeadFile(filename)); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := Open(filename)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	log.Close()
+	if head, err := Verify(filename); err != nil || head.Seq != 1 {
+		t.Errorf("Verify() = %+v, %v", head, err)
+	}
+}
+
+func TestOpenLocksLog(t *testing.T) {
+	filename := writeLog(t)
+
+	first, err := Open(filename)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := Open(filename); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("second Open() error = %v, want in use", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Open(filename)
+	if err != nil {
+		t.Fatalf("Open() after Close() error = %v", err)
+	}
+	second.Close()
+}
+
+func TestRecordWarmupPull(t *testing.T) {
+	filename := writeLog(t)
+
+	log, err := Open(filename)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	result := types.PullResult{Image: "nginx", CanonicalImage: "docker.io/library/nginx:latest", Success: true}
+	if err := log.RecordWarmupPull(result); err != nil {
+		t.Fatalf("RecordWarmupPull() error = %v", err)
+	}
+	if err := log.RecordPull(result); err != nil {
+		t.Fatalf("RecordPull() error = %v", err)
+	}
+	log.Close()
+
+	if _, err := Verify(filename); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, want := range map[int]bool{len(lines) - 2: true, len(lines) - 1: false} {
+		var entry Entry
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Warmup != want {
+			t.Errorf("entry %d warmup = %v, want %v", entry.Seq, entry.Warmup, want)
+		}
+	}
+}
+
+// lineHead returns the head of the entry at index i of the log, counting negative indexes from the end
+func lineHead(t *testing.T, filename string, i int) Head {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if i < 0 {
+		i += len(lines)
+	}
+	var head Head
+	decoder := json.NewDecoder(strings.NewReader(lines[i]))
+	if err := decoder.Decode(&head); err != nil {
+		t.Fatal(err)
+	}
+	return head
+}
+
+// setHead overwrites the head file of a log
+func setHead(t *testing.T, filename string, head Head) {
+	t.Helper()
+	data, err := json.Marshal(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(HeadFile(filename), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rewrite replaces the lines of a file using edit
+func rewrite(t *testing.T, filename string, edit func(lines []string) []string) {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	lines = edit(lines)
+	if err := os.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package audit
+
+import "os"
+
+// lockFile is a no-op on platforms without flock; concurrent runs must not share a log there
+func lockFile(file *os.File) error {
+	return nil
+}
@@ -2,33 +2,213 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"go.yaml.in/yaml/v3"
 
+	"github.com/guessi/docker-parallel-pull/internal/redact"
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	"github.com/guessi/docker-parallel-pull/internal/sbom"
 	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/stats"
+	"github.com/guessi/docker-parallel-pull/internal/vuln"
 )
 
 // Security constants
 const (
-	MaxConcurrency = 20               // Hard limit on concurrency
-	MaxTimeout     = 30 * time.Minute // Maximum timeout
-	MaxRetries     = 10               // Maximum retries
+	MaxConcurrency  = 20               // Hard limit on concurrency
+	MaxTimeout      = 30 * time.Minute // Maximum timeout
+	MaxRetries      = 10               // Maximum retries
+	MaxBaselineRuns = 1000             // Maximum runs compared against
+	MaxIterations   = 100              // Maximum benchmark iterations
 )
 
+// StdinSource is the container source name that reads the image list from standard input
+const StdinSource = "-"
+
 // Config holds all configuration options for the application
 type Config struct {
-	ContainerFile    string        `yaml:"container_file"`
-	CleanupAfterTest bool          `yaml:"cleanup_after_test"`
-	ShowPullDetail   bool          `yaml:"show_pull_detail"`
-	MaxConcurrency   int           `yaml:"max_concurrency"`
-	Timeout          time.Duration `yaml:"timeout"`
-	MaxRetries       int           `yaml:"max_retries"`
-	RetryDelay       time.Duration `yaml:"retry_delay"`
-	ShowProgress     bool          `yaml:"show_progress"`
-	OutputFormat     string        `yaml:"output_format"`
+	ContainerFile    string              `yaml:"container_file"`
+	ContainerFiles   []string            `yaml:"container_files"` // Additional sources merged with container_file
+	CleanupAfterTest bool                `yaml:"cleanup_after_test"`
+	ShowPullDetail   bool                `yaml:"show_pull_detail"`
+	MaxConcurrency   int                 `yaml:"max_concurrency"`
+	Timeout          time.Duration       `yaml:"timeout"`
+	MaxRetries       int                 `yaml:"max_retries"`
+	RetryDelay       time.Duration       `yaml:"retry_delay"`
+	ShowProgress     bool                `yaml:"show_progress"`
+	OutputFormat     string              `yaml:"output_format"`
+	Reporters        []ReporterConfig    `yaml:"reporters"`   // Reports written to files in addition to standard output
+	ReportSort       string              `yaml:"report_sort"` // Row order of CSV, Markdown and HTML reports
+	DryRun           bool                `yaml:"dry_run"`
+	SkipUpToDate     bool                `yaml:"skip_up_to_date"` // Only pull images whose remote digest changed
+	Lockfile         string              `yaml:"lockfile"`        // Tag-to-digest lockfile written after pulling
+	Locked           bool                `yaml:"locked"`          // Pull lockfile digests and fail on drift
+	PolicyFile       string              `yaml:"policy_file"`     // Image admission policy
+	AuditLog         string              `yaml:"audit_log"`       // Hash-chained JSON lines audit log
+	ComposeEnvFile   string              `yaml:"compose_env_file"`
+	ComposeProfiles  []string            `yaml:"compose_profiles"`
+	Registry         RegistryConfig      `yaml:"registry"`
+	Mirror           MirrorConfig        `yaml:"mirror"`
+	Signature        SignatureConfig     `yaml:"signature_verification"`
+	Vulnerability    VulnerabilityConfig `yaml:"vulnerability_gate"`
+	SBOM             SBOMConfig          `yaml:"sbom"`
+	Prometheus       PrometheusConfig    `yaml:"prometheus"`
+	Tracing          TracingConfig       `yaml:"tracing"`
+	History          HistoryConfig       `yaml:"history"`
+	Benchmark        BenchmarkConfig     `yaml:"benchmark"`
+	PathPolicy       PathPolicyConfig    `yaml:"path_policy"`
+	Redaction        RedactionConfig     `yaml:"redaction"`
+}
+
+// RedactionConfig selects how sensitive data is removed from logs and errors
+type RedactionConfig struct {
+	DisabledRules         []string `yaml:"disabled_rules"`          // Built-in rules to turn off
+	RedactImageReferences bool     `yaml:"redact_image_references"` // Also redact inside image references
+}
+
+// Redactor builds the redactor described by the configuration
+func (r RedactionConfig) Redactor() (*redact.Redactor, error) {
+	return redact.New(redact.Options{
+		DisabledRules:         r.DisabledRules,
+		RedactImageReferences: r.RedactImageReferences,
+	})
+}
+
+// PathPolicyConfig holds the directories and file checks applied to every file read or written
+type PathPolicyConfig struct {
+	AllowedRoots       []string `yaml:"allowed_roots"`        // Replaces the default allowed directories when set
+	CheckOwnership     bool     `yaml:"check_ownership"`      // Files must be owned by the current user or root
+	AllowWorldWritable bool     `yaml:"allow_world_writable"` // Accept files that any user can modify
+}
+
+// Policy converts the configuration into a security path policy
+func (p PathPolicyConfig) Policy() security.PathPolicy {
+	policy := security.DefaultPathPolicy()
+	if len(p.AllowedRoots) > 0 {
+		policy.AllowedRoots = p.AllowedRoots
+	}
+	policy.CheckOwnership = p.CheckOwnership
+	policy.AllowWorldWritable = p.AllowWorldWritable
+	return policy
+}
+
+// ReporterConfig writes the pull report in one format to a file
+type ReporterConfig struct {
+	Format string `yaml:"format"`
+	Path   string `yaml:"path"`
+}
+
+// ReportFormats are the supported report formats
+var ReportFormats = []string{"text", "json", "junit", "csv", "markdown", "html"}
+
+// SummaryFormats are the output formats of commands other than pull and of dry runs;
+// the remaining report formats only describe pull results
+var SummaryFormats = []string{"text", "json"}
+
+// ReportSorts are the supported row orders of table reports; empty keeps the listed order
+var ReportSorts = []string{"", "duration", "image"}
+
+// SignatureConfig holds options for offline cosign signature verification
+type SignatureConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	PublicKeys []string `yaml:"public_keys"` // PEM encoded public key files
+	Mode       string   `yaml:"mode"`        // "enforce" fails unverified images, "warn" only reports them
+}
+
+// VulnerabilityConfig holds options for the offline vulnerability gate
+type VulnerabilityConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Feed    string `yaml:"feed"`    // Local JSON vulnerability feed
+	FailOn  string `yaml:"fail_on"` // Lowest severity that fails an image
+}
+
+// SBOMConfig holds options for writing a software bill of materials per pulled image
+type SBOMConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Format    string `yaml:"format"`     // "spdx" or "cyclonedx"
+	OutputDir string `yaml:"output_dir"` // Directory SBOMs are written to, one file per digest
+}
+
+// PrometheusConfig holds the destinations of the Prometheus metrics of a run
+type PrometheusConfig struct {
+	Textfile    string            `yaml:"textfile"` // node exporter textfile collector file, must end in .prom
+	Pushgateway PushgatewayConfig `yaml:"pushgateway"`
+}
+
+// PushgatewayConfig holds options for pushing metrics to a Pushgateway
+type PushgatewayConfig struct {
+	URL         string        `yaml:"url"`
+	Job         string        `yaml:"job"`
+	Instance    string        `yaml:"instance"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Username    string        `yaml:"username"`
+	PasswordEnv string        `yaml:"password_env"` // Name of the environment variable holding the password
+}
+
+// TracingConfig holds options for exporting each run as an OpenTelemetry trace
+type TracingConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Endpoint    string        `yaml:"endpoint"` // OTLP/HTTP collector base URL, /v1/traces is appended
+	ServiceName string        `yaml:"service_name"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
+// HistoryConfig holds the run history file and the regression thresholds of the compare command
+type HistoryConfig struct {
+	File                     string        `yaml:"file"`                       // JSON lines file every pull run is appended to
+	BaselineRuns             int           `yaml:"baseline_runs"`              // Runs before the latest one the compare command uses as baseline
+	DurationThresholdPercent float64       `yaml:"duration_threshold_percent"` // Pull time increase reported as a regression
+	SizeThresholdPercent     float64       `yaml:"size_threshold_percent"`     // Image size increase reported as a regression
+	MinDurationDelta         time.Duration `yaml:"min_duration_delta"`         // Smaller pull time increases are never regressions
+}
+
+// BenchmarkConfig holds the options of the benchmark command
+type BenchmarkConfig struct {
+	Iterations int     `yaml:"iterations"`        // Measured iterations
+	Warmup     int     `yaml:"warmup_iterations"` // Unmeasured iterations run first; at least 1 unless cold
+	Cold       bool    `yaml:"cold"`              // Remove the images before every iteration
+	Confidence float64 `yaml:"confidence"`        // Level of the confidence intervals: 0.90, 0.95 or 0.99
+}
+
+// RegistryConfig holds how the registry API is reached for tag expansion, digest checks and signatures
+type RegistryConfig struct {
+	DockerConfig string   `yaml:"docker_config"` // Docker CLI config directory with credentials, defaults to DOCKER_CONFIG or ~/.docker
+	Insecure     []string `yaml:"insecure"`      // Registries (host, host:port or CIDR) reached over unverified TLS or HTTP
+}
+
+// Validate checks if the registry configuration is usable
+func (r RegistryConfig) Validate() error {
+	for _, entry := range r.Insecure {
+		if err := registry.ValidateInsecureRegistry(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MirrorConfig holds options for retagging and pushing pulled images to a mirror registry
+type MirrorConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Rules          []MirrorRule  `yaml:"rules"`
+	MaxConcurrency int           `yaml:"max_concurrency"`
+	Timeout        time.Duration `yaml:"timeout"`
+	MaxRetries     int           `yaml:"max_retries"`
+	RetryDelay     time.Duration `yaml:"retry_delay"`
+	Username       string        `yaml:"username"`
+	PasswordEnv    string        `yaml:"password_env"` // Name of the environment variable holding the password
+}
+
+// MirrorRule rewrites a source image reference into a mirror reference.
+// A single trailing "*" in From matches the rest of the reference and is
+// substituted for the "*" in To.
+type MirrorRule struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
 }
 
 // LoadConfig loads configuration from a YAML file with defaults
@@ -42,7 +222,7 @@ func LoadConfig(filename string) (*Config, error) {
 	}
 
 	// Set defaults for missing values
-	if config.ContainerFile == "" {
+	if config.ContainerFile == "" && len(config.ContainerFiles) == 0 {
 		config.ContainerFile = "containers.yaml"
 	}
 	if config.MaxConcurrency == 0 {
@@ -60,6 +240,67 @@ func LoadConfig(filename string) (*Config, error) {
 	if config.OutputFormat == "" {
 		config.OutputFormat = "text"
 	}
+	if config.Mirror.MaxConcurrency == 0 {
+		config.Mirror.MaxConcurrency = config.MaxConcurrency
+	}
+	if config.Mirror.Timeout == 0 {
+		config.Mirror.Timeout = config.Timeout
+	}
+	if config.Mirror.MaxRetries == 0 {
+		config.Mirror.MaxRetries = config.MaxRetries
+	}
+	if config.Mirror.RetryDelay == 0 {
+		config.Mirror.RetryDelay = config.RetryDelay
+	}
+	if config.Signature.Mode == "" {
+		config.Signature.Mode = "enforce"
+	}
+	if config.Vulnerability.FailOn == "" {
+		config.Vulnerability.FailOn = "high"
+	}
+	if config.SBOM.Format == "" {
+		config.SBOM.Format = sbom.FormatSPDX
+	}
+	if config.SBOM.OutputDir == "" {
+		config.SBOM.OutputDir = "sboms"
+	}
+	if config.Prometheus.Pushgateway.Job == "" {
+		config.Prometheus.Pushgateway.Job = "docker_parallel_pull"
+	}
+	if config.Prometheus.Pushgateway.Timeout == 0 {
+		config.Prometheus.Pushgateway.Timeout = 10 * time.Second
+	}
+	if config.Tracing.Endpoint == "" {
+		config.Tracing.Endpoint = "http://localhost:4318"
+	}
+	if config.Tracing.ServiceName == "" {
+		config.Tracing.ServiceName = "docker-parallel-pull"
+	}
+	if config.Tracing.Timeout == 0 {
+		config.Tracing.Timeout = 10 * time.Second
+	}
+	if config.History.BaselineRuns == 0 {
+		config.History.BaselineRuns = 7
+	}
+	if config.History.DurationThresholdPercent == 0 {
+		config.History.DurationThresholdPercent = 25
+	}
+	if config.History.SizeThresholdPercent == 0 {
+		config.History.SizeThresholdPercent = 10
+	}
+	if config.History.MinDurationDelta == 0 {
+		config.History.MinDurationDelta = time.Second
+	}
+	if config.Benchmark.Iterations == 0 {
+		config.Benchmark.Iterations = 5
+	}
+	if config.Benchmark.Confidence == 0 {
+		config.Benchmark.Confidence = 0.95
+	}
+	if !config.Benchmark.Cold && config.Benchmark.Warmup == 0 {
+		// The first warm iteration pulls into an empty cache, so leave it out
+		config.Benchmark.Warmup = 1
+	}
 	// ShowProgress and CleanupAfterTest default to true if not set
 	// (YAML unmarshaling will set them to false if not specified)
 
@@ -84,23 +325,54 @@ func LoadConfigFile(filename string) (*Config, error) {
 	return &config, nil
 }
 
+// ContainerSources returns every configured image source, container_file first
+func (c *Config) ContainerSources() []string {
+	var sources []string
+	if c.ContainerFile != "" {
+		sources = append(sources, c.ContainerFile)
+	}
+	return append(sources, c.ContainerFiles...)
+}
 
+// ValidateSummaryFormat checks that the output format can be used by commands that do not produce a pull report
+func (c *Config) ValidateSummaryFormat() error {
+	if !slices.Contains(SummaryFormats, c.OutputFormat) {
+		return fmt.Errorf("output format %s is only supported when pulling; use one of %s", c.OutputFormat, strings.Join(SummaryFormats, ", "))
+	}
+	return nil
+}
 
 // Validate checks if the configuration parameters are valid and secure
 func (c *Config) Validate() error {
 	if c == nil {
 		return fmt.Errorf("config is nil")
 	}
-	if c.ContainerFile == "" {
+	containerSources := c.ContainerSources()
+	if len(containerSources) == 0 {
 		return fmt.Errorf("container file path cannot be empty")
 	}
 
-	if err := security.ValidateFilePath(c.ContainerFile); err != nil {
-		return fmt.Errorf("invalid container file path: %w", err)
+	stdinSources := 0
+	for _, source := range containerSources {
+		if source == "" {
+			return fmt.Errorf("container file path cannot be empty")
+		}
+		if source == StdinSource {
+			stdinSources++
+			continue
+		}
+
+		if err := security.ValidateFilePath(source); err != nil {
+			return fmt.Errorf("invalid container file path: %w", err)
+		}
+
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			return fmt.Errorf("container file does not exist: %s", security.SanitizeLogMessage(source))
+		}
 	}
 
-	if _, err := os.Stat(c.ContainerFile); os.IsNotExist(err) {
-		return fmt.Errorf("container file does not exist: %s", security.SanitizeLogMessage(c.ContainerFile))
+	if stdinSources > 1 {
+		return fmt.Errorf("standard input can only be used once as a container source")
 	}
 
 	if c.MaxConcurrency <= 0 {
@@ -135,8 +407,297 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("retry delay cannot be negative, got: %v", c.RetryDelay)
 	}
 
-	if c.OutputFormat != "text" && c.OutputFormat != "json" {
-		return fmt.Errorf("output format must be 'text' or 'json', got: %s", c.OutputFormat)
+	if !slices.Contains(ReportFormats, c.OutputFormat) {
+		return fmt.Errorf("output format must be one of %s, got: %s", strings.Join(ReportFormats, ", "), c.OutputFormat)
+	}
+
+	if !slices.Contains(ReportSorts, c.ReportSort) {
+		return fmt.Errorf("report sort must be duration or image, got: %s", c.ReportSort)
+	}
+
+	reportPaths := make(map[string]bool)
+	for i, reporter := range c.Reporters {
+		if !slices.Contains(ReportFormats, reporter.Format) {
+			return fmt.Errorf("reporter %d: format must be one of %s, got: %s", i+1, strings.Join(ReportFormats, ", "), reporter.Format)
+		}
+		if reporter.Path == "" {
+			return fmt.Errorf("reporter %d: path is required", i+1)
+		}
+		if err := security.ValidateFilePath(reporter.Path); err != nil {
+			return fmt.Errorf("reporter %d: invalid path: %w", i+1, err)
+		}
+		if reportPaths[reporter.Path] {
+			return fmt.Errorf("reporter %d: path %s is used by another reporter", i+1, reporter.Path)
+		}
+		reportPaths[reporter.Path] = true
+	}
+
+	if c.Lockfile != "" {
+		if err := security.ValidateFilePath(c.Lockfile); err != nil {
+			return fmt.Errorf("invalid lockfile path: %w", err)
+		}
+	}
+
+	if c.PolicyFile != "" {
+		if err := security.ValidateFilePath(c.PolicyFile); err != nil {
+			return fmt.Errorf("invalid policy file path: %w", err)
+		}
+	}
+
+	if c.Locked && c.Lockfile == "" {
+		return fmt.Errorf("locked mode requires a lockfile")
+	}
+
+	if c.AuditLog != "" {
+		if err := security.ValidateFilePath(c.AuditLog); err != nil {
+			return fmt.Errorf("invalid audit log path: %w", err)
+		}
+	}
+
+	if c.ComposeEnvFile != "" {
+		if err := security.ValidateFilePath(c.ComposeEnvFile); err != nil {
+			return fmt.Errorf("invalid compose env file path: %w", err)
+		}
+	}
+
+	if err := c.Registry.Validate(); err != nil {
+		return fmt.Errorf("invalid registry configuration: %w", err)
+	}
+
+	if err := c.Mirror.Validate(); err != nil {
+		return fmt.Errorf("invalid mirror configuration: %w", err)
+	}
+
+	if err := c.Signature.Validate(); err != nil {
+		return fmt.Errorf("invalid signature verification configuration: %w", err)
+	}
+
+	if err := c.Vulnerability.Validate(); err != nil {
+		return fmt.Errorf("invalid vulnerability gate configuration: %w", err)
+	}
+
+	if err := c.SBOM.Validate(); err != nil {
+		return fmt.Errorf("invalid SBOM configuration: %w", err)
+	}
+
+	if err := c.Prometheus.Validate(); err != nil {
+		return fmt.Errorf("invalid prometheus configuration: %w", err)
+	}
+
+	if err := c.Tracing.Validate(); err != nil {
+		return fmt.Errorf("invalid tracing configuration: %w", err)
+	}
+
+	if err := c.History.Validate(); err != nil {
+		return fmt.Errorf("invalid history configuration: %w", err)
+	}
+
+	if err := c.Benchmark.Validate(); err != nil {
+		return fmt.Errorf("invalid benchmark configuration: %w", err)
+	}
+
+	return nil
+}
+
+// Validate checks if the mirror configuration is usable
+func (m *MirrorConfig) Validate() error {
+	if !m.Enabled {
+		return nil
+	}
+
+	if len(m.Rules) == 0 {
+		return fmt.Errorf("at least one rewrite rule is required")
+	}
+
+	for i, rule := range m.Rules {
+		if rule.From == "" || rule.To == "" {
+			return fmt.Errorf("rule %d: from and to cannot be empty", i)
+		}
+		fromWildcards := strings.Count(rule.From, "*")
+		toWildcards := strings.Count(rule.To, "*")
+		if fromWildcards > 1 || toWildcards > 1 {
+			return fmt.Errorf("rule %d: only one '*' is allowed per pattern", i)
+		}
+		if fromWildcards == 1 && !strings.HasSuffix(rule.From, "*") {
+			return fmt.Errorf("rule %d: '*' must be the last character of from, got: %s", i, rule.From)
+		}
+		if toWildcards > fromWildcards {
+			return fmt.Errorf("rule %d: to uses '*' but from does not", i)
+		}
+	}
+
+	if m.MaxConcurrency <= 0 || m.MaxConcurrency > MaxConcurrency {
+		return fmt.Errorf("max concurrency must be between 1 and %d, got: %d", MaxConcurrency, m.MaxConcurrency)
+	}
+
+	if m.Timeout <= 0 || m.Timeout > MaxTimeout {
+		return fmt.Errorf("timeout must be between 0 and %v, got: %v", MaxTimeout, m.Timeout)
+	}
+
+	if m.MaxRetries < 0 || m.MaxRetries > MaxRetries {
+		return fmt.Errorf("max retries must be between 0 and %d, got: %d", MaxRetries, m.MaxRetries)
+	}
+
+	if m.RetryDelay < 0 {
+		return fmt.Errorf("retry delay cannot be negative, got: %v", m.RetryDelay)
+	}
+
+	if m.Username != "" && m.PasswordEnv == "" {
+		return fmt.Errorf("password_env is required when username is set")
+	}
+
+	return nil
+}
+
+// Validate checks if the vulnerability gate configuration is usable
+func (v *VulnerabilityConfig) Validate() error {
+	if !v.Enabled {
+		return nil
+	}
+
+	if v.Feed == "" {
+		return fmt.Errorf("feed is required")
+	}
+	if err := security.ValidateFilePath(v.Feed); err != nil {
+		return fmt.Errorf("invalid feed path: %w", err)
+	}
+
+	if vuln.SeverityRank(v.FailOn) < 0 {
+		return fmt.Errorf("unknown fail_on severity: %s", v.FailOn)
+	}
+
+	return nil
+}
+
+// Validate checks if the SBOM configuration is usable
+func (s *SBOMConfig) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+
+	if s.Format != sbom.FormatSPDX && s.Format != sbom.FormatCycloneDX {
+		return fmt.Errorf("format must be '%s' or '%s', got: %s", sbom.FormatSPDX, sbom.FormatCycloneDX, s.Format)
+	}
+
+	if err := security.ValidateFilePath(s.OutputDir); err != nil {
+		return fmt.Errorf("invalid output directory: %w", err)
+	}
+
+	return nil
+}
+
+// Validate checks if the Prometheus export configuration is usable
+func (p *PrometheusConfig) Validate() error {
+	if p.Textfile != "" {
+		if !strings.HasSuffix(p.Textfile, ".prom") {
+			return fmt.Errorf("textfile must end in .prom to be read by the textfile collector, got: %s", p.Textfile)
+		}
+		if err := security.ValidateFilePath(p.Textfile); err != nil {
+			return fmt.Errorf("invalid textfile path: %w", err)
+		}
+	}
+
+	gateway := p.Pushgateway
+	if gateway.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(gateway.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("pushgateway url must be an http or https URL, got: %s", security.SanitizeLogMessage(gateway.URL))
+	}
+	if u.User != nil {
+		return fmt.Errorf("pushgateway url must not contain credentials, use username and password_env")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("pushgateway url must not have a query or fragment")
+	}
+	if gateway.Timeout <= 0 || gateway.Timeout > MaxTimeout {
+		return fmt.Errorf("pushgateway timeout must be between 0 and %v, got: %v", MaxTimeout, gateway.Timeout)
+	}
+	if gateway.Username != "" && gateway.PasswordEnv == "" {
+		return fmt.Errorf("password_env is required when username is set")
+	}
+	return nil
+}
+
+// Validate checks if the tracing configuration is usable
+func (t *TracingConfig) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	u, err := url.Parse(t.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("endpoint must be an http or https URL, got: %s", security.SanitizeLogMessage(t.Endpoint))
+	}
+	if u.User != nil {
+		return fmt.Errorf("endpoint must not contain credentials")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("endpoint must not have a query or fragment")
+	}
+	if t.ServiceName == "" {
+		return fmt.Errorf("service_name must not be empty")
+	}
+	if t.Timeout <= 0 || t.Timeout > MaxTimeout {
+		return fmt.Errorf("timeout must be between 0 and %v, got: %v", MaxTimeout, t.Timeout)
+	}
+	return nil
+}
+
+// Validate checks if the history configuration is usable
+func (h *HistoryConfig) Validate() error {
+	if h.File != "" {
+		if err := security.ValidateFilePath(h.File); err != nil {
+			return fmt.Errorf("invalid history file: %w", err)
+		}
+	}
+	if h.BaselineRuns < 1 || h.BaselineRuns > MaxBaselineRuns {
+		return fmt.Errorf("baseline_runs must be between 1 and %d, got: %d", MaxBaselineRuns, h.BaselineRuns)
+	}
+	if h.DurationThresholdPercent < 0 || h.SizeThresholdPercent < 0 {
+		return fmt.Errorf("threshold percentages must not be negative")
+	}
+	if h.MinDurationDelta < 0 {
+		return fmt.Errorf("min_duration_delta must not be negative, got: %v", h.MinDurationDelta)
+	}
+	return nil
+}
+
+// Validate checks if the benchmark configuration is usable
+func (b *BenchmarkConfig) Validate() error {
+	if b.Iterations < 2 || b.Iterations > MaxIterations {
+		return fmt.Errorf("iterations must be between 2 and %d, got: %d", MaxIterations, b.Iterations)
+	}
+	if b.Warmup < 0 || b.Warmup > MaxIterations {
+		return fmt.Errorf("warmup_iterations must be between 0 and %d, got: %d", MaxIterations, b.Warmup)
+	}
+	if !b.Cold && b.Warmup == 0 {
+		return fmt.Errorf("warmup_iterations must be at least 1 without cold, so the first pull into an empty cache is not measured")
+	}
+	if !slices.Contains(stats.ConfidenceLevels, b.Confidence) {
+		return fmt.Errorf("confidence must be one of %v, got: %v", stats.ConfidenceLevels, b.Confidence)
+	}
+	return nil
+}
+
+// Validate checks if the signature verification configuration is usable
+func (s *SignatureConfig) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+
+	if len(s.PublicKeys) == 0 {
+		return fmt.Errorf("at least one public key is required")
+	}
+
+	for _, key := range s.PublicKeys {
+		if err := security.ValidateFilePath(key); err != nil {
+			return fmt.Errorf("invalid public key path: %w", err)
+		}
+	}
+
+	if s.Mode != "enforce" && s.Mode != "warn" {
+		return fmt.Errorf("mode must be 'enforce' or 'warn', got: %s", s.Mode)
 	}
 
 	return nil
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/client"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/output"
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// checkImage compares the local digest of an image with the digest its tag resolves to remotely
+func checkImage(ctx context.Context, client *client.Client, registryClient *registry.Client, ref dockertypes.ImageRef) dockertypes.CheckResult {
+	result := dockertypes.CheckResult{
+		Image:          ref.Name,
+		CanonicalImage: ref.Canonical,
+		Sources:        ref.Sources,
+	}
+
+	named, err := reference.ParseNormalizedNamed(ref.Canonical)
+	if err != nil {
+		result.Status = dockertypes.CheckStatusError
+		result.Error = security.SanitizeErrorMessage(err)
+		return result
+	}
+
+	result.RemoteDigest, err = registryClient.ManifestDigest(ctx, named)
+	if err != nil {
+		result.Status = dockertypes.CheckStatusError
+		result.Error = security.SanitizeErrorMessage(fmt.Errorf("cannot resolve remote digest: %w", err))
+		return result
+	}
+
+	result.LocalDigest, err = localDigest(ctx, client, ref.Canonical)
+	switch {
+	case cerrdefs.IsNotFound(err):
+		result.Status = dockertypes.CheckStatusMissing
+	case err != nil:
+		// Present locally but without a digest for this repository (e.g. built or retagged locally)
+		result.Status = dockertypes.CheckStatusStale
+	case result.LocalDigest == result.RemoteDigest:
+		result.Status = dockertypes.CheckStatusUpToDate
+	default:
+		result.Status = dockertypes.CheckStatusStale
+	}
+
+	return result
+}
+
+// CheckImages compares every image to its remote tag without pulling
+func CheckImages(ctx context.Context, client *client.Client, images []dockertypes.ImageRef, config *config.Config) []dockertypes.CheckResult {
+	if client == nil || config == nil {
+		return []dockertypes.CheckResult{}
+	}
+
+	registryClient := NewRegistryClient(config)
+	semaphore := make(chan struct{}, config.MaxConcurrency)
+	results := make([]dockertypes.CheckResult, len(images))
+	var wg sync.WaitGroup
+
+	for i, img := range images {
+		wg.Add(1)
+		go func(i int, ref dockertypes.ImageRef) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = checkImage(ctx, client, registryClient, ref)
+		}(i, img)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// FilterChanged returns the images that are stale or missing locally, keeping
+// images whose status cannot be determined so they are pulled as usual
+func FilterChanged(ctx context.Context, client *client.Client, images []dockertypes.ImageRef, config *config.Config) []dockertypes.ImageRef {
+	results := CheckImages(ctx, client, images, config)
+
+	changed := make([]dockertypes.ImageRef, 0, len(images))
+	for i, result := range results {
+		if result.Status == dockertypes.CheckStatusUpToDate {
+			output.SecureLogMessage(config, "INFO", fmt.Sprintf("Skipping up-to-date image: %s", security.SanitizeLogMessage(result.Image)))
+			continue
+		}
+		changed = append(changed, images[i])
+	}
+	return changed
+}
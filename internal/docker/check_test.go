@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestCheckImagesPrivateRegistry(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	remote := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if username, password, ok := req.BasicAuth(); !ok || username != "robot" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="harbor"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer remote.Close()
+	host := strings.TrimPrefix(remote.URL, "https://")
+
+	dockerConfig := t.TempDir()
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:secret"))
+	if err := os.WriteFile(filepath.Join(dockerConfig, "config.json"), fmt.Appendf(nil, `{"auths":{%q:{"auth":%q}}}`, host, auth), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := fakeDaemon(t, func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, `{"Id":"sha256:abc","RepoDigests":[%q]}`, host+"/app@"+digest)
+	})
+
+	image := host + "/app:1.0"
+	results := CheckImages(context.Background(), cli, []dockertypes.ImageRef{{Name: image, Canonical: image}}, &config.Config{
+		MaxConcurrency: 1,
+		Registry:       config.RegistryConfig{DockerConfig: dockerConfig, Insecure: []string{host}},
+	})
+
+	if len(results) != 1 || results[0].Status != dockertypes.CheckStatusUpToDate || results[0].RemoteDigest != digest {
+		t.Errorf("CheckImages() = %+v, want up-to-date", results)
+	}
+}
@@ -5,10 +5,15 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"go.yaml.in/yaml/v3"
@@ -16,10 +21,16 @@ import (
 	"github.com/guessi/docker-parallel-pull/internal/config"
 	"github.com/guessi/docker-parallel-pull/internal/output"
 	"github.com/guessi/docker-parallel-pull/internal/progress"
+	"github.com/guessi/docker-parallel-pull/internal/registry"
 	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/sources"
+	"github.com/guessi/docker-parallel-pull/internal/tags"
 	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
 )
 
+// stdinSource is the container source name that reads from standard input
+const stdinSource = config.StdinSource
+
 // CreateDockerClient creates a new Docker client with API version negotiation
 func CreateDockerClient() (*client.Client, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
@@ -29,35 +40,321 @@ func CreateDockerClient() (*client.Client, error) {
 	return cli, nil
 }
 
-// LoadContainerImages reads and parses the YAML file containing image names with security validation
-func LoadContainerImages(filename string) ([]string, error) {
-	data, err := security.SecureReadFile(filename)
+// LoadContainerImages reads and parses every configured image source with security validation
+// and merges their entries. A source is an image list (YAML, JSON or plain text), a compose file,
+// a Kubernetes manifest, a Dockerfile, a directory of manifests and Dockerfiles, or "-" for stdin.
+func LoadContainerImages(config *config.Config) ([]dockertypes.ImageSpec, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config is nil")
+	}
+
+	var images []dockertypes.ImageSpec
+	for _, source := range config.ContainerSources() {
+		found, err := loadImageSource(source, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read container image list %s: %w", security.SanitizeLogMessage(source), err)
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no images found in %s", security.SanitizeLogMessage(source))
+		}
+
+		images = append(images, found...)
+		if len(images) > security.MaxImages {
+			return nil, fmt.Errorf("too many images (%d), maximum allowed: %d", len(images), security.MaxImages)
+		}
+	}
+
+	validatedImages := make([]dockertypes.ImageSpec, 0, len(images))
+	for i, spec := range images {
+		if err := validateImageSpec(spec); err != nil {
+			return nil, fmt.Errorf("invalid image entry at index %d (%s): %w", i, security.SanitizeLogMessage(spec.Source), err)
+		}
+		validatedImages = append(validatedImages, spec)
+	}
+
+	return validatedImages, nil
+}
+
+// loadImageSource reads the image entries of a single source
+func loadImageSource(source string, config *config.Config) ([]dockertypes.ImageSpec, error) {
+	if source == stdinSource {
+		data, err := security.SecureReadStdin()
+		if err != nil {
+			return nil, err
+		}
+		return parseImageSource("stdin", data, config, false)
+	}
+
+	info, err := os.Stat(source)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read container image list: %w", err)
+		return nil, fmt.Errorf("cannot access source: %s", security.SanitizeErrorMessage(err))
+	}
+
+	if !info.IsDir() {
+		data, err := security.SecureReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		return parseImageSource(source, data, config, false)
+	}
+
+	var images []dockertypes.ImageSpec
+	err = security.SecureWalkFiles(source, isManifestFile, func(path string, data []byte) error {
+		found, err := parseImageSource(path, data, config, true)
+		images = append(images, found...)
+		return err
+	})
+	return images, err
+}
+
+// isManifestFile reports whether a file found in a source directory should be parsed
+func isManifestFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yaml" || ext == ".yml" || sources.IsDockerfile(name)
+}
+
+// parseImageSource parses the image entries of a single source file according to its detected format.
+// Unrecognized YAML files are skipped when they are found while walking a directory.
+func parseImageSource(filename string, data []byte, config *config.Config, inDirectory bool) ([]dockertypes.ImageSpec, error) {
+	switch sources.DetectFile(filename, data) {
+	case sources.KindDockerfile:
+		return sources.ParseDockerfile(data, filename)
+	case sources.KindText:
+		return sources.ParseTextList(data, filename), nil
+	case sources.KindJSON:
+		return sources.ParseJSONList(data, filename)
+	case sources.KindCompose:
+		opts, err := composeOptions(config, filename)
+		if err != nil {
+			return nil, err
+		}
+		return sources.ParseCompose(data, filename, opts)
+	case sources.KindKubernetes:
+		return sources.ParseKubernetes(data, filename)
+	case sources.KindUnknown:
+		if inDirectory {
+			return nil, nil
+		}
 	}
 
 	var containerImageList dockertypes.ImageList
 	if err := parseYAML(data, &containerImageList); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
+	for i := range containerImageList.Images {
+		containerImageList.Images[i].Source = filename
+	}
+	return containerImageList.Images, nil
+}
 
-	if len(containerImageList.Images) == 0 {
-		return nil, fmt.Errorf("no images found in %s", filename)
+// composeOptions builds compose interpolation options for a compose file, reading the env file
+// (or the .env next to the compose file when present) for variable values
+func composeOptions(config *config.Config, filename string) (sources.ComposeOptions, error) {
+	opts := sources.ComposeOptions{Profiles: config.ComposeProfiles}
+
+	envFile := config.ComposeEnvFile
+	if envFile == "" {
+		// Like docker compose, the default .env belongs to the project directory of the compose file;
+		// a compose file read from stdin uses the working directory
+		envFile = ".env"
+		if filename != "stdin" {
+			envFile = filepath.Join(filepath.Dir(filename), ".env")
+		}
+		if _, err := os.Stat(envFile); err != nil {
+			return opts, nil
+		}
 	}
 
-	if len(containerImageList.Images) > security.MaxImages {
-		return nil, fmt.Errorf("too many images (%d), maximum allowed: %d", len(containerImageList.Images), security.MaxImages)
+	data, err := security.SecureReadFile(envFile)
+	if err != nil {
+		return opts, fmt.Errorf("failed to read compose env file: %w", err)
 	}
+	opts.Env, err = sources.ParseEnvFile(data)
+	if err != nil {
+		return opts, fmt.Errorf("failed to parse compose env file: %w", err)
+	}
+	return opts, nil
+}
+
+// validateImageSpec validates an image list entry and its tag expansion options
+func validateImageSpec(spec dockertypes.ImageSpec) error {
+	if err := security.ValidateImageName(spec.Name); err != nil {
+		return err
+	}
+	if !spec.IsExpansion() {
+		return nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(spec.Name)
+	if err != nil {
+		return fmt.Errorf("cannot parse image reference: %w", err)
+	}
+	if !reference.IsNameOnly(named) {
+		return fmt.Errorf("image %s must not include a tag or digest when tags are expanded", security.SanitizeLogMessage(spec.Name))
+	}
+	if spec.LatestN < 0 {
+		return fmt.Errorf("latest_n cannot be negative, got: %d", spec.LatestN)
+	}
+	if _, err := tagFilter(spec); err != nil {
+		return err
+	}
+	return nil
+}
 
-	validatedImages := make([]string, 0, len(containerImageList.Images))
-	for i, imageName := range containerImageList.Images {
-		if err := security.ValidateImageName(imageName); err != nil {
-			return nil, fmt.Errorf("invalid image name at index %d: %w", i, err)
+// tagFilter builds the tag selection filter of an image list entry
+func tagFilter(spec dockertypes.ImageSpec) (tags.Filter, error) {
+	filter := tags.Filter{LatestN: spec.LatestN}
+	if spec.Tags != "" {
+		constraint, err := tags.ParseConstraint(spec.Tags)
+		if err != nil {
+			return tags.Filter{}, err
+		}
+		filter.Range = constraint
+	}
+	if spec.TagRegex != "" {
+		regex, err := regexp.Compile(spec.TagRegex)
+		if err != nil {
+			return tags.Filter{}, fmt.Errorf("invalid tag_regex: %w", err)
 		}
-		validatedImages = append(validatedImages, imageName)
+		filter.Regex = regex
 	}
+	return filter, nil
+}
 
-	return validatedImages, nil
+// NewRegistryClient creates a registry API client with the credentials of the Docker CLI config
+// and the configured insecure registries. An unreadable Docker config leaves the client anonymous.
+func NewRegistryClient(config *config.Config) *registry.Client {
+	options := registry.Options{Timeout: config.Timeout, Insecure: config.Registry.Insecure}
+
+	dockerConfig, err := registry.LoadDockerConfig(registry.DockerConfigDir(config.Registry.DockerConfig))
+	if err != nil {
+		output.SecureLogMessage(config, "WARN", fmt.Sprintf("Registry credentials unavailable, using anonymous access: %s", security.SanitizeErrorMessage(err)))
+	} else {
+		options.Credentials = dockerConfig
+	}
+	return registry.NewClient(options)
+}
+
+// ApplyDaemonRegistryConfig adds the registries the daemon treats as insecure to the configured ones,
+// so the registry API is reached the same way the daemon pulls
+func ApplyDaemonRegistryConfig(ctx context.Context, client *client.Client, config *config.Config) {
+	info, err := client.Info(ctx)
+	if err != nil {
+		output.SecureLogMessage(config, "WARN", fmt.Sprintf("Cannot read daemon registry configuration: %s", security.SanitizeErrorMessage(err)))
+		return
+	}
+	if info.RegistryConfig == nil {
+		return
+	}
+
+	for _, cidr := range info.RegistryConfig.InsecureRegistryCIDRs {
+		config.Registry.Insecure = append(config.Registry.Insecure, cidr.String())
+	}
+	for name, index := range info.RegistryConfig.IndexConfigs {
+		if index != nil && !index.Secure {
+			config.Registry.Insecure = append(config.Registry.Insecure, name)
+		}
+	}
+	slices.Sort(config.Registry.Insecure)
+	config.Registry.Insecure = slices.Compact(config.Registry.Insecure)
+}
+
+// ResolveImages expands tag selection entries by querying the registry tag list,
+// normalizes every reference to its canonical form and collapses duplicates
+func ResolveImages(ctx context.Context, specs []dockertypes.ImageSpec, config *config.Config) ([]dockertypes.ImageRef, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config is nil")
+	}
+
+	registryClient := NewRegistryClient(config)
+	refs := make([]dockertypes.ImageRef, 0, len(specs))
+	seen := make(map[string]int) // canonical image name -> index in refs
+
+	addRef := func(imageName, source string) error {
+		canonical, err := CanonicalName(imageName)
+		if err != nil {
+			return fmt.Errorf("invalid image reference %s: %w", security.SanitizeLogMessage(imageName), err)
+		}
+
+		i, ok := seen[canonical]
+		if !ok {
+			seen[canonical] = len(refs)
+			refs = append(refs, dockertypes.ImageRef{Name: imageName, Canonical: canonical, Sources: []string{source}})
+			return nil
+		}
+
+		output.SecureLogMessage(config, "WARN", fmt.Sprintf("Duplicate image %s (from %s) resolves to %s, pulling it once",
+			security.SanitizeLogMessage(imageName), security.SanitizeLogMessage(source), security.SanitizeLogMessage(canonical)))
+		refs[i].Sources = append(refs[i].Sources, source)
+		if imageName != refs[i].Name && !slices.Contains(refs[i].Aliases, imageName) {
+			refs[i].Aliases = append(refs[i].Aliases, imageName)
+		}
+		return nil
+	}
+
+	for _, spec := range specs {
+		if !spec.IsExpansion() {
+			if err := addRef(spec.Name, spec.Source); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		named, err := reference.ParseNormalizedNamed(spec.Name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse image reference: %w", err)
+		}
+		available, err := registryClient.ListTags(ctx, named)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for %s: %s", security.SanitizeLogMessage(spec.Name), security.SanitizeErrorMessage(err))
+		}
+
+		filter, err := tagFilter(spec)
+		if err != nil {
+			return nil, err
+		}
+		selected := filter.Select(available)
+		if len(selected) == 0 {
+			output.SecureLogMessage(config, "WARN", fmt.Sprintf("No tags of %s match %s", security.SanitizeLogMessage(spec.Name), spec.String()))
+			continue
+		}
+
+		source := fmt.Sprintf("%s: %s", spec.Source, spec.String())
+		for _, tag := range selected {
+			imageName := spec.Name + ":" + tag
+			if err := security.ValidateImageName(imageName); err != nil {
+				return nil, fmt.Errorf("invalid expanded image name: %w", err)
+			}
+			if err := addRef(imageName, source); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(refs) > security.MaxImages {
+		return nil, fmt.Errorf("too many images after tag expansion (%d), maximum allowed: %d", len(refs), security.MaxImages)
+	}
+
+	return refs, nil
+}
+
+// CanonicalName returns the fully-qualified form of an image reference,
+// e.g. docker.io/library/alpine:latest for alpine
+func CanonicalName(imageName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", err
+	}
+	return reference.TagNameOnly(named).String(), nil
+}
+
+// ImageNames returns the canonical references of resolved images
+func ImageNames(refs []dockertypes.ImageRef) []string {
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.Canonical)
+	}
+	return names
 }
 
 // calculateBackoffDelay calculates exponential backoff delay
@@ -104,21 +401,33 @@ func pullImageWithRetry(ctx context.Context, client *client.Client, imageName st
 
 	if err := security.ValidateImageName(imageName); err != nil {
 		return dockertypes.PullResult{
-			Image:    imageName,
-			Success:  false,
-			Error:    security.SanitizeErrorMessage(err),
-			Duration: time.Since(startTime),
-			Attempts: 1,
+			Image:         imageName,
+			Success:       false,
+			Error:         security.SanitizeErrorMessage(err),
+			ErrorCategory: dockertypes.ErrorCategoryInvalid,
+			Duration:      time.Since(startTime),
+			Attempts:      1,
+		}
+	}
+
+	var timeline []dockertypes.Attempt
+	recordAttempt := func(attempt int, attemptStart time.Time, layers []dockertypes.Layer, err error) {
+		entry := dockertypes.Attempt{Number: attempt, StartedAt: attemptStart, FinishedAt: time.Now(), Layers: layers}
+		if err != nil {
+			entry.Error = security.SanitizeErrorMessage(err)
 		}
+		timeline = append(timeline, entry)
 	}
 
 	for attempt := 1; attempt <= config.MaxRetries+1; attempt++ {
+		attemptStart := time.Now()
 		pullCtx, cancel := context.WithTimeout(ctx, config.Timeout)
 
 		r, err := client.ImagePull(pullCtx, imageName, image.PullOptions{})
 		if err != nil {
 			lastErr = fmt.Errorf("attempt %d failed to pull image %s: %w", attempt, security.SanitizeLogMessage(imageName), err)
 			cancel()
+			recordAttempt(attempt, attemptStart, nil, err)
 
 			if attempt <= config.MaxRetries {
 				delay := calculateBackoffDelay(attempt, config.RetryDelay)
@@ -130,38 +439,33 @@ func pullImageWithRetry(ctx context.Context, client *client.Client, imageName st
 			break
 		}
 
-		var size int64
-		var imageData []byte
-
+		// The pull stream reports layer progress; only the first MaxFileSize bytes are kept for the detail hash
+		captured := &limitedBuffer{limit: security.MaxFileSize}
+		var stream io.Reader = r
 		if config.ShowPullDetail {
 			output.SecureLogMessage(config, "INFO", fmt.Sprintf("=== Pulling %s (attempt %d) ===", security.SanitizeLogMessage(imageName), attempt))
-
-			limitedReader := io.LimitReader(r, security.MaxFileSize)
-			data, err := io.ReadAll(limitedReader)
-			if err != nil {
-				r.Close()
-				cancel()
+			stream = io.TeeReader(r, captured)
+		}
+		counter := &countingReader{reader: stream}
+		layers, err := readPullStream(counter, time.Now)
+		r.Close()
+		cancel()
+		if err != nil {
+			if config.ShowPullDetail {
 				lastErr = fmt.Errorf("failed to read pull output for %s: %w", security.SanitizeLogMessage(imageName), err)
-				continue
-			}
-			size = int64(len(data))
-			imageData = data
-
-			output.SecureLogMessage(config, "INFO", fmt.Sprintf("=== Completed %s ===", security.SanitizeLogMessage(imageName)))
-		} else {
-			limitedReader := io.LimitReader(r, security.MaxFileSize)
-			written, err := io.Copy(io.Discard, limitedReader)
-			if err != nil {
-				r.Close()
-				cancel()
+			} else {
 				lastErr = fmt.Errorf("failed to complete pull for %s: %w", security.SanitizeLogMessage(imageName), err)
-				continue
 			}
-			size = written
+			recordAttempt(attempt, attemptStart, layers, err)
+			continue
 		}
+		recordAttempt(attempt, attemptStart, layers, nil)
 
-		r.Close()
-		cancel()
+		size := counter.count
+		imageData := captured.Bytes()
+		if config.ShowPullDetail {
+			output.SecureLogMessage(config, "INFO", fmt.Sprintf("=== Completed %s ===", security.SanitizeLogMessage(imageName)))
+		}
 
 		var imageHash string
 		if len(imageData) > 0 {
@@ -173,22 +477,27 @@ func pullImageWithRetry(ctx context.Context, client *client.Client, imageName st
 			Success:   true,
 			Duration:  time.Since(startTime),
 			Attempts:  attempt,
+			Timeline:  timeline,
 			Size:      size,
 			ImageHash: imageHash,
 		}
 	}
 
 	return dockertypes.PullResult{
-		Image:    imageName,
-		Success:  false,
-		Error:    security.SanitizeErrorMessage(lastErr),
-		Duration: time.Since(startTime),
-		Attempts: config.MaxRetries + 1,
+		Image:         imageName,
+		Success:       false,
+		Error:         security.SanitizeErrorMessage(lastErr),
+		ErrorCategory: classifyError(lastErr),
+		Duration:      time.Since(startTime),
+		Attempts:      config.MaxRetries + 1,
+		Timeline:      timeline,
 	}
 }
 
-// PullImages orchestrates parallel pulling of multiple images with concurrency control
-func PullImages(ctx context.Context, client *client.Client, images []string, config *config.Config) []dockertypes.PullResult {
+// PullImages orchestrates parallel pulling of multiple images with concurrency control.
+// onResult, if set, is called with each result as soon as it completes. The returned
+// results are in the order of images, regardless of the order the pulls finished in.
+func PullImages(ctx context.Context, client *client.Client, images []dockertypes.ImageRef, gates Gates, onResult func(dockertypes.PullResult), config *config.Config) []dockertypes.PullResult {
 	if client == nil || config == nil {
 		return []dockertypes.PullResult{}
 	}
@@ -196,8 +505,14 @@ func PullImages(ctx context.Context, client *client.Client, images []string, con
 	tracker := &progress.ProgressTracker{}
 	tracker.SetTotal(int64(len(images)))
 
-	semaphore := make(chan struct{}, config.MaxConcurrency)
+	registryClient := NewRegistryClient(config)
+	// Free worker slots; holding one numbers the worker that handles an image
+	workers := make(chan int, config.MaxConcurrency)
+	for worker := 1; worker <= config.MaxConcurrency; worker++ {
+		workers <- worker
+	}
 	results := make(chan dockertypes.PullResult, len(images))
+	pullResults := make([]dockertypes.PullResult, len(images))
 	var wg sync.WaitGroup
 
 	var progressDone chan struct{}
@@ -218,16 +533,59 @@ func PullImages(ctx context.Context, client *client.Client, images []string, con
 		}()
 	}
 
-	for _, img := range images {
+	for i, img := range images {
 		wg.Add(1)
-		go func(imageName string) {
+		go func(i int, ref dockertypes.ImageRef) {
 			defer wg.Done()
+			imageName := ref.Name
 
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			worker := <-workers
+			defer func() { workers <- worker }()
 
 			output.SecureLogMessage(config, "INFO", fmt.Sprintf("Starting pull for: %s", security.SanitizeLogMessage(imageName)))
-			result := pullImageWithRetry(ctx, client, imageName, config)
+			startedAt := time.Now()
+			var result dockertypes.PullResult
+			if len(ref.PolicyViolations) > 0 {
+				result = policyViolationResult(ref)
+			} else if pullName, err := pullReference(ref); err != nil {
+				result = dockertypes.PullResult{Error: security.SanitizeErrorMessage(err), ErrorCategory: dockertypes.ErrorCategoryInvalid, Attempts: 1}
+			} else {
+				result = pullImageWithRetry(ctx, client, pullName, config)
+				// Post-pull steps resolve and report the image by its names
+				result.Image = imageName
+				result.CanonicalImage = ref.Canonical
+				if result.Success {
+					recordDigest(ctx, client, registryClient, ref, pullName, &result)
+				}
+				if result.Success {
+					applyPulledPolicy(gates.Policy, &result)
+				}
+				if result.Success && len(gates.SignatureKeys) > 0 {
+					verifyImageSignature(ctx, registryClient, gates.SignatureKeys, &result, config)
+				}
+				if result.Success && (gates.VulnerabilityFeed != nil || config.SBOM.Enabled) {
+					if inv, err := exportInventory(ctx, client, pullName); err != nil {
+						failInventory(&result, err, config)
+					} else {
+						if gates.VulnerabilityFeed != nil {
+							scanImageVulnerabilities(gates.VulnerabilityFeed, inv, &result, config)
+						}
+						if config.SBOM.Enabled {
+							writeImageSBOM(inv, &result, config)
+						}
+					}
+				}
+				if slices.Contains(rejectedCategories, result.ErrorCategory) {
+					removeRejectedImage(ctx, client, []string{pullName, ref.Canonical}, config)
+				}
+			}
+			result.Image = imageName
+			result.CanonicalImage = ref.Canonical
+			result.Aliases = ref.Aliases
+			result.Sources = ref.Sources
+			result.Worker = worker
+			result.StartedAt = startedAt
+			result.FinishedAt = time.Now()
 
 			if result.Success {
 				output.SecureLogMessage(config, "INFO", fmt.Sprintf("✅ Successfully pulled: %s (took %v, %d bytes)",
@@ -236,10 +594,15 @@ func PullImages(ctx context.Context, client *client.Client, images []string, con
 				output.SecureLogMessage(config, "ERROR", fmt.Sprintf("❌ Failed to pull %s after %d attempts",
 					security.SanitizeLogMessage(imageName), result.Attempts))
 			}
+			if result.Drifted {
+				output.SecureLogMessage(config, "ERROR", fmt.Sprintf("🔀 %s has drifted from the lockfile: %s",
+					security.SanitizeLogMessage(imageName), result.Error))
+			}
 
 			tracker.Increment(result.Success)
+			pullResults[i] = result
 			results <- result
-		}(img)
+		}(i, img)
 	}
 
 	go func() {
@@ -250,9 +613,10 @@ func PullImages(ctx context.Context, client *client.Client, images []string, con
 		}
 	}()
 
-	var pullResults []dockertypes.PullResult
 	for result := range results {
-		pullResults = append(pullResults, result)
+		if onResult != nil {
+			onResult(result)
+		}
 	}
 
 	return pullResults
@@ -282,6 +646,29 @@ func CleanupImages(ctx context.Context, client *client.Client, images []string,
 	}
 }
 
+// rejectedCategories are the failures of checks that run after an image was pulled
+var rejectedCategories = []dockertypes.ErrorCategory{
+	dockertypes.ErrorCategoryPolicy,
+	dockertypes.ErrorCategorySignature,
+	dockertypes.ErrorCategoryVulnerability,
+}
+
+// removeRejectedImage removes the references of an image that was pulled but then rejected,
+// so it cannot be run by accident. Images still used by a container are kept with a warning.
+func removeRejectedImage(ctx context.Context, client *client.Client, names []string, config *config.Config) {
+	removeOptions := image.RemoveOptions{PruneChildren: true}
+	for _, name := range slices.Compact(names) {
+		if _, err := client.ImageRemove(ctx, name, removeOptions); err != nil {
+			if !strings.Contains(err.Error(), "No such image:") {
+				output.SecureLogMessage(config, "WARN", fmt.Sprintf("Failed to remove rejected image %s: %s",
+					security.SanitizeLogMessage(name), security.SanitizeErrorMessage(err)))
+			}
+			continue
+		}
+		output.SecureLogMessage(config, "INFO", fmt.Sprintf("🗑️  Removed rejected image: %s", security.SanitizeLogMessage(name)))
+	}
+}
+
 // parseYAML is a helper function to parse YAML with security settings
 func parseYAML(data []byte, v interface{}) error {
 	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
@@ -0,0 +1,305 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// useTempDir returns a temporary directory that the path policy allows
+func useTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	policy := security.DefaultPathPolicy()
+	policy.AllowedRoots = append(policy.AllowedRoots, dir)
+	if err := security.SetPathPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = security.SetPathPolicy(security.DefaultPathPolicy()) })
+	return dir
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// fakeDaemon returns a Docker client talking to handler instead of a daemon
+func fakeDaemon(t *testing.T, handler http.HandlerFunc) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+server.Listener.Addr().String()),
+		client.WithHTTPClient(server.Client()), client.WithVersion("1.47"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cli.Close() })
+	return cli
+}
+
+func TestLoadContainerImagesDirectory(t *testing.T) {
+	dir := useTempDir(t)
+	writeFiles(t, dir, map[string]string{
+		"deploy/web.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.27
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`,
+		"deploy/job.yml": `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: backup
+              image: restic/restic:0.16
+`,
+		"app/Dockerfile":       "FROM golang:1.24 AS build\nFROM alpine:3.20\n",
+		"values.yaml":          "replicas: 3\n", // Unrecognized YAML is skipped in directories
+		"notes.txt":            "not-an-image\n",
+		"compose/compose.yaml": "services:\n  cache:\n    image: redis:7\n",
+	})
+
+	images, err := LoadContainerImages(&config.Config{ContainerFile: dir})
+	if err != nil {
+		t.Fatalf("LoadContainerImages() error = %v", err)
+	}
+
+	var names []string
+	for _, image := range images {
+		names = append(names, image.Name)
+	}
+	// Files are walked in lexical order
+	expected := []string{"golang:1.24", "alpine:3.20", "redis:7", "restic/restic:0.16", "nginx:1.27"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("LoadContainerImages() = %v, want %v", names, expected)
+	}
+	if want := filepath.Join(dir, "deploy/web.yaml") + ": Deployment web (container web)"; images[4].Source != want {
+		t.Errorf("source = %q, want %q", images[4].Source, want)
+	}
+}
+
+func TestLoadComposeDefaultEnvFile(t *testing.T) {
+	dir := useTempDir(t)
+	writeFiles(t, dir, map[string]string{
+		"project/compose.yaml": "services:\n  web:\n    image: nginx:${TAG:-latest}\n",
+		"project/.env":         "TAG=1.27\n",
+		"elsewhere/.env":       "TAG=wrong\n",
+	})
+	// The default .env is the one next to the compose file, not the one in the working directory
+	t.Chdir(filepath.Join(dir, "elsewhere"))
+
+	images, err := LoadContainerImages(&config.Config{ContainerFile: filepath.Join(dir, "project/compose.yaml")})
+	if err != nil {
+		t.Fatalf("LoadContainerImages() error = %v", err)
+	}
+	if len(images) != 1 || images[0].Name != "nginx:1.27" {
+		t.Errorf("LoadContainerImages() = %+v, want nginx:1.27", images)
+	}
+}
+
+func TestLoadContainerImagesEmptyDirectory(t *testing.T) {
+	dir := useTempDir(t)
+	writeFiles(t, dir, map[string]string{"values.yaml": "replicas: 3\n"})
+
+	if _, err := LoadContainerImages(&config.Config{ContainerFile: dir}); err == nil {
+		t.Error("LoadContainerImages() of a directory without images succeeded")
+	}
+}
+
+func TestParseImageList(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expected  []dockertypes.ImageSpec
+		wantError bool
+	}{
+		{
+			name:    "names and entries",
+			content: "images:\n  - nginx:1.27\n  - name: ghcr.io/org/app\n    tags: \">=1.2\"\n    tag_regex: ^v\n    latest_n: 2\n",
+			expected: []dockertypes.ImageSpec{
+				{Name: "nginx:1.27"},
+				{Name: "ghcr.io/org/app", Tags: ">=1.2", TagRegex: "^v", LatestN: 2},
+			},
+		},
+		{name: "unknown entry field", content: "images:\n  - name: ghcr.io/org/app\n    tag_regx: ^v\n", wantError: true},
+		{name: "source is not settable", content: "images:\n  - name: nginx\n    source: elsewhere\n", wantError: true},
+		{name: "unknown top-level field", content: "image:\n  - nginx\n", wantError: true},
+		{name: "wrong field type", content: "images:\n  - name: nginx\n    latest_n: two\n", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list dockertypes.ImageList
+			err := parseYAML([]byte(tt.content), &list)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("parseYAML() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && !reflect.DeepEqual(list.Images, tt.expected) {
+				t.Errorf("parseYAML() = %+v, want %+v", list.Images, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCanonicalName(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		image     string
+		expected  string
+		wantError bool
+	}{
+		{image: "nginx", expected: "docker.io/library/nginx:latest"},
+		{image: "nginx:latest", expected: "docker.io/library/nginx:latest"},
+		{image: "library/nginx:1.27", expected: "docker.io/library/nginx:1.27"},
+		{image: "docker.io/library/nginx:latest", expected: "docker.io/library/nginx:latest"},
+		{image: "index.docker.io/library/nginx", expected: "docker.io/library/nginx:latest"},
+		{image: "bitnami/redis", expected: "docker.io/bitnami/redis:latest"},
+		{image: "ghcr.io/org/app", expected: "ghcr.io/org/app:latest"},
+		{image: "localhost:5000/app:1", expected: "localhost:5000/app:1"},
+		{image: "nginx@" + digest, expected: "docker.io/library/nginx@" + digest},
+		{image: "nginx:1.27@" + digest, expected: "docker.io/library/nginx:1.27@" + digest},
+		{image: "Nginx", wantError: true},
+		{image: "nginx:", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := CanonicalName(tt.image)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("CanonicalName() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.expected {
+				t.Errorf("CanonicalName() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveImagesDeduplicates(t *testing.T) {
+	specs := []dockertypes.ImageSpec{
+		{Name: "nginx", Source: "a.txt:1"},
+		{Name: "redis:7", Source: "a.txt:2"},
+		{Name: "nginx:latest", Source: "compose.yaml: service web"},
+		{Name: "docker.io/library/nginx:latest", Source: "k8s.yaml: Deployment web (container web)"},
+		{Name: "nginx", Source: "b.txt:4"},
+		{Name: "docker.io/library/redis:7", Source: "b.txt:5"},
+	}
+
+	refs, err := ResolveImages(context.Background(), specs, &config.Config{OutputFormat: "csv"})
+	if err != nil {
+		t.Fatalf("ResolveImages() error = %v", err)
+	}
+
+	expected := []dockertypes.ImageRef{
+		{
+			Name:      "nginx",
+			Canonical: "docker.io/library/nginx:latest",
+			Aliases:   []string{"nginx:latest", "docker.io/library/nginx:latest"},
+			Sources:   []string{"a.txt:1", "compose.yaml: service web", "k8s.yaml: Deployment web (container web)", "b.txt:4"},
+		},
+		{
+			Name:      "redis:7",
+			Canonical: "docker.io/library/redis:7",
+			Aliases:   []string{"docker.io/library/redis:7"},
+			Sources:   []string{"a.txt:2", "b.txt:5"},
+		},
+	}
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("ResolveImages() = %+v, want %+v", refs, expected)
+	}
+
+	if _, err := ResolveImages(context.Background(), []dockertypes.ImageSpec{{Name: "Invalid"}}, &config.Config{OutputFormat: "csv"}); err == nil {
+		t.Error("ResolveImages() of an invalid reference succeeded")
+	}
+}
+
+func TestLoadGates(t *testing.T) {
+	dir := useTempDir(t)
+	writeFiles(t, dir, map[string]string{
+		"policy.yaml": "forbid_latest: true\n",
+		"feed.json":   "{not json",
+	})
+
+	gates, err := LoadGates(&config.Config{})
+	if err != nil || gates.Policy != nil || gates.SignatureKeys != nil || gates.VulnerabilityFeed != nil {
+		t.Errorf("LoadGates() without gates = %+v, %v", gates, err)
+	}
+	if gates, err := LoadGates(&config.Config{PolicyFile: filepath.Join(dir, "policy.yaml")}); err != nil || gates.Policy == nil {
+		t.Errorf("LoadGates() with a policy = %+v, %v", gates, err)
+	}
+
+	tests := []struct {
+		name   string
+		config config.Config
+	}{
+		{name: "missing policy", config: config.Config{PolicyFile: filepath.Join(dir, "missing.yaml")}},
+		{name: "missing signature key", config: config.Config{PolicyFile: filepath.Join(dir, "policy.yaml"),
+			Signature: config.SignatureConfig{Enabled: true, PublicKeys: []string{filepath.Join(dir, "cosign.pub")}}}},
+		{name: "invalid vulnerability feed", config: config.Config{
+			Vulnerability: config.VulnerabilityConfig{Enabled: true, Feed: filepath.Join(dir, "feed.json")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadGates(&tt.config); err == nil {
+				t.Error("LoadGates() succeeded")
+			}
+		})
+	}
+}
+
+func TestApplyDaemonRegistryConfig(t *testing.T) {
+	cli := fakeDaemon(t, func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasSuffix(req.URL.Path, "/info") {
+			t.Errorf("unexpected request %s", req.URL.Path)
+		}
+		fmt.Fprint(w, `{"RegistryConfig":{"InsecureRegistryCIDRs":["127.0.0.0/8"],"IndexConfigs":{
+			"docker.io":{"Name":"docker.io","Secure":true},
+			"harbor.local":{"Name":"harbor.local","Secure":false}}}}`)
+	})
+
+	config := &config.Config{Registry: config.RegistryConfig{Insecure: []string{"harbor.local", "10.0.0.0/8"}}}
+	ApplyDaemonRegistryConfig(context.Background(), cli, config)
+
+	expected := []string{"10.0.0.0/8", "127.0.0.0/8", "harbor.local"}
+	if !reflect.DeepEqual(config.Registry.Insecure, expected) {
+		t.Errorf("insecure registries = %v, want %v", config.Registry.Insecure, expected)
+	}
+}
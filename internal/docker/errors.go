@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/client"
+
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// classifyError maps an error from the daemon or a registry to a report category
+func classifyError(err error) dockertypes.ErrorCategory {
+	if err == nil {
+		return ""
+	}
+
+	var statusErr *registry.StatusError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || cerrdefs.IsDeadlineExceeded(err):
+		return dockertypes.ErrorCategoryTimeout
+	case cerrdefs.IsResourceExhausted(err) || strings.Contains(strings.ToLower(err.Error()), "toomanyrequests"):
+		return dockertypes.ErrorCategoryRateLimit
+	case cerrdefs.IsUnauthorized(err) || cerrdefs.IsPermissionDenied(err):
+		return dockertypes.ErrorCategoryAuth
+	case cerrdefs.IsNotFound(err):
+		return dockertypes.ErrorCategoryNotFound
+	case cerrdefs.IsInvalidArgument(err):
+		return dockertypes.ErrorCategoryInvalid
+	case errors.As(err, &statusErr):
+		return classifyStatus(statusErr.StatusCode)
+	case client.IsErrConnectionFailed(err) || cerrdefs.IsUnavailable(err):
+		return dockertypes.ErrorCategoryNetwork
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return dockertypes.ErrorCategoryTimeout
+		}
+		return dockertypes.ErrorCategoryNetwork
+	case cerrdefs.IsInternal(err) || cerrdefs.IsUnknown(err):
+		return dockertypes.ErrorCategoryDaemon
+	}
+	return dockertypes.ErrorCategoryUnknown
+}
+
+// classifyStatus maps a registry HTTP status code to a report category
+func classifyStatus(code int) dockertypes.ErrorCategory {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return dockertypes.ErrorCategoryAuth
+	case code == http.StatusNotFound:
+		return dockertypes.ErrorCategoryNotFound
+	case code == http.StatusTooManyRequests:
+		return dockertypes.ErrorCategoryRateLimit
+	case code >= http.StatusInternalServerError:
+		return dockertypes.ErrorCategoryNetwork
+	}
+	return dockertypes.ErrorCategoryUnknown
+}
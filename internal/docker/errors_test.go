@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want dockertypes.ErrorCategory
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "deadline", err: fmt.Errorf("attempt 1 failed: %w", context.DeadlineExceeded), want: dockertypes.ErrorCategoryTimeout},
+		{name: "not found", err: cerrdefs.ErrNotFound.WithMessage("manifest unknown"), want: dockertypes.ErrorCategoryNotFound},
+		{name: "unauthorized", err: cerrdefs.ErrUnauthenticated, want: dockertypes.ErrorCategoryAuth},
+		{name: "rate limit message", err: errors.New("toomanyrequests: You have reached your pull rate limit"), want: dockertypes.ErrorCategoryRateLimit},
+		{name: "registry 429", err: fmt.Errorf("digest lookup: %w", &registry.StatusError{StatusCode: 429}), want: dockertypes.ErrorCategoryRateLimit},
+		{name: "registry 403", err: &registry.StatusError{StatusCode: 403}, want: dockertypes.ErrorCategoryAuth},
+		{name: "registry 503", err: &registry.StatusError{StatusCode: 503}, want: dockertypes.ErrorCategoryNetwork},
+		{name: "dial", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: dockertypes.ErrorCategoryNetwork},
+		{name: "daemon", err: cerrdefs.ErrInternal, want: dockertypes.ErrorCategoryDaemon},
+		{name: "other", err: errors.New("something odd"), want: dockertypes.ErrorCategoryUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package docker
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/policy"
+	"github.com/guessi/docker-parallel-pull/internal/vuln"
+)
+
+// Gates are the checks applied to every image before and after it is pulled.
+// A nil or empty field means the check is disabled.
+type Gates struct {
+	Policy            *policy.Policy
+	SignatureKeys     []crypto.PublicKey
+	VulnerabilityFeed *vuln.Feed
+}
+
+// LoadGates loads the image policy, signature keys and vulnerability feed enabled in the config
+func LoadGates(config *config.Config) (Gates, error) {
+	var gates Gates
+	var err error
+	if gates.Policy, err = loadPolicy(config); err != nil {
+		return Gates{}, fmt.Errorf("failed to load policy: %w", err)
+	}
+	if gates.SignatureKeys, err = loadSignatureKeys(config); err != nil {
+		return Gates{}, fmt.Errorf("failed to load signature keys: %w", err)
+	}
+	if gates.VulnerabilityFeed, err = loadVulnerabilityFeed(config); err != nil {
+		return Gates{}, fmt.Errorf("failed to load vulnerability feed: %w", err)
+	}
+	return gates, nil
+}
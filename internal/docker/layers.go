@@ -0,0 +1,142 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// pullMessage is one JSON progress message of the daemon's image pull stream
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+// Layer progress statuses; other messages with an ID, such as "Pulling from", refer to the tag
+const (
+	layerQueued     = "Pulling fs layer"
+	layerWaiting    = "Waiting"
+	layerDownload   = "Downloading"
+	layerVerifying  = "Verifying Checksum"
+	layerDownloaded = "Download complete"
+	layerExtracting = "Extracting"
+	layerComplete   = "Pull complete"
+	layerExists     = "Already exists"
+)
+
+// layerTracker collects the layer timings of one pull attempt in the order the layers were announced
+type layerTracker struct {
+	now         func() time.Time
+	layers      []dockertypes.Layer
+	index       map[string]int
+	downloading map[string]bool
+}
+
+// readPullStream consumes the pull stream to its end, returning the layers it
+// reported. Only failures to read the stream are errors: layer timings are
+// best effort, so output that cannot be decoded ends them and is drained.
+func readPullStream(stream io.Reader, now func() time.Time) ([]dockertypes.Layer, error) {
+	tracker := &layerTracker{now: now, index: make(map[string]int), downloading: make(map[string]bool)}
+	reader := &errorReader{reader: stream}
+	decoder := json.NewDecoder(reader)
+	for {
+		var message pullMessage
+		if err := decoder.Decode(&message); err != nil {
+			if reader.err != nil {
+				return tracker.layers, reader.err
+			}
+			if errors.Is(err, io.EOF) {
+				return tracker.layers, nil
+			}
+			_, err := io.Copy(io.Discard, reader)
+			return tracker.layers, err
+		}
+		tracker.observe(message)
+	}
+}
+
+// observe updates the layer a progress message refers to
+func (t *layerTracker) observe(message pullMessage) {
+	switch message.Status {
+	case layerQueued, layerWaiting, layerDownload, layerVerifying, layerDownloaded, layerExtracting, layerComplete, layerExists:
+	default:
+		return
+	}
+	if message.ID == "" {
+		return
+	}
+
+	now := t.now()
+	i, ok := t.index[message.ID]
+	if !ok {
+		// Layers are timed from their announcement until they start downloading
+		i = len(t.layers)
+		t.index[message.ID] = i
+		t.layers = append(t.layers, dockertypes.Layer{ID: message.ID, StartedAt: now})
+	}
+	layer := &t.layers[i]
+
+	switch message.Status {
+	case layerDownload:
+		if !t.downloading[message.ID] {
+			t.downloading[message.ID] = true
+			layer.StartedAt = now
+		}
+		if message.ProgressDetail.Total > 0 {
+			layer.Bytes = message.ProgressDetail.Total
+		}
+	case layerExists:
+		layer.Cached = true
+		layer.FinishedAt = now
+	case layerComplete:
+		layer.FinishedAt = now
+	}
+}
+
+// errorReader records the first error of a reader other than io.EOF, so read
+// failures can be told apart from output that cannot be decoded
+type errorReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (e *errorReader) Read(p []byte) (int, error) {
+	n, err := e.reader.Read(p)
+	if err != nil && err != io.EOF && e.err == nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
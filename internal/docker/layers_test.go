@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestReadPullStream(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"status":"Pulling from library/nginx","id":"latest"}`,
+		`{"status":"Already exists","progressDetail":{},"id":"aaa"}`,
+		`{"status":"Pulling fs layer","progressDetail":{},"id":"bbb"}`,
+		`{"status":"Waiting","progressDetail":{},"id":"bbb"}`,
+		`{"status":"Downloading","progressDetail":{"current":512,"total":2048},"id":"bbb"}`,
+		`{"status":"Downloading","progressDetail":{"current":2048,"total":2048},"id":"bbb"}`,
+		`{"status":"Download complete","progressDetail":{},"id":"bbb"}`,
+		`{"status":"Extracting","progressDetail":{"current":2048,"total":2048},"id":"bbb"}`,
+		`{"status":"Pull complete","progressDetail":{},"id":"bbb"}`,
+		`{"status":"Digest: sha256:0123"}`,
+		`{"status":"Status: Downloaded newer image for nginx:latest"}`,
+	}, "\n")
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tick := 0
+	now := func() time.Time {
+		tick++
+		return base.Add(time.Duration(tick) * time.Second)
+	}
+
+	layers, err := readPullStream(strings.NewReader(stream), now)
+	if err != nil {
+		t.Fatalf("readPullStream() error = %v", err)
+	}
+	if len(layers) != 2 {
+		t.Fatalf("readPullStream() layers = %+v", layers)
+	}
+
+	cached := layers[0]
+	if cached.ID != "aaa" || !cached.Cached || cached.FinishedAt.IsZero() {
+		t.Errorf("cached layer = %+v", cached)
+	}
+
+	downloaded := layers[1]
+	if downloaded.ID != "bbb" || downloaded.Cached || downloaded.Bytes != 2048 {
+		t.Errorf("downloaded layer = %+v", downloaded)
+	}
+	// Timed from the first Downloading message (tick 4) to Pull complete (tick 8)
+	if got := downloaded.FinishedAt.Sub(downloaded.StartedAt); got != 4*time.Second {
+		t.Errorf("downloaded layer took %v, want 4s", got)
+	}
+}
+
+func TestReadPullStreamErrors(t *testing.T) {
+	// Errors reported inside the stream do not fail the attempt
+	stream := `{"status":"Pulling fs layer","progressDetail":{},"id":"bbb"}
+{"errorDetail":{"message":"toomanyrequests: rate limit exceeded"},"error":"toomanyrequests: rate limit exceeded"}`
+	layers, err := readPullStream(strings.NewReader(stream), time.Now)
+	if err != nil {
+		t.Fatalf("readPullStream() error = %v", err)
+	}
+	if len(layers) != 1 || !layers[0].FinishedAt.IsZero() {
+		t.Errorf("readPullStream() layers = %+v", layers)
+	}
+
+	// Output that cannot be decoded ends the layer timings and is drained
+	reader := strings.NewReader(`{"status":"Pulling fs layer","id":"aaa"}` + "\nnot json\n" + strings.Repeat("x", 64*1024))
+	layers, err = readPullStream(reader, time.Now)
+	if err != nil || len(layers) != 1 || reader.Len() != 0 {
+		t.Errorf("readPullStream() = %+v, %v with %d bytes left", layers, err, reader.Len())
+	}
+	if _, err := readPullStream(strings.NewReader(`{"status":`), time.Now); err != nil {
+		t.Errorf("readPullStream() of truncated output error = %v", err)
+	}
+
+	// Failures to read the stream are returned
+	readErr := errors.New("connection reset by peer")
+	if _, err := readPullStream(io.MultiReader(strings.NewReader(`{"status":"Waiting","id":"aaa"}`), iotest.ErrReader(readErr)), time.Now); !errors.Is(err, readErr) {
+		t.Errorf("readPullStream() error = %v, want %v", err, readErr)
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buffer := &limitedBuffer{limit: 5}
+	for _, chunk := range []string{"abc", "defg", "hij"} {
+		if n, err := buffer.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Errorf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if got := buffer.String(); got != "abcde" {
+		t.Errorf("limitedBuffer kept %q, want abcde", got)
+	}
+}
@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/lockfile"
+	"github.com/guessi/docker-parallel-pull/internal/output"
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// ApplyLockfile pins every image to the digest recorded in the lockfile when running in locked mode
+func ApplyLockfile(images []dockertypes.ImageRef, config *config.Config) error {
+	if config == nil || !config.Locked {
+		return nil
+	}
+
+	lock, err := lockfile.Load(config.Lockfile)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for i := range images {
+		entry, ok := lock.Lookup(images[i].Name, images[i].Canonical)
+		if !ok {
+			missing = append(missing, images[i].Name)
+			continue
+		}
+		images[i].LockedDigest = entry.Digest
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("images missing from lockfile: %s", security.SanitizeLogMessage(strings.Join(missing, ", ")))
+	}
+	return nil
+}
+
+// WriteLockfile records the resolved digest of every pulled image.
+// The lockfile is only replaced when all pulls succeeded.
+func WriteLockfile(results []dockertypes.PullResult, config *config.Config) error {
+	if config == nil || config.Lockfile == "" || config.Locked {
+		return nil
+	}
+
+	lock := lockfile.New()
+	for _, result := range results {
+		if !result.Success || result.Digest == "" {
+			return fmt.Errorf("lockfile not updated: %s was not pulled or has no digest", security.SanitizeLogMessage(result.Image))
+		}
+
+		entry := lockfile.Entry{Canonical: result.CanonicalImage, Digest: result.Digest}
+		lock.Images[result.Image] = entry
+		for _, alias := range result.Aliases {
+			lock.Images[alias] = entry
+		}
+	}
+
+	if err := lock.Write(config.Lockfile); err != nil {
+		return err
+	}
+	output.SecureLogMessage(config, "INFO", fmt.Sprintf("Wrote lockfile with %d entries", len(lock.Images)))
+	return nil
+}
+
+// pullReference returns the reference to pull for an image: its digest in locked mode, its canonical name otherwise
+func pullReference(ref dockertypes.ImageRef) (string, error) {
+	if ref.LockedDigest == "" {
+		return ref.Canonical, nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(ref.Canonical)
+	if err != nil {
+		return "", err
+	}
+	return named.Name() + "@" + ref.LockedDigest, nil
+}
+
+// recordDigest resolves the digest and size of a successfully pulled image. In locked mode the digest-pulled
+// image is tagged with its canonical name (see lockedTag) and the result fails if the tag has drifted from the lockfile.
+func recordDigest(ctx context.Context, client *client.Client, registryClient *registry.Client, ref dockertypes.ImageRef, pulled string, result *dockertypes.PullResult) {
+	if ref.LockedDigest != "" {
+		if tag, ok := lockedTag(ref.Canonical); ok {
+			if err := client.ImageTag(ctx, pulled, tag); err != nil {
+				result.Success = false
+				result.Error = security.SanitizeErrorMessage(fmt.Errorf("failed to tag locked image: %w", err))
+				result.ErrorCategory = classifyError(err)
+				return
+			}
+		}
+	}
+
+	if inspect, err := client.ImageInspect(ctx, ref.Canonical); err == nil {
+		result.ImageSize = inspect.Size
+		result.Digest, _ = repoDigest(inspect, ref.Canonical)
+	}
+
+	if ref.LockedDigest == "" {
+		return
+	}
+	result.Digest = ref.LockedDigest
+	result.LockedDigest = ref.LockedDigest
+
+	named, err := reference.ParseNormalizedNamed(ref.Canonical)
+	if err != nil {
+		result.Success = false
+		result.Error = security.SanitizeErrorMessage(err)
+		result.ErrorCategory = dockertypes.ErrorCategoryInvalid
+		return
+	}
+	current, err := registryClient.ManifestDigest(ctx, named)
+	if err != nil {
+		result.Success = false
+		result.Error = fmt.Sprintf("cannot verify lockfile digest: %s", security.SanitizeErrorMessage(err))
+		result.ErrorCategory = classifyError(err)
+		return
+	}
+	if current != ref.LockedDigest {
+		result.Success = false
+		result.Drifted = true
+		result.Error = fmt.Sprintf("digest drift: tag now resolves to %s, lockfile has %s", current, ref.LockedDigest)
+		result.ErrorCategory = dockertypes.ErrorCategoryDrift
+	}
+}
+
+// lockedTag returns the tag a digest-pulled image receives in locked mode: the canonical name without
+// its digest. Digest-pinned references without a tag are not tagged, as the daemon cannot tag a digest.
+func lockedTag(canonical string) (string, bool) {
+	named, err := reference.ParseNormalizedNamed(canonical)
+	if err != nil {
+		return canonical, true
+	}
+	if _, ok := named.(reference.Digested); !ok {
+		return canonical, true
+	}
+	tagged, ok := named.(reference.Tagged)
+	if !ok {
+		return "", false
+	}
+	withTag, err := reference.WithTag(reference.TrimNamed(named), tagged.Tag())
+	if err != nil {
+		return "", false
+	}
+	return withTag.String(), true
+}
+
+// localDigest returns the repository digest of a local image for the repository of imageName
+func localDigest(ctx context.Context, client *client.Client, imageName string) (string, error) {
+	inspect, err := client.ImageInspect(ctx, imageName)
+	if err != nil {
+		return "", err
+	}
+	return repoDigest(inspect, imageName)
+}
+
+// repoDigest finds the repository digest of inspected image data for the repository of imageName
+func repoDigest(inspect image.InspectResponse, imageName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, repoDigest := range inspect.RepoDigests {
+		digested, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil || digested.Name() != named.Name() {
+			continue
+		}
+		if canonical, ok := digested.(reference.Canonical); ok {
+			return canonical.Digest().String(), nil
+		}
+	}
+	return "", fmt.Errorf("no repository digest for %s", security.SanitizeLogMessage(imageName))
+}
@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestRecordDigestLocked(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	remote := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer remote.Close()
+	host := strings.TrimPrefix(remote.URL, "https://")
+	// The test registry uses a self-signed certificate
+	registryClient := registry.NewClient(registry.Options{Insecure: []string{host}})
+
+	tests := []struct {
+		name      string
+		canonical string
+		wantTag   string // Empty when the pulled image must not be tagged
+	}{
+		{name: "tag", canonical: host + "/app:1.0", wantTag: host + "/app:1.0"},
+		{name: "digest pinned", canonical: host + "/app@" + digest},
+		{name: "tag and digest pinned", canonical: host + "/app:1.0@" + digest, wantTag: host + "/app:1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var tagged []string
+			cli := fakeDaemon(t, func(w http.ResponseWriter, req *http.Request) {
+				switch {
+				case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/tag"):
+					mu.Lock()
+					tagged = append(tagged, req.URL.Query().Get("repo")+":"+req.URL.Query().Get("tag"))
+					mu.Unlock()
+					w.WriteHeader(http.StatusCreated)
+				case strings.HasSuffix(req.URL.Path, "/json"):
+					fmt.Fprintf(w, `{"Id":"sha256:abc","Size":42,"RepoDigests":[%q]}`, host+"/app@"+digest)
+				default:
+					t.Errorf("unexpected daemon request %s %s", req.Method, req.URL)
+				}
+			})
+
+			ref := dockertypes.ImageRef{Name: tt.canonical, Canonical: tt.canonical, LockedDigest: digest}
+			pulled, err := pullReference(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := dockertypes.PullResult{Success: true}
+			recordDigest(context.Background(), cli, registryClient, ref, pulled, &result)
+
+			if !result.Success || result.Digest != digest || result.ImageSize != 42 {
+				t.Errorf("recordDigest() result = %+v", result)
+			}
+			var want []string
+			if tt.wantTag != "" {
+				want = []string{tt.wantTag}
+			}
+			if fmt.Sprint(tagged) != fmt.Sprint(want) {
+				t.Errorf("tagged %v, want %v", tagged, want)
+			}
+		})
+	}
+}
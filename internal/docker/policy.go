@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/output"
+	"github.com/guessi/docker-parallel-pull/internal/policy"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// loadPolicy loads the configured image policy, returning nil when no policy is configured
+func loadPolicy(config *config.Config) (*policy.Policy, error) {
+	if config == nil || config.PolicyFile == "" {
+		return nil, nil
+	}
+	return policy.Load(config.PolicyFile)
+}
+
+// ApplyPolicy evaluates the image policy against every resolved image before any pull.
+// Images violating the policy are kept in the list so they are reported, but are not pulled.
+func ApplyPolicy(images []dockertypes.ImageRef, imagePolicy *policy.Policy, config *config.Config) {
+	if imagePolicy == nil {
+		return
+	}
+
+	for i := range images {
+		images[i].PolicyViolations = imagePolicy.Evaluate(images[i].Canonical, images[i].LockedDigest)
+		for _, violation := range images[i].PolicyViolations {
+			output.SecureLogMessage(config, "WARN", fmt.Sprintf("Policy violation for %s: %s",
+				security.SanitizeLogMessage(images[i].Name), violation))
+		}
+	}
+}
+
+// policyViolationResult builds the result of an image rejected by the policy before pulling
+func policyViolationResult(ref dockertypes.ImageRef) dockertypes.PullResult {
+	return dockertypes.PullResult{
+		Success:          false,
+		Error:            "policy violation: " + strings.Join(ref.PolicyViolations, "; "),
+		ErrorCategory:    dockertypes.ErrorCategoryPolicy,
+		PolicyViolations: ref.PolicyViolations,
+	}
+}
+
+// applyPulledPolicy evaluates post-pull policy rules against the inspect data of a pulled image
+func applyPulledPolicy(imagePolicy *policy.Policy, result *dockertypes.PullResult) {
+	violations := imagePolicy.EvaluatePulled(result.ImageSize)
+	if len(violations) == 0 {
+		return
+	}
+	result.Success = false
+	result.PolicyViolations = append(result.PolicyViolations, violations...)
+	result.Error = "policy violation: " + strings.Join(violations, "; ")
+	result.ErrorCategory = dockertypes.ErrorCategoryPolicy
+}
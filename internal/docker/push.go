@@ -0,0 +1,200 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/mirror"
+	"github.com/guessi/docker-parallel-pull/internal/output"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// encodeMirrorAuth builds the X-Registry-Auth value for the mirror registry of target
+func encodeMirrorAuth(target string, mirrorConfig *config.MirrorConfig) (string, error) {
+	if mirrorConfig.Username == "" {
+		return "", nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(target)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse mirror reference: %w", err)
+	}
+
+	password := os.Getenv(mirrorConfig.PasswordEnv)
+	if password == "" {
+		return "", fmt.Errorf("environment variable %s is empty", mirrorConfig.PasswordEnv)
+	}
+
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      mirrorConfig.Username,
+		Password:      password,
+		ServerAddress: reference.Domain(named),
+	})
+}
+
+// streamMessage is a single JSON progress message emitted by the daemon
+type streamMessage struct {
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// readPushStream drains a push progress stream and returns the first error reported by the daemon
+func readPushStream(r io.Reader) error {
+	decoder := json.NewDecoder(io.LimitReader(r, security.MaxFileSize))
+	for {
+		var msg streamMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+	}
+}
+
+// pushImageWithRetry retags a pulled image and pushes it to its mirror target with retry logic
+func pushImageWithRetry(ctx context.Context, client *client.Client, imageName, target string, config *config.Config) dockertypes.PushResult {
+	startTime := time.Now()
+	mirrorConfig := &config.Mirror
+	var lastErr error
+
+	if err := client.ImageTag(ctx, imageName, target); err != nil {
+		return dockertypes.PushResult{
+			Target:   target,
+			Success:  false,
+			Error:    security.SanitizeErrorMessage(fmt.Errorf("failed to tag image: %w", err)),
+			Duration: time.Since(startTime),
+			Attempts: 1,
+		}
+	}
+
+	registryAuth, err := encodeMirrorAuth(target, mirrorConfig)
+	if err != nil {
+		return dockertypes.PushResult{
+			Target:   target,
+			Success:  false,
+			Error:    security.SanitizeErrorMessage(fmt.Errorf("failed to prepare registry auth: %w", err)),
+			Duration: time.Since(startTime),
+			Attempts: 1,
+		}
+	}
+
+	for attempt := 1; attempt <= mirrorConfig.MaxRetries+1; attempt++ {
+		pushCtx, cancel := context.WithTimeout(ctx, mirrorConfig.Timeout)
+
+		r, err := client.ImagePush(pushCtx, target, image.PushOptions{RegistryAuth: registryAuth})
+		if err == nil {
+			err = readPushStream(r)
+			r.Close()
+		}
+		cancel()
+
+		if err == nil {
+			return dockertypes.PushResult{
+				Target:   target,
+				Success:  true,
+				Duration: time.Since(startTime),
+				Attempts: attempt,
+			}
+		}
+
+		lastErr = fmt.Errorf("attempt %d failed to push image %s: %w", attempt, security.SanitizeLogMessage(target), err)
+		if attempt <= mirrorConfig.MaxRetries {
+			delay := calculateBackoffDelay(attempt, mirrorConfig.RetryDelay)
+			output.SecureLogMessage(config, "WARN", fmt.Sprintf("Push failed for %s (attempt %d/%d), retrying in %v",
+				security.SanitizeLogMessage(target), attempt, mirrorConfig.MaxRetries+1, delay))
+			time.Sleep(delay)
+		}
+	}
+
+	return dockertypes.PushResult{
+		Target:   target,
+		Success:  false,
+		Error:    security.SanitizeErrorMessage(lastErr),
+		Duration: time.Since(startTime),
+		Attempts: mirrorConfig.MaxRetries + 1,
+	}
+}
+
+// PushImages retags every successfully pulled image according to the mirror rules
+// and pushes it to the mirror registry, recording the outcome in each result
+func PushImages(ctx context.Context, client *client.Client, results []dockertypes.PullResult, config *config.Config) {
+	if client == nil || config == nil || !config.Mirror.Enabled {
+		return
+	}
+
+	semaphore := make(chan struct{}, config.Mirror.MaxConcurrency)
+	var wg sync.WaitGroup
+
+	for i := range results {
+		if !results[i].Success {
+			continue
+		}
+
+		target, ok, err := mirror.Rewrite(config.Mirror.Rules, results[i].CanonicalImage)
+		if err != nil {
+			results[i].Push = &dockertypes.PushResult{
+				Success: false,
+				Error:   security.SanitizeErrorMessage(err),
+			}
+			continue
+		}
+		if !ok {
+			output.SecureLogMessage(config, "INFO", fmt.Sprintf("No mirror rule matches %s, skipping push",
+				security.SanitizeLogMessage(results[i].Image)))
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *dockertypes.PullResult, target string) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			output.SecureLogMessage(config, "INFO", fmt.Sprintf("Pushing %s to %s",
+				security.SanitizeLogMessage(result.Image), security.SanitizeLogMessage(target)))
+			push := pushImageWithRetry(ctx, client, result.CanonicalImage, target, config)
+
+			if push.Success {
+				output.SecureLogMessage(config, "INFO", fmt.Sprintf("✅ Successfully pushed: %s (took %v)",
+					security.SanitizeLogMessage(target), push.Duration.Round(time.Second)))
+			} else {
+				output.SecureLogMessage(config, "ERROR", fmt.Sprintf("❌ Failed to push %s after %d attempts",
+					security.SanitizeLogMessage(target), push.Attempts))
+			}
+
+			result.Push = &push
+		}(&results[i], target)
+	}
+
+	wg.Wait()
+}
+
+// MirrorTargets returns the mirror references created by PushImages
+func MirrorTargets(results []dockertypes.PullResult) []string {
+	var targets []string
+	for _, result := range results {
+		if result.Push != nil && result.Push.Target != "" {
+			targets = append(targets, result.Push.Target)
+		}
+	}
+	return targets
+}
@@ -0,0 +1,55 @@
+package docker
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/inventory"
+	"github.com/guessi/docker-parallel-pull/internal/output"
+	"github.com/guessi/docker-parallel-pull/internal/sbom"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// writeImageSBOM writes the SBOM of a pulled image to the output directory, named
+// after its digest, and records the path in the result. Images whose SBOM cannot
+// be written fail, so every successfully pulled image has an SBOM. Package databases
+// that cannot be read are recorded in the SBOM and the result, and warned about.
+func writeImageSBOM(inv *inventory.Inventory, result *dockertypes.PullResult, config *config.Config) {
+	err := func() error {
+		if result.Digest == "" {
+			return fmt.Errorf("image digest is unknown")
+		}
+
+		data, err := sbom.Generate(config.SBOM.Format, sbom.Image{Name: result.CanonicalImage, Digest: result.Digest}, inv, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if err := security.SecureMkdirAll(config.SBOM.OutputDir); err != nil {
+			return fmt.Errorf("cannot create output directory: %s", security.SanitizeErrorMessage(err))
+		}
+		filename := filepath.Join(config.SBOM.OutputDir, sbom.FileName(result.Digest, config.SBOM.Format))
+		if err := security.WriteFileAtomic(filename, data); err != nil {
+			return err
+		}
+
+		result.SBOM = filename
+		result.SBOMUnscanned = inv.Unsupported
+		return nil
+	}()
+
+	for _, database := range result.SBOMUnscanned {
+		output.SecureLogMessage(config, "WARN", fmt.Sprintf("SBOM of %s does not list the packages of %s: unsupported package database",
+			security.SanitizeLogMessage(result.Image), database))
+	}
+	if err != nil {
+		output.SecureLogMessage(config, "ERROR", fmt.Sprintf("Failed to write SBOM of %s: %s",
+			security.SanitizeLogMessage(result.Image), security.SanitizeErrorMessage(err)))
+		result.Success = false
+		result.Error = "SBOM generation failed: " + security.SanitizeErrorMessage(err)
+		result.ErrorCategory = dockertypes.ErrorCategoryInventory
+	}
+}
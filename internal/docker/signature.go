@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+
+	"github.com/distribution/reference"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/output"
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/signature"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// loadSignatureKeys loads the configured public keys, returning nil when verification is disabled
+func loadSignatureKeys(config *config.Config) ([]crypto.PublicKey, error) {
+	if config == nil || !config.Signature.Enabled {
+		return nil, nil
+	}
+	return signature.LoadPublicKeys(config.Signature.PublicKeys)
+}
+
+// verifyImageSignature verifies the cosign signature of a pulled image's digest and records the outcome.
+// In enforce mode an image without a verified signature fails.
+func verifyImageSignature(ctx context.Context, registryClient *registry.Client, keys []crypto.PublicKey, result *dockertypes.PullResult, config *config.Config) {
+	status := signature.StatusError
+	var err error
+
+	if result.Digest == "" {
+		err = fmt.Errorf("image digest is unknown")
+	} else if named, parseErr := reference.ParseNormalizedNamed(result.CanonicalImage); parseErr != nil {
+		err = parseErr
+	} else {
+		status, err = signature.Verify(ctx, registryClient, named, result.Digest, keys)
+	}
+
+	result.Signature = string(status)
+	if err != nil {
+		result.SignatureError = security.SanitizeErrorMessage(err)
+	}
+	if status == signature.StatusVerified {
+		return
+	}
+
+	message := fmt.Sprintf("Signature of %s is %s", security.SanitizeLogMessage(result.Image), status)
+	if result.SignatureError != "" {
+		message += ": " + result.SignatureError
+	}
+
+	if config.Signature.Mode == "enforce" {
+		output.SecureLogMessage(config, "ERROR", message)
+		result.Success = false
+		result.Error = "signature verification failed: " + string(status)
+		result.ErrorCategory = dockertypes.ErrorCategorySignature
+		return
+	}
+	output.SecureLogMessage(config, "WARN", message)
+}
@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/client"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/inventory"
+	"github.com/guessi/docker-parallel-pull/internal/output"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+	"github.com/guessi/docker-parallel-pull/internal/vuln"
+)
+
+// loadVulnerabilityFeed loads the configured feed, returning nil when the gate is disabled
+func loadVulnerabilityFeed(config *config.Config) (*vuln.Feed, error) {
+	if config == nil || !config.Vulnerability.Enabled {
+		return nil, nil
+	}
+	return vuln.LoadFeed(config.Vulnerability.Feed)
+}
+
+// exportInventory reads the package inventory of a pulled image from its daemon export
+func exportInventory(ctx context.Context, client *client.Client, pullName string) (*inventory.Inventory, error) {
+	archive, err := client.ImageSave(ctx, []string{pullName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export image: %w", err)
+	}
+	defer archive.Close()
+
+	return inventory.Extract(archive)
+}
+
+// scanImageVulnerabilities matches the package inventory of a pulled image against
+// the feed and fails the result if any finding reaches the fail_on severity. Package
+// databases that cannot be read, such as RPM, are skipped with a warning and listed
+// in the report as unsupported.
+func scanImageVulnerabilities(feed *vuln.Feed, inv *inventory.Inventory, result *dockertypes.PullResult, config *config.Config) {
+	report := &dockertypes.VulnerabilityReport{
+		Packages:    len(inv.Packages),
+		Counts:      make(map[string]int),
+		Unsupported: inv.Unsupported,
+	}
+	threshold := vuln.SeverityRank(config.Vulnerability.FailOn)
+	for _, finding := range feed.Match(inv) {
+		report.Counts[finding.Severity]++
+		if vuln.SeverityRank(finding.Severity) >= threshold {
+			report.Blocking = append(report.Blocking, finding.String())
+		}
+	}
+	result.Vulnerabilities = report
+
+	if len(inv.Unsupported) > 0 {
+		output.SecureLogMessage(config, "WARN", fmt.Sprintf("🐞 Skipping vulnerability scan of unsupported package database %s in %s",
+			strings.Join(inv.Unsupported, ", "), security.SanitizeLogMessage(result.Image)))
+	}
+	if len(report.Blocking) > 0 {
+		output.SecureLogMessage(config, "ERROR", fmt.Sprintf("🐞 %s has %d vulnerabilities at or above %s severity",
+			security.SanitizeLogMessage(result.Image), len(report.Blocking), config.Vulnerability.FailOn))
+		result.Success = false
+		result.Error = fmt.Sprintf("vulnerability gate: %d findings at or above %s severity", len(report.Blocking), config.Vulnerability.FailOn)
+		result.ErrorCategory = dockertypes.ErrorCategoryVulnerability
+	}
+}
+
+// failInventory fails a result whose package inventory could not be read
+func failInventory(result *dockertypes.PullResult, err error, config *config.Config) {
+	output.SecureLogMessage(config, "ERROR", fmt.Sprintf("Failed to read package inventory of %s: %s",
+		security.SanitizeLogMessage(result.Image), security.SanitizeErrorMessage(err)))
+	result.Success = false
+	result.Error = "package inventory failed: " + security.SanitizeErrorMessage(err)
+	result.ErrorCategory = dockertypes.ErrorCategoryInventory
+}
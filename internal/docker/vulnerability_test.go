@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/inventory"
+	dockertypes "github.com/guessi/docker-parallel-pull/internal/types"
+	"github.com/guessi/docker-parallel-pull/internal/vuln"
+)
+
+func TestScanImageVulnerabilities(t *testing.T) {
+	feed, err := vuln.NewFeed([]vuln.Vulnerability{
+		{ID: "CVE-1", Ecosystem: "deb", Distro: "debian", Package: "openssl", Fixed: "3.0.13-1~deb12u1", Severity: "high"},
+		{ID: "CVE-2", Ecosystem: "deb", Package: "bash", Severity: "low"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{OutputFormat: "csv", Vulnerability: config.VulnerabilityConfig{Enabled: true, FailOn: "high"}}
+	debian := inventory.Distro{ID: "debian", VersionID: "12"}
+
+	tests := []struct {
+		name      string
+		inventory inventory.Inventory
+		success   bool
+	}{
+		{
+			name: "findings below threshold",
+			inventory: inventory.Inventory{Distro: debian, Packages: []inventory.Package{
+				{Name: "bash", Version: "5.2.15-2+b2", Ecosystem: "deb"},
+			}},
+			success: true,
+		},
+		{
+			name: "blocking finding",
+			inventory: inventory.Inventory{Distro: debian, Packages: []inventory.Package{
+				{Name: "libssl3", Version: "3.0.11-1~deb12u1", Source: "openssl", Ecosystem: "deb"},
+			}},
+		},
+		{
+			name:      "unsupported package database is skipped",
+			inventory: inventory.Inventory{Distro: inventory.Distro{ID: "rhel", VersionID: "9.4"}, Unsupported: []string{"var/lib/rpm/rpmdb.sqlite"}},
+			success:   true,
+		},
+		{
+			name: "readable packages next to an unsupported database are gated",
+			inventory: inventory.Inventory{Distro: debian, Unsupported: []string{"var/lib/rpm/rpmdb.sqlite"}, Packages: []inventory.Package{
+				{Name: "libssl3", Version: "3.0.11-1~deb12u1", Source: "openssl", Ecosystem: "deb"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := dockertypes.PullResult{Image: "app", Success: true}
+			scanImageVulnerabilities(feed, &tt.inventory, &result, cfg)
+			if result.Success != tt.success {
+				t.Errorf("success = %v, want %v (%s)", result.Success, tt.success, result.Error)
+			}
+			if !tt.success && result.ErrorCategory != dockertypes.ErrorCategoryVulnerability {
+				t.Errorf("error category = %q, want %q", result.ErrorCategory, dockertypes.ErrorCategoryVulnerability)
+			}
+			if !reflect.DeepEqual(result.Vulnerabilities.Unsupported, tt.inventory.Unsupported) {
+				t.Errorf("unsupported = %v, want %v", result.Vulnerabilities.Unsupported, tt.inventory.Unsupported)
+			}
+		})
+	}
+}
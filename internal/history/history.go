@@ -0,0 +1,275 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/stats"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// maxLineSize bounds a single run record when reading the history
+const maxLineSize = security.MaxFileSize
+
+// Image comparison statuses
+const (
+	StatusOK        = "ok"        // Within the thresholds of the baseline
+	StatusRegressed = "regressed" // Pull time or size regressed beyond a threshold
+	StatusNew       = "new"       // No successful pull in the baseline
+	StatusFailed    = "failed"    // Latest pull failed
+)
+
+// Record is one run of the history file
+type Record struct {
+	RunID     string        `json:"run_id"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"` // Wall clock time of the pulls
+	Images    []Image       `json:"images"`
+}
+
+// Image is the outcome of one image pull in a run
+type Image struct {
+	Image         string              `json:"image"` // Canonical reference
+	Registry      string              `json:"registry"`
+	Success       bool                `json:"success"`
+	Duration      time.Duration       `json:"duration"`
+	Size          int64               `json:"size,omitempty"`
+	Digest        string              `json:"digest,omitempty"`
+	Attempts      int                 `json:"attempts"`
+	ErrorCategory types.ErrorCategory `json:"error_category,omitempty"`
+}
+
+// NewRunID returns a random run ID
+func NewRunID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate run id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// NewRecord builds the history record of a run
+func NewRecord(runID string, start time.Time, duration time.Duration, results []types.PullResult) Record {
+	record := Record{RunID: runID, StartedAt: start.UTC(), Duration: duration, Images: []Image{}}
+	for _, result := range results {
+		image := result.CanonicalImage
+		if image == "" {
+			image = result.Image
+		}
+		entry := Image{
+			Image:    security.SanitizeLogMessage(image),
+			Registry: stats.Registry(result),
+			Success:  result.Success,
+			Duration: result.Duration,
+			Digest:   result.Digest,
+			Attempts: result.Attempts,
+		}
+		if result.Success {
+			entry.Size = result.ImageSize
+		} else {
+			entry.ErrorCategory = result.ErrorCategory
+			if entry.ErrorCategory == "" {
+				entry.ErrorCategory = types.ErrorCategoryUnknown
+			}
+		}
+		record.Images = append(record.Images, entry)
+	}
+	return record
+}
+
+// Append adds a run to the end of the history file, creating it if needed
+func Append(filename string, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %w", err)
+	}
+	if err := security.SecureAppendFile(filename, append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// Load reads every run of the history file in the order they were recorded.
+// A missing file is an empty history.
+func Load(filename string) ([]Record, error) {
+	if _, err := os.Lstat(filename); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	file, _, err := security.SecureOpenFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("history line %d: %w", line, err)
+		}
+		if record.RunID == "" {
+			return nil, fmt.Errorf("history line %d: missing run_id", line)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return records, nil
+}
+
+// Thresholds select when a change against the baseline is a regression
+type Thresholds struct {
+	BaselineRuns     int           // Runs before the latest one forming the baseline
+	DurationPercent  float64       // Pull time increase over the baseline median
+	SizePercent      float64       // Size increase over the baseline median
+	MinDurationDelta time.Duration // Pull time increases below this are never regressions
+}
+
+// Comparison is the latest run of a history compared to its baseline
+type Comparison struct {
+	RunID            string            `json:"run_id"`
+	StartedAt        time.Time         `json:"started_at"`
+	BaselineRuns     []string          `json:"baseline_runs"`
+	Duration         time.Duration     `json:"duration"`
+	BaselineDuration time.Duration     `json:"baseline_duration"` // Median wall clock time of the baseline runs
+	DurationChange   float64           `json:"duration_change_percent"`
+	Regressed        bool              `json:"regressed"` // Run wall clock time regressed
+	Images           []ImageComparison `json:"images"`
+	Counts           map[string]int    `json:"counts"` // Images per status
+}
+
+// ImageComparison is one image of the latest run compared to its baseline
+type ImageComparison struct {
+	Image             string        `json:"image"`
+	Status            string        `json:"status"`
+	Samples           int           `json:"samples"` // Successful pulls in the baseline
+	Duration          time.Duration `json:"duration"`
+	BaselineDuration  time.Duration `json:"baseline_duration,omitempty"` // Median of the samples
+	DurationChange    float64       `json:"duration_change_percent"`
+	DurationRegressed bool          `json:"duration_regressed,omitempty"`
+	Size              int64         `json:"size,omitempty"`
+	BaselineSize      int64         `json:"baseline_size,omitempty"` // Median of the samples
+	SizeChange        float64       `json:"size_change_percent"`
+	SizeRegressed     bool          `json:"size_regressed,omitempty"`
+	ErrorCategory     string        `json:"error_category,omitempty"`
+}
+
+// HasRegressions reports whether the run or any of its images regressed
+func (c Comparison) HasRegressions() bool {
+	return c.Regressed || c.Counts[StatusRegressed] > 0
+}
+
+// Compare compares the last run of a history to the median of up to
+// BaselineRuns runs before it. Images are matched by canonical reference and
+// only successful pulls count towards the baseline.
+func Compare(records []Record, thresholds Thresholds) (Comparison, error) {
+	if len(records) == 0 {
+		return Comparison{}, fmt.Errorf("history has no runs")
+	}
+	latest := records[len(records)-1]
+	baseline := records[max(len(records)-1-thresholds.BaselineRuns, 0) : len(records)-1]
+
+	comparison := Comparison{
+		RunID:        latest.RunID,
+		StartedAt:    latest.StartedAt,
+		BaselineRuns: []string{},
+		Duration:     latest.Duration,
+		Images:       []ImageComparison{},
+		Counts:       make(map[string]int),
+	}
+
+	var runDurations []time.Duration
+	durations := make(map[string][]time.Duration)
+	sizes := make(map[string][]int64)
+	for _, record := range baseline {
+		comparison.BaselineRuns = append(comparison.BaselineRuns, record.RunID)
+		runDurations = append(runDurations, record.Duration)
+		for _, image := range record.Images {
+			if image.Success {
+				durations[image.Image] = append(durations[image.Image], image.Duration)
+				if image.Size > 0 {
+					sizes[image.Image] = append(sizes[image.Image], image.Size)
+				}
+			}
+		}
+	}
+
+	if len(runDurations) > 0 {
+		comparison.BaselineDuration = median(runDurations)
+		comparison.DurationChange = change(float64(latest.Duration), float64(comparison.BaselineDuration))
+		comparison.Regressed = durationRegressed(latest.Duration, comparison.BaselineDuration, thresholds)
+	}
+
+	for _, image := range latest.Images {
+		result := ImageComparison{
+			Image:    image.Image,
+			Samples:  len(durations[image.Image]),
+			Duration: image.Duration,
+			Size:     image.Size,
+		}
+		if result.Samples > 0 {
+			result.BaselineDuration = median(durations[image.Image])
+			result.DurationChange = change(float64(image.Duration), float64(result.BaselineDuration))
+		}
+		if len(sizes[image.Image]) > 0 && image.Size > 0 {
+			result.BaselineSize = median(sizes[image.Image])
+			result.SizeChange = change(float64(image.Size), float64(result.BaselineSize))
+		}
+
+		switch {
+		case !image.Success:
+			result.Status = StatusFailed
+			result.ErrorCategory = string(image.ErrorCategory)
+		case result.Samples == 0:
+			result.Status = StatusNew
+		default:
+			result.DurationRegressed = durationRegressed(image.Duration, result.BaselineDuration, thresholds)
+			result.SizeRegressed = result.BaselineSize > 0 && result.SizeChange > thresholds.SizePercent
+			result.Status = StatusOK
+			if result.DurationRegressed || result.SizeRegressed {
+				result.Status = StatusRegressed
+			}
+		}
+		comparison.Counts[result.Status]++
+		comparison.Images = append(comparison.Images, result)
+	}
+	return comparison, nil
+}
+
+// durationRegressed reports whether a pull time exceeds its baseline by both thresholds
+func durationRegressed(latest, baseline time.Duration, thresholds Thresholds) bool {
+	if baseline <= 0 || latest-baseline < thresholds.MinDurationDelta {
+		return false
+	}
+	return change(float64(latest), float64(baseline)) > thresholds.DurationPercent
+}
+
+// change returns the relative change of a value to its baseline in percent
+func change(latest, baseline float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return (latest - baseline) / baseline * 100
+}
+
+// median returns the nearest-rank median of values
+func median[T int64 | time.Duration](values []T) T {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return stats.Percentile(sorted, 50)
+}
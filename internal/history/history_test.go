@@ -0,0 +1,151 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func useTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	policy := security.DefaultPathPolicy()
+	policy.AllowedRoots = append(policy.AllowedRoots, dir)
+	if err := security.SetPathPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = security.SetPathPolicy(security.DefaultPathPolicy()) })
+	return dir
+}
+
+func TestAppendLoad(t *testing.T) {
+	filename := filepath.Join(useTempDir(t), "history.jsonl")
+
+	records, err := Load(filename)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("Load() of a missing file = %v, %v", records, err)
+	}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	results := []types.PullResult{
+		{Image: "nginx", CanonicalImage: "docker.io/library/nginx:latest", Success: true,
+			Duration: 2 * time.Second, ImageSize: 1000, Digest: "sha256:abc", Attempts: 1},
+		{Image: "ghcr.io/org/app:1", Attempts: 3, Duration: time.Second},
+	}
+	for _, id := range []string{"run-1", "run-2"} {
+		if err := Append(filename, NewRecord(id, start, 5*time.Second, results)); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	records, err = Load(filename)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != 2 || records[0].RunID != "run-1" || records[1].RunID != "run-2" {
+		t.Fatalf("Load() = %+v", records)
+	}
+	want := Image{Image: "docker.io/library/nginx:latest", Registry: "registry-1.docker.io", Success: true,
+		Duration: 2 * time.Second, Size: 1000, Digest: "sha256:abc", Attempts: 1}
+	if got := records[1].Images[0]; got != want {
+		t.Errorf("image = %+v, want %+v", got, want)
+	}
+	if got := records[1].Images[1]; got.Image != "ghcr.io/org/app:1" || got.ErrorCategory != types.ErrorCategoryUnknown {
+		t.Errorf("failed image = %+v", got)
+	}
+	if !records[0].StartedAt.Equal(start) || records[0].Duration != 5*time.Second {
+		t.Errorf("record = %+v", records[0])
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	filename := filepath.Join(useTempDir(t), "history.jsonl")
+	if err := os.WriteFile(filename, []byte(`{"run_id":"a","images":[]}`+"\n\n{not json}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(filename); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Load() error = %v, want line 3", err)
+	}
+}
+
+func run(id string, duration time.Duration, images ...Image) Record {
+	return Record{RunID: id, Duration: duration, Images: images}
+}
+
+func pulled(image string, duration time.Duration, size int64) Image {
+	return Image{Image: image, Success: true, Duration: duration, Size: size}
+}
+
+func TestCompare(t *testing.T) {
+	thresholds := Thresholds{BaselineRuns: 3, DurationPercent: 20, SizePercent: 10, MinDurationDelta: time.Second}
+	records := []Record{
+		// Outside the baseline window
+		run("old", time.Minute, pulled("slow", time.Minute, 100)),
+		run("b1", 10*time.Second, pulled("slow", 10*time.Second, 100), pulled("grown", 5*time.Second, 100), pulled("tiny", 100*time.Millisecond, 10)),
+		run("b2", 12*time.Second, pulled("slow", 11*time.Second, 100), pulled("grown", 5*time.Second, 100), pulled("tiny", 100*time.Millisecond, 10)),
+		run("b3", 11*time.Second, pulled("slow", 12*time.Second, 100), pulled("grown", 5*time.Second, 100),
+			Image{Image: "broken", Duration: time.Second, ErrorCategory: types.ErrorCategoryTimeout}),
+		run("latest", 14*time.Second,
+			pulled("slow", 15*time.Second, 100),
+			pulled("grown", 5*time.Second, 120),
+			pulled("tiny", 500*time.Millisecond, 10),
+			pulled("broken", time.Second, 50),
+			Image{Image: "failing", ErrorCategory: types.ErrorCategoryAuth},
+		),
+	}
+
+	comparison, err := Compare(records, thresholds)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if comparison.RunID != "latest" || strings.Join(comparison.BaselineRuns, ",") != "b1,b2,b3" {
+		t.Errorf("compared %s to %v", comparison.RunID, comparison.BaselineRuns)
+	}
+	if comparison.BaselineDuration != 11*time.Second || !comparison.Regressed {
+		t.Errorf("run baseline = %v, regressed = %v", comparison.BaselineDuration, comparison.Regressed)
+	}
+
+	want := map[string]struct {
+		status           string
+		duration, size   bool
+		baselineDuration time.Duration
+	}{
+		"slow":    {status: StatusRegressed, duration: true, baselineDuration: 11 * time.Second},
+		"grown":   {status: StatusRegressed, size: true, baselineDuration: 5 * time.Second},
+		"tiny":    {status: StatusOK, baselineDuration: 100 * time.Millisecond}, // +400% but below the minimum delta
+		"broken":  {status: StatusNew},                                          // Never pulled successfully
+		"failing": {status: StatusFailed},
+	}
+	for _, image := range comparison.Images {
+		w := want[image.Image]
+		if image.Status != w.status || image.DurationRegressed != w.duration || image.SizeRegressed != w.size ||
+			image.BaselineDuration != w.baselineDuration {
+			t.Errorf("%s = %+v, want %+v", image.Image, image, w)
+		}
+	}
+	if comparison.Counts[StatusRegressed] != 2 || !comparison.HasRegressions() {
+		t.Errorf("counts = %v", comparison.Counts)
+	}
+	if comparison.Images[1].SizeChange != 20 {
+		t.Errorf("size change = %v, want 20", comparison.Images[1].SizeChange)
+	}
+}
+
+func TestCompareWithoutBaseline(t *testing.T) {
+	comparison, err := Compare([]Record{run("only", time.Second, pulled("nginx", time.Second, 1))}, Thresholds{BaselineRuns: 7})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if comparison.HasRegressions() || comparison.Counts[StatusNew] != 1 || len(comparison.BaselineRuns) != 0 {
+		t.Errorf("Compare() = %+v", comparison)
+	}
+
+	if _, err := Compare(nil, Thresholds{}); err == nil {
+		t.Error("Compare() of an empty history succeeded")
+	}
+}
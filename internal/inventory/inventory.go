@@ -0,0 +1,286 @@
+package inventory
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Package ecosystems
+const (
+	EcosystemDeb = "deb"
+	EcosystemApk = "apk"
+)
+
+// Limits applied while reading an image archive
+const (
+	maxDatabaseSize = 64 * 1024 * 1024 // Largest package database read from a layer
+	maxManifestSize = 1024 * 1024      // Largest manifest.json accepted
+)
+
+// Package is an installed operating system package
+type Package struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Source        string `json:"source,omitempty"`         // Source package name, if different from Name
+	SourceVersion string `json:"source_version,omitempty"` // Source package version, if different from Version
+	Ecosystem     string `json:"ecosystem"`
+}
+
+// Distro identifies the operating system of an image from its os-release file
+type Distro struct {
+	ID        string `json:"id"`                   // e.g. "debian" or "alpine"
+	VersionID string `json:"version_id,omitempty"` // e.g. "12" or "3.19.1"
+}
+
+// Inventory lists the packages found in an image
+type Inventory struct {
+	Distro   Distro    `json:"distro"`
+	Packages []Package `json:"packages"`
+	// Unsupported lists package databases that were found but cannot be read,
+	// so their packages are missing from the inventory
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+// databases are the package database files read from image layers
+var databases = map[string]string{
+	"var/lib/dpkg/status":  EcosystemDeb,
+	"lib/apk/db/installed": EcosystemApk,
+}
+
+// osReleaseFiles identify the distribution, in order of preference
+var osReleaseFiles = []string{"etc/os-release", "usr/lib/os-release"}
+
+// dpkgStatusDir holds one status file per package in distroless images
+const dpkgStatusDir = "var/lib/dpkg/status.d/"
+
+// rpmDatabases indicate RPM packages, which are stored in Berkeley DB, SQLite or
+// NDB files that cannot be read without the rpm libraries
+var rpmDatabases = []string{
+	"var/lib/rpm/Packages",
+	"var/lib/rpm/Packages.db",
+	"var/lib/rpm/rpmdb.sqlite",
+	"usr/lib/sysimage/rpm/Packages",
+	"usr/lib/sysimage/rpm/Packages.db",
+	"usr/lib/sysimage/rpm/rpmdb.sqlite",
+}
+
+// layerChanges are the package database changes made by a single layer
+type layerChanges struct {
+	files     map[string][]byte // Database files added or replaced
+	whiteouts []string          // Paths deleted by the layer
+	opaque    []string          // Directories whose lower contents are hidden
+}
+
+// manifestEntry is an image of a docker save archive
+type manifestEntry struct {
+	Layers []string `json:"Layers"`
+}
+
+// Extract reads the package inventory from an image archive as written by
+// docker save. Layers are applied in order, honouring whiteouts, so packages
+// removed in later layers are not reported.
+func Extract(archive io.Reader) (*Inventory, error) {
+	layers := make(map[string]*layerChanges)
+	var manifest []manifestEntry
+
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if name == "manifest.json" {
+			data, err := io.ReadAll(io.LimitReader(reader, maxManifestSize+1))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read image manifest: %w", err)
+			}
+			if len(data) > maxManifestSize {
+				return nil, fmt.Errorf("image manifest too large")
+			}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse image manifest: %w", err)
+			}
+			continue
+		}
+
+		// Any other entry may be a layer; the manifest, which can come last,
+		// decides which ones are used and in which order
+		if changes, ok := readLayer(reader); ok {
+			layers[name] = changes
+		}
+	}
+
+	if len(manifest) == 0 {
+		return nil, fmt.Errorf("image archive has no manifest")
+	}
+
+	files := make(map[string][]byte)
+	for _, layerName := range manifest[0].Layers {
+		changes, ok := layers[path.Clean(layerName)]
+		if !ok {
+			return nil, fmt.Errorf("image archive is missing layer %s", layerName)
+		}
+		changes.apply(files)
+	}
+
+	return buildInventory(files), nil
+}
+
+// readLayer collects the package database changes of a layer. It reports
+// false if the entry is not a (possibly gzip compressed) tar archive.
+func readLayer(entry io.Reader) (*layerChanges, bool) {
+	buffered := bufio.NewReader(entry)
+	var layer io.Reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, false
+		}
+		defer gz.Close()
+		layer = gz
+	}
+
+	changes := &layerChanges{files: make(map[string][]byte)}
+	reader := tar.NewReader(layer)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return changes, true
+		}
+		if err != nil {
+			return nil, false
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		dir, base := path.Split(name)
+
+		switch {
+		case base == ".wh..wh..opq":
+			changes.opaque = append(changes.opaque, dir)
+		case strings.HasPrefix(base, ".wh."):
+			changes.whiteouts = append(changes.whiteouts, dir+strings.TrimPrefix(base, ".wh."))
+		case header.Typeflag == tar.TypeReg && isDatabase(name):
+			data, err := io.ReadAll(io.LimitReader(reader, maxDatabaseSize+1))
+			if err != nil || len(data) > maxDatabaseSize {
+				return nil, false
+			}
+			changes.files[name] = data
+		}
+	}
+}
+
+// isDatabase reports whether a layer path is a package database or os-release file
+func isDatabase(name string) bool {
+	if _, ok := databases[name]; ok {
+		return true
+	}
+	if strings.HasPrefix(name, dpkgStatusDir) && len(name) > len(dpkgStatusDir) {
+		return true
+	}
+	for _, release := range osReleaseFiles {
+		if name == release {
+			return true
+		}
+	}
+	for _, rpm := range rpmDatabases {
+		if name == rpm {
+			return true
+		}
+	}
+	return false
+}
+
+// apply merges the layer changes into the files of the layers below
+func (c *layerChanges) apply(files map[string][]byte) {
+	for _, dir := range c.opaque {
+		for name := range files {
+			if strings.HasPrefix(name, dir) {
+				delete(files, name)
+			}
+		}
+	}
+	for _, removed := range c.whiteouts {
+		for name := range files {
+			if name == removed || strings.HasPrefix(name, removed+"/") {
+				delete(files, name)
+			}
+		}
+	}
+	for name, data := range c.files {
+		files[name] = data
+	}
+}
+
+// buildInventory parses the package databases left after applying all layers
+func buildInventory(files map[string][]byte) *Inventory {
+	inv := &Inventory{}
+	for _, release := range osReleaseFiles {
+		if data, ok := files[release]; ok {
+			inv.Distro = parseOSRelease(data)
+			break
+		}
+	}
+	for _, release := range osReleaseFiles {
+		delete(files, release)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data := files[name]
+		switch {
+		case databases[name] == EcosystemDeb || strings.HasPrefix(name, dpkgStatusDir):
+			inv.Packages = append(inv.Packages, ParseDpkgStatus(data)...)
+		case databases[name] == EcosystemApk:
+			inv.Packages = append(inv.Packages, ParseApkInstalled(data)...)
+		default:
+			inv.Unsupported = append(inv.Unsupported, "/"+name)
+		}
+	}
+
+	return inv
+}
+
+// paragraphs splits a database into blank line separated records
+func paragraphs(data []byte) [][]string {
+	var records [][]string
+	var current []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				records = append(records, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		records = append(records, current)
+	}
+	return records
+}
@@ -0,0 +1,191 @@
+package inventory
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+)
+
+const dpkgStatus = `Package: openssl
+Status: install ok installed
+Version: 3.0.11-1~deb12u1
+Description: Secure Sockets Layer toolkit
+ multi-line description
+
+Package: libssl3
+Status: install ok installed
+Source: openssl (3.0.11-1~deb12u1)
+Version: 3.0.11-1~deb12u1
+
+Package: removed
+Status: deinstall ok config-files
+Version: 1.0
+`
+
+const apkInstalled = `P:musl
+V:1.2.4-r2
+o:musl
+
+P:libcrypto3
+V:3.1.4-r1
+o:openssl
+`
+
+// layerFile is a file or whiteout in a test layer
+type layerFile struct {
+	name string
+	data string
+}
+
+// buildTar creates a tar archive of the given files
+func buildTar(t *testing.T, files []layerFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0o644, Size: int64(len(file.data)), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(file.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gzipped compresses data
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildArchive creates a docker save archive with the layers in order and the manifest last
+func buildArchive(t *testing.T, layers ...[]byte) []byte {
+	t.Helper()
+	var files []layerFile
+	var names []string
+	for i, layer := range layers {
+		name := "blobs/sha256/layer" + string(rune('a'+i))
+		names = append(names, name)
+		files = append(files, layerFile{name: name, data: string(layer)})
+	}
+	files = append(files, layerFile{name: "blobs/sha256/config", data: `{"architecture":"amd64"}`})
+	manifest, err := json.Marshal([]manifestEntry{{Layers: names}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, layerFile{name: "manifest.json", data: string(manifest)})
+	return buildTar(t, files)
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name            string
+		archive         func(t *testing.T) []byte
+		wantPackages    []string
+		wantUnsupported int
+	}{
+		{
+			name: "dpkg status",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, buildTar(t, []layerFile{{"var/lib/dpkg/status", dpkgStatus}}))
+			},
+			wantPackages: []string{"openssl", "libssl3"},
+		},
+		{
+			name: "compressed apk layer",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, gzipped(t, buildTar(t, []layerFile{{"lib/apk/db/installed", apkInstalled}})))
+			},
+			wantPackages: []string{"musl", "libcrypto3"},
+		},
+		{
+			name: "later layer replaces database",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t,
+					buildTar(t, []layerFile{{"lib/apk/db/installed", apkInstalled}}),
+					buildTar(t, []layerFile{{"lib/apk/db/installed", "P:musl\nV:1.2.5-r0\n"}}))
+			},
+			wantPackages: []string{"musl"},
+		},
+		{
+			name: "whiteout removes database",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t,
+					buildTar(t, []layerFile{{"var/lib/dpkg/status", dpkgStatus}}),
+					buildTar(t, []layerFile{{"var/lib/dpkg/.wh.status", ""}}))
+			},
+		},
+		{
+			name: "opaque directory hides distroless status files",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t,
+					buildTar(t, []layerFile{{"var/lib/dpkg/status.d/base", dpkgStatus}}),
+					buildTar(t, []layerFile{{"var/lib/dpkg/status.d/.wh..wh..opq", ""}}))
+			},
+		},
+		{
+			name: "rpm database is reported as unsupported",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, buildTar(t, []layerFile{{"var/lib/rpm/rpmdb.sqlite", "SQLite format 3"}}))
+			},
+			wantUnsupported: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, err := Extract(bytes.NewReader(tt.archive(t)))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			var names []string
+			for _, pkg := range inv.Packages {
+				names = append(names, pkg.Name)
+			}
+			if len(names) != len(tt.wantPackages) {
+				t.Fatalf("Extract() packages = %v, want %v", names, tt.wantPackages)
+			}
+			for i := range names {
+				if names[i] != tt.wantPackages[i] {
+					t.Errorf("Extract() packages = %v, want %v", names, tt.wantPackages)
+				}
+			}
+			if len(inv.Unsupported) != tt.wantUnsupported {
+				t.Errorf("Extract() unsupported = %v, want %d entries", inv.Unsupported, tt.wantUnsupported)
+			}
+		})
+	}
+}
+
+func TestExtractWithoutManifest(t *testing.T) {
+	archive := buildTar(t, []layerFile{{"layer.tar", string(buildTar(t, nil))}})
+	if _, err := Extract(bytes.NewReader(archive)); err == nil {
+		t.Error("Extract() accepted an archive without manifest")
+	}
+}
+
+func TestParseDpkgStatusSource(t *testing.T) {
+	packages := ParseDpkgStatus([]byte(dpkgStatus))
+	if len(packages) != 2 {
+		t.Fatalf("ParseDpkgStatus() = %v, want 2 packages", packages)
+	}
+	if packages[1].Source != "openssl" || packages[1].SourceVersion != "3.0.11-1~deb12u1" {
+		t.Errorf("ParseDpkgStatus() source = %q %q", packages[1].Source, packages[1].SourceVersion)
+	}
+}
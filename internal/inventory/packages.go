@@ -0,0 +1,92 @@
+package inventory
+
+import "strings"
+
+// ParseDpkgStatus reads the installed packages from a dpkg status file
+func ParseDpkgStatus(data []byte) []Package {
+	var packages []Package
+	for _, record := range paragraphs(data) {
+		fields := make(map[string]string)
+		for _, line := range record {
+			// Continuation lines of multi-line fields start with whitespace
+			if line[0] == ' ' || line[0] == '\t' {
+				continue
+			}
+			key, value, ok := strings.Cut(line, ":")
+			if ok {
+				fields[key] = strings.TrimSpace(value)
+			}
+		}
+
+		// Packages removed but not purged keep a record in the status file
+		if status, ok := fields["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+		if fields["Package"] == "" || fields["Version"] == "" {
+			continue
+		}
+
+		pkg := Package{Name: fields["Package"], Version: fields["Version"], Ecosystem: EcosystemDeb}
+
+		// Source is either "name" or "name (version)"
+		if source := fields["Source"]; source != "" {
+			name, version, hasVersion := strings.Cut(source, " (")
+			pkg.Source = name
+			if hasVersion {
+				pkg.SourceVersion = strings.TrimSuffix(version, ")")
+			}
+		}
+
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// ParseApkInstalled reads the installed packages from an apk installed database
+func ParseApkInstalled(data []byte) []Package {
+	var packages []Package
+	for _, record := range paragraphs(data) {
+		pkg := Package{Ecosystem: EcosystemApk}
+		for _, line := range record {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "P":
+				pkg.Name = value
+			case "V":
+				pkg.Version = value
+			case "o":
+				pkg.Source = value // Origin package
+			}
+		}
+		if pkg.Name == "" || pkg.Version == "" {
+			continue
+		}
+		if pkg.Source == pkg.Name {
+			pkg.Source = ""
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// parseOSRelease reads the distribution ID and version from an os-release file
+func parseOSRelease(data []byte) Distro {
+	var distro Distro
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			distro.ID = value
+		case "VERSION_ID":
+			distro.VersionID = value
+		}
+	}
+	return distro
+}
@@ -0,0 +1,85 @@
+package lockfile
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+)
+
+// Version is the lockfile format version written by this tool
+const Version = 1
+
+// Lockfile maps image references as written in the image list to resolved digests
+type Lockfile struct {
+	Version int              `yaml:"version"`
+	Images  map[string]Entry `yaml:"images"`
+}
+
+// Entry is the resolved form of a single locked reference
+type Entry struct {
+	Canonical string `yaml:"canonical"`
+	Digest    string `yaml:"digest"`
+}
+
+// New creates an empty lockfile
+func New() *Lockfile {
+	return &Lockfile{Version: Version, Images: make(map[string]Entry)}
+}
+
+// Load reads and validates a lockfile
+func Load(filename string) (*Lockfile, error) {
+	data, err := security.SecureReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	lock := New()
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true) // Reject unknown fields for security
+	if err := decoder.Decode(lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile: %w", err)
+	}
+
+	if lock.Version != Version {
+		return nil, fmt.Errorf("unsupported lockfile version %d", lock.Version)
+	}
+	for name, entry := range lock.Images {
+		if !strings.HasPrefix(entry.Digest, "sha256:") {
+			return nil, fmt.Errorf("invalid digest for %s in lockfile", security.SanitizeLogMessage(name))
+		}
+	}
+
+	return lock, nil
+}
+
+// Lookup returns the entry of a reference, matching either the written or the canonical name
+func (l *Lockfile) Lookup(name, canonical string) (Entry, bool) {
+	if entry, ok := l.Images[name]; ok {
+		return entry, true
+	}
+	for _, entry := range l.Images {
+		if entry.Canonical == canonical {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Write atomically replaces the lockfile at filename
+func (l *Lockfile) Write(filename string) error {
+	var b strings.Builder
+	b.WriteString("# Generated by docker-parallel-pull. Do not edit.\n")
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	b.Write(data)
+
+	if err := security.WriteFileAtomic(filename, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
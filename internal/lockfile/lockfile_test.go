@@ -0,0 +1,159 @@
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+)
+
+const (
+	nginxDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	redisDigest = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+)
+
+func useTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	policy := security.DefaultPathPolicy()
+	policy.AllowedRoots = append(policy.AllowedRoots, dir)
+	if err := security.SetPathPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = security.SetPathPolicy(security.DefaultPathPolicy()) })
+	return dir
+}
+
+func TestWriteLoad(t *testing.T) {
+	dir := useTempDir(t)
+	filename := filepath.Join(dir, "images.lock")
+
+	lock := New()
+	lock.Images["nginx"] = Entry{Canonical: "docker.io/library/nginx:latest", Digest: nginxDigest}
+	lock.Images["ghcr.io/org/redis:7"] = Entry{Canonical: "ghcr.io/org/redis:7", Digest: redisDigest}
+	if err := lock.Write(filename); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# Generated by docker-parallel-pull") {
+		t.Errorf("lockfile header missing:\n%s", data)
+	}
+
+	loaded, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, lock) {
+		t.Errorf("Load() = %+v, want %+v", loaded, lock)
+	}
+}
+
+func TestWriteIsAtomic(t *testing.T) {
+	dir := useTempDir(t)
+	filename := filepath.Join(dir, "images.lock")
+
+	first := New()
+	first.Images["nginx"] = Entry{Canonical: "docker.io/library/nginx:latest", Digest: nginxDigest}
+	if err := first.Write(filename); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	second := New()
+	second.Images["redis"] = Entry{Canonical: "docker.io/library/redis:latest", Digest: redisDigest}
+	if err := second.Write(filename); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	loaded, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, second) {
+		t.Errorf("Load() = %+v, want the replacement %+v", loaded, second)
+	}
+
+	// A failed replacement leaves no temporary files behind
+	target := filepath.Join(dir, "directory.lock")
+	if err := os.Mkdir(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Write(target); err == nil {
+		t.Error("Write() over a directory succeeded")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"directory.lock", "images.lock"}) {
+		t.Errorf("directory contains %v, want only the lockfiles", names)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := useTempDir(t)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unsupported version", content: "version: 2\nimages: {}\n"},
+		{name: "unknown field", content: "version: 1\nimages: {}\npinned: true\n"},
+		{name: "unknown entry field", content: "version: 1\nimages:\n  nginx:\n    digest: " + nginxDigest + "\n    tag: latest\n"},
+		{name: "invalid digest", content: "version: 1\nimages:\n  nginx:\n    canonical: docker.io/library/nginx:latest\n    digest: md5:abc\n"},
+		{name: "not YAML", content: "version: [1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".lock")
+			if err := os.WriteFile(filename, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(filename); err == nil {
+				t.Error("Load() succeeded")
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.lock")); err == nil {
+		t.Error("Load() of a missing lockfile succeeded")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	lock := New()
+	lock.Images["nginx"] = Entry{Canonical: "docker.io/library/nginx:latest", Digest: nginxDigest}
+	lock.Images["redis:7"] = Entry{Canonical: "docker.io/library/redis:7", Digest: redisDigest}
+
+	tests := []struct {
+		name      string
+		image     string
+		canonical string
+		expected  string
+		found     bool
+	}{
+		{name: "written name", image: "nginx", canonical: "docker.io/library/nginx:latest", expected: nginxDigest, found: true},
+		{name: "other spelling of a locked image", image: "docker.io/library/redis:7", canonical: "docker.io/library/redis:7", expected: redisDigest, found: true},
+		{name: "written name wins over canonical", image: "redis:7", canonical: "docker.io/library/nginx:latest", expected: redisDigest, found: true},
+		{name: "missing entry", image: "redis:8", canonical: "docker.io/library/redis:8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, found := lock.Lookup(tt.image, tt.canonical)
+			if found != tt.found || entry.Digest != tt.expected {
+				t.Errorf("Lookup() = %+v, %v; want %s, %v", entry, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
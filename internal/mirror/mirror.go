@@ -0,0 +1,54 @@
+package mirror
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+)
+
+// Rewrite maps an image reference to its mirror reference using the first matching rule.
+// The image is matched in its fully-qualified form (e.g. docker.io/library/nginx:latest).
+// It returns false if no rule matches.
+func Rewrite(rules []config.MirrorRule, imageName string) (string, bool, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", false, fmt.Errorf("cannot parse image reference: %w", err)
+	}
+	if _, isDigested := named.(reference.Digested); isDigested {
+		return "", false, fmt.Errorf("cannot mirror a digest reference")
+	}
+	qualified := reference.TagNameOnly(named).String()
+
+	for _, rule := range rules {
+		target, ok := applyRule(rule, qualified)
+		if !ok {
+			continue
+		}
+		if _, err := reference.ParseNormalizedNamed(target); err != nil {
+			return "", false, fmt.Errorf("rule %s -> %s produced an invalid reference: %w", rule.From, rule.To, err)
+		}
+		return target, true, nil
+	}
+
+	return "", false, nil
+}
+
+// applyRule matches a single rule against a fully-qualified reference
+func applyRule(rule config.MirrorRule, qualified string) (string, bool) {
+	prefix, wildcard := strings.CutSuffix(rule.From, "*")
+	if !wildcard {
+		if qualified != rule.From {
+			return "", false
+		}
+		return rule.To, true
+	}
+
+	rest, ok := strings.CutPrefix(qualified, prefix)
+	if !ok || rest == "" {
+		return "", false
+	}
+	return strings.Replace(rule.To, "*", rest, 1), true
+}
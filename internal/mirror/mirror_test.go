@@ -0,0 +1,51 @@
+package mirror
+
+import (
+	"testing"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+)
+
+func TestRewrite(t *testing.T) {
+	rules := []config.MirrorRule{
+		{From: "docker.io/library/nginx:stable", To: "harbor.local/pinned/nginx:stable"},
+		{From: "docker.io/library/*", To: "harbor.local/mirror/*"},
+		{From: "ghcr.io/org/*", To: "harbor.local/ghcr/*"},
+		{From: "*", To: "harbor.local/default/*"},
+	}
+
+	tests := []struct {
+		name    string
+		rules   []config.MirrorRule
+		image   string
+		want    string
+		matched bool
+		wantErr bool
+	}{
+		{name: "exact rule before prefix", rules: rules, image: "nginx:stable", want: "harbor.local/pinned/nginx:stable", matched: true},
+		{name: "prefix rule with short name", rules: rules, image: "nginx", want: "harbor.local/mirror/nginx:latest", matched: true},
+		{name: "prefix rule with qualified name", rules: rules, image: "docker.io/library/redis:7", want: "harbor.local/mirror/redis:7", matched: true},
+		{name: "prefix rule of another registry", rules: rules, image: "ghcr.io/org/app:1.2", want: "harbor.local/ghcr/app:1.2", matched: true},
+		{name: "default rule", rules: rules, image: "quay.io/prometheus/node-exporter:v1", want: "harbor.local/default/quay.io/prometheus/node-exporter:v1", matched: true},
+		{name: "no matching rule", rules: rules[:3], image: "quay.io/prometheus/node-exporter:v1"},
+		{name: "prefix needs a remainder", rules: []config.MirrorRule{{From: "docker.io/library/nginx:latest*", To: "x/*"}}, image: "nginx"},
+		{name: "digest reference", rules: rules, image: "nginx@sha256:" + sha, wantErr: true},
+		{name: "tag and digest reference", rules: rules, image: "nginx:1.27@sha256:" + sha, wantErr: true},
+		{name: "invalid reference", rules: rules, image: "Invalid Image", wantErr: true},
+		{name: "rule producing an invalid reference", rules: []config.MirrorRule{{From: "*", To: "harbor.local/UPPER/*"}}, image: "nginx", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched, err := Rewrite(tt.rules, tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rewrite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || matched != tt.matched {
+				t.Errorf("Rewrite() = %q, %v; want %q, %v", got, matched, tt.want, tt.matched)
+			}
+		})
+	}
+}
+
+const sha = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/stats"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// CalculateBenchmark summarizes the measured iterations of a benchmark, given
+// the results and wall clock time of each. Images are listed in the order they
+// first finished; only successful pulls count towards an image's pull times.
+func CalculateBenchmark(iterations [][]types.PullResult, durations []time.Duration, config *config.Config) (types.BenchmarkReport, error) {
+	report := types.BenchmarkReport{
+		Iterations: len(iterations),
+		Warmup:     config.Benchmark.Warmup,
+		Cold:       config.Benchmark.Cold,
+		Confidence: config.Benchmark.Confidence,
+		Images:     []types.ImageBenchmark{},
+	}
+
+	var order []string
+	pullTimes := make(map[string][]time.Duration)
+	failures := make(map[string]int)
+	for _, results := range iterations {
+		for _, result := range results {
+			image := result.CanonicalImage
+			if image == "" {
+				image = result.Image
+			}
+			if _, seen := failures[image]; !seen {
+				order = append(order, image)
+				failures[image] = 0
+			}
+			if result.Success {
+				pullTimes[image] = append(pullTimes[image], result.Duration)
+			} else {
+				failures[image]++
+				report.Failures++
+			}
+		}
+	}
+
+	total, err := stats.Estimate(durations, report.Confidence)
+	if err != nil {
+		return types.BenchmarkReport{}, err
+	}
+	report.Total = total
+
+	for _, image := range order {
+		estimate, err := stats.Estimate(pullTimes[image], report.Confidence)
+		if err != nil {
+			return types.BenchmarkReport{}, err
+		}
+		report.Images = append(report.Images, types.ImageBenchmark{
+			Image:    security.SanitizeLogMessage(image),
+			Failures: failures[image],
+			Duration: estimate,
+		})
+	}
+	return report, nil
+}
+
+// OutputBenchmark displays the pull time statistics of a benchmark
+func OutputBenchmark(report types.BenchmarkReport, config *config.Config) {
+	if config == nil {
+		return
+	}
+
+	if config.OutputFormat == "json" {
+		if data, err := json.MarshalIndent(report, "", "  "); err == nil {
+			fmt.Println(string(data))
+		}
+		return
+	}
+
+	cache := "warm"
+	if report.Cold {
+		cache = "cold"
+	}
+	fmt.Printf("\n🏁 Benchmark: %d %s iterations, %d warmup, %.0f%% confidence intervals\n",
+		report.Iterations, cache, report.Warmup, report.Confidence*100)
+	fmt.Printf("   ⏱️ Total time: %s\n", formatEstimate(report.Total))
+
+	fmt.Printf("\n📦 Pull time per image:\n")
+	for _, image := range report.Images {
+		fmt.Printf("   • %s: %s\n", image.Image, formatEstimate(image.Duration))
+		if image.Failures > 0 {
+			fmt.Printf("      ❌ %d of %d pulls failed\n", image.Failures, image.Failures+image.Duration.Samples)
+		}
+	}
+	if report.Failures > 0 {
+		fmt.Printf("\n   ❌ Failed pulls: %d\n", report.Failures)
+	}
+}
+
+// formatEstimate renders an estimate as mean ± standard deviation with its confidence interval and range
+func formatEstimate(e types.Estimate) string {
+	if e.Samples == 0 {
+		return "no successful pulls"
+	}
+	if e.Samples < 2 {
+		return fmt.Sprintf("%v (1 sample)", e.Mean.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("mean %v ± %v (CI %v – %v, min %v, max %v, %d samples)",
+		e.Mean.Round(time.Millisecond), e.StdDev.Round(time.Millisecond),
+		e.Low.Round(time.Millisecond), e.High.Round(time.Millisecond),
+		e.Min.Round(time.Millisecond), e.Max.Round(time.Millisecond), e.Samples)
+}
@@ -0,0 +1,68 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestCalculateBenchmark(t *testing.T) {
+	pull := func(image string, seconds int, success bool) types.PullResult {
+		return types.PullResult{Image: image, CanonicalImage: "docker.io/library/" + image,
+			Duration: time.Duration(seconds) * time.Second, Success: success}
+	}
+	iterations := [][]types.PullResult{
+		{pull("redis:7", 4, true), pull("nginx:latest", 2, true)},
+		{pull("nginx:latest", 4, true), pull("redis:7", 6, false)},
+		{pull("nginx:latest", 6, true), pull("redis:7", 5, true)},
+	}
+	durations := []time.Duration{5 * time.Second, 7 * time.Second, 6 * time.Second}
+	cfg := &config.Config{Benchmark: config.BenchmarkConfig{Iterations: 3, Warmup: 1, Cold: true, Confidence: 0.95}}
+
+	report, err := CalculateBenchmark(iterations, durations, cfg)
+	if err != nil {
+		t.Fatalf("CalculateBenchmark() error = %v", err)
+	}
+
+	if report.Iterations != 3 || report.Warmup != 1 || !report.Cold || report.Failures != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Total.Samples != 3 || report.Total.Mean != 6*time.Second || report.Total.StdDev != time.Second {
+		t.Errorf("Total = %+v", report.Total)
+	}
+	if len(report.Images) != 2 || report.Images[0].Image != "docker.io/library/redis:7" {
+		t.Fatalf("Images = %+v, want redis first", report.Images)
+	}
+
+	redis, nginx := report.Images[0], report.Images[1]
+	if redis.Failures != 1 || redis.Duration.Samples != 2 || redis.Duration.Mean != 4500*time.Millisecond {
+		t.Errorf("redis = %+v", redis)
+	}
+	// t(0.95, 2) = 4.303, stddev 2s, margin = 4.303 * 2 / sqrt(3)
+	if nginx.Duration.Mean != 4*time.Second || nginx.Duration.StdDev != 2*time.Second ||
+		nginx.Duration.Low != 0 || nginx.Duration.High.Round(time.Millisecond) != 8969*time.Millisecond {
+		t.Errorf("nginx = %+v", nginx.Duration)
+	}
+}
+
+func TestFormatEstimate(t *testing.T) {
+	tests := []struct {
+		estimate types.Estimate
+		want     string
+	}{
+		{estimate: types.Estimate{}, want: "no successful pulls"},
+		{estimate: types.Estimate{Samples: 1, Mean: 1500 * time.Millisecond}, want: "1.5s (1 sample)"},
+		{
+			estimate: types.Estimate{Samples: 5, Mean: 2 * time.Second, StdDev: 100 * time.Millisecond,
+				Low: 1900 * time.Millisecond, High: 2100 * time.Millisecond, Min: 1800 * time.Millisecond, Max: 2200 * time.Millisecond},
+			want: "mean 2s ± 100ms (CI 1.9s – 2.1s, min 1.8s, max 2.2s, 5 samples)",
+		},
+	}
+	for _, tt := range tests {
+		if got := formatEstimate(tt.estimate); got != tt.want {
+			t.Errorf("formatEstimate() = %q, want %q", got, tt.want)
+		}
+	}
+}
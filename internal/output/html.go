@@ -0,0 +1,528 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// Chart geometry of the HTML report, in SVG user units
+const (
+	chartLabelWidth   = 280.0 // Left column holding the worker and image of a timeline row
+	chartPlotWidth    = 860.0
+	chartAxisHeight   = 24.0
+	ganttRowHeight    = 22.0
+	ganttBarHeight    = 14.0
+	throughputHeight  = 160.0
+	throughputBuckets = 60
+)
+
+// htmlReport is the view model of the HTML report template
+type htmlReport struct {
+	Generated  string
+	Summary    [][2]string
+	Gantt      *ganttChart
+	Throughput *throughputChart
+	Registries []metricGroup
+	Categories []metricGroup
+	Rows       []htmlRow
+}
+
+// ganttChart places every attempt of every image on a shared time axis
+type ganttChart struct {
+	Width, Height float64
+	Ticks         []axisTick
+	Rows          []ganttRow
+}
+
+type axisTick struct {
+	X     float64
+	Label string
+}
+
+type ganttRow struct {
+	Y, BarY  float64
+	Label    string
+	Title    string
+	Segments []ganttSegment
+}
+
+// ganttSegment is one stretch of an image's pull: an attempt, a retry backoff or post-pull verification
+type ganttSegment struct {
+	X, Width float64
+	Class    string
+	Title    string
+}
+
+// throughputChart shows the image bytes pulled per second over the run
+type throughputChart struct {
+	Width, Height float64
+	Ticks         []axisTick
+	Bars          []throughputBar
+	Peak          string
+}
+
+type throughputBar struct {
+	X, Y, Width, Height float64
+	Title               string
+}
+
+// htmlRow is one image of the sortable results table
+type htmlRow struct {
+	Image      string
+	Success    bool
+	Worker     int
+	Attempts   int
+	Seconds    string
+	Duration   string
+	SizeBytes  int64
+	Size       string
+	Digest     string
+	Category   string
+	Error      string
+	StartedAt  string
+	FinishedAt string
+}
+
+// RenderHTML renders a self-contained HTML report with a timeline of every pull
+// across workers, the throughput over time and a sortable results table
+func RenderHTML(metrics types.PullMetrics, results []types.PullResult, config *config.Config) ([]byte, error) {
+	results = sortResults(results, config.ReportSort)
+
+	report := htmlReport{
+		Generated: time.Now().UTC().Format(time.RFC3339),
+		Summary: [][2]string{
+			{"Images", strconv.Itoa(metrics.TotalImages)},
+			{"Successful", strconv.Itoa(metrics.SuccessCount)},
+			{"Failed", strconv.Itoa(metrics.FailureCount)},
+			{"Total retries", strconv.Itoa(metrics.TotalRetries)},
+			{"Total time", metrics.TotalDuration.Round(time.Millisecond).String()},
+			{"Cumulative pull time", metrics.CumulativeDuration.Round(time.Millisecond).String()},
+			{"Average time per image", metrics.AverageDuration.Round(time.Millisecond).String()},
+			{"Pull time p50 / p90 / p95 / p99 / max", percentiles(metrics.Durations)},
+			{"Pulled", fmt.Sprintf("%s (%s)", units.HumanSize(float64(metrics.TotalBytes)), byteRate(metrics.Throughput))},
+			{"Concurrency", strconv.Itoa(metrics.Concurrency)},
+		},
+		Registries: registryGroups(metrics),
+		Categories: categoryGroups(metrics),
+	}
+
+	if start, end, ok := runSpan(results); ok {
+		report.Gantt = buildGantt(results, start, end)
+		report.Throughput = buildThroughput(results, start, end)
+	}
+
+	for _, result := range results {
+		row := htmlRow{
+			Image:     security.SanitizeLogMessage(result.Image),
+			Success:   result.Success,
+			Worker:    result.Worker,
+			Attempts:  result.Attempts,
+			Seconds:   seconds(result.Duration),
+			Duration:  result.Duration.Round(time.Millisecond).String(),
+			SizeBytes: result.ImageSize,
+			Digest:    result.Digest,
+			Category:  string(errorCategory(result)),
+			Error:     result.Error,
+		}
+		if result.ImageSize > 0 {
+			row.Size = units.HumanSize(float64(result.ImageSize))
+		}
+		if !result.StartedAt.IsZero() {
+			row.StartedAt = result.StartedAt.UTC().Format("15:04:05.000")
+			row.FinishedAt = result.FinishedAt.UTC().Format("15:04:05.000")
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	var b bytes.Buffer
+	if err := htmlTemplate.Execute(&b, report); err != nil {
+		return nil, fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// runSpan returns the earliest start and latest finish of the timed results
+func runSpan(results []types.PullResult) (time.Time, time.Time, bool) {
+	var start, end time.Time
+	for _, result := range results {
+		if result.StartedAt.IsZero() || result.FinishedAt.IsZero() {
+			continue
+		}
+		if start.IsZero() || result.StartedAt.Before(start) {
+			start = result.StartedAt
+		}
+		if result.FinishedAt.After(end) {
+			end = result.FinishedAt
+		}
+	}
+	return start, end, !start.IsZero() && end.After(start)
+}
+
+// timeScale maps instants of the run onto the horizontal plot area
+type timeScale struct {
+	start time.Time
+	span  time.Duration
+}
+
+func (s timeScale) x(t time.Time) float64 {
+	return chartLabelWidth + float64(t.Sub(s.start))/float64(s.span)*chartPlotWidth
+}
+
+// ticks returns six evenly spaced axis labels of the elapsed run time
+func (s timeScale) ticks() []axisTick {
+	var ticks []axisTick
+	for i := 0; i <= 5; i++ {
+		offset := s.span * time.Duration(i) / 5
+		ticks = append(ticks, axisTick{
+			X:     chartLabelWidth + chartPlotWidth*float64(i)/5,
+			Label: offset.Round(tickPrecision(s.span)).String(),
+		})
+	}
+	return ticks
+}
+
+// tickPrecision rounds axis labels to a resolution that suits the run length
+func tickPrecision(span time.Duration) time.Duration {
+	switch {
+	case span >= time.Minute:
+		return time.Second
+	case span >= time.Second:
+		return 100 * time.Millisecond
+	}
+	return time.Millisecond
+}
+
+// buildGantt lays out one row per image, grouped by worker in start order
+func buildGantt(results []types.PullResult, start, end time.Time) *ganttChart {
+	scale := timeScale{start: start, span: end.Sub(start)}
+
+	timed := make([]types.PullResult, 0, len(results))
+	for _, result := range results {
+		if !result.StartedAt.IsZero() && !result.FinishedAt.IsZero() {
+			timed = append(timed, result)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool {
+		if timed[i].Worker != timed[j].Worker {
+			return timed[i].Worker < timed[j].Worker
+		}
+		return timed[i].StartedAt.Before(timed[j].StartedAt)
+	})
+
+	chart := &ganttChart{
+		Width:  chartLabelWidth + chartPlotWidth + 20,
+		Height: chartAxisHeight + float64(len(timed))*ganttRowHeight + 4,
+		Ticks:  scale.ticks(),
+	}
+	for i, result := range timed {
+		image := security.SanitizeLogMessage(result.Image)
+		label := image
+		if result.Worker > 0 {
+			label = fmt.Sprintf("W%d  %s", result.Worker, image)
+		}
+		chart.Rows = append(chart.Rows, ganttRow{
+			Y:        chartAxisHeight + float64(i)*ganttRowHeight,
+			BarY:     chartAxisHeight + float64(i)*ganttRowHeight + (ganttRowHeight-ganttBarHeight)/2,
+			Label:    truncateLabel(label, 40),
+			Title:    fmt.Sprintf("%s: %s in %d attempts", image, resultStatus(result), result.Attempts),
+			Segments: ganttSegments(result, scale),
+		})
+	}
+	return chart
+}
+
+// ganttSegments splits an image's time into attempts, backoff between them
+// and the post-pull steps after the last attempt
+func ganttSegments(result types.PullResult, scale timeScale) []ganttSegment {
+	segment := func(from, to time.Time, class, title string) ganttSegment {
+		x := scale.x(from)
+		return ganttSegment{X: x, Width: max(scale.x(to)-x, 1), Class: class, Title: title}
+	}
+
+	if len(result.Timeline) == 0 {
+		class := "done"
+		if !result.Success {
+			class = "failed"
+		}
+		return []ganttSegment{segment(result.StartedAt, result.FinishedAt, class,
+			fmt.Sprintf("%s (%v)", resultStatus(result), result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond)))}
+	}
+
+	var segments []ganttSegment
+	previous := result.StartedAt
+	for i, attempt := range result.Timeline {
+		if attempt.StartedAt.After(previous) {
+			segments = append(segments, segment(previous, attempt.StartedAt, "wait",
+				fmt.Sprintf("waiting %v", attempt.StartedAt.Sub(previous).Round(time.Millisecond))))
+		}
+
+		class, outcome := "done", "succeeded"
+		if attempt.Error != "" {
+			class, outcome = "retry", "failed: "+attempt.Error
+			if i == len(result.Timeline)-1 {
+				class = "failed"
+			}
+		}
+		segments = append(segments, segment(attempt.StartedAt, attempt.FinishedAt, class,
+			fmt.Sprintf("attempt %d (%v) %s", attempt.Number, attempt.FinishedAt.Sub(attempt.StartedAt).Round(time.Millisecond), outcome)))
+		previous = attempt.FinishedAt
+	}
+
+	if result.FinishedAt.After(previous) {
+		class := "verify"
+		if !result.Success && result.Timeline[len(result.Timeline)-1].Error == "" {
+			class = "failed" // Pulled, then rejected by a post-pull check
+		}
+		segments = append(segments, segment(previous, result.FinishedAt, class,
+			fmt.Sprintf("post-pull checks (%v)", result.FinishedAt.Sub(previous).Round(time.Millisecond))))
+	}
+	return segments
+}
+
+// buildThroughput spreads the size of every pulled image evenly over its
+// successful attempt and sums the bytes per second in fixed time buckets
+func buildThroughput(results []types.PullResult, start, end time.Time) *throughputChart {
+	scale := timeScale{start: start, span: end.Sub(start)}
+	bucketSpan := scale.span / throughputBuckets
+	if bucketSpan <= 0 {
+		return nil
+	}
+
+	bytesPerBucket := make([]float64, throughputBuckets)
+	for _, result := range results {
+		if !result.Success || result.ImageSize <= 0 || len(result.Timeline) == 0 {
+			continue
+		}
+		last := result.Timeline[len(result.Timeline)-1]
+		pullTime := last.FinishedAt.Sub(last.StartedAt)
+		if pullTime <= 0 {
+			continue
+		}
+		for i := range bytesPerBucket {
+			from := start.Add(bucketSpan * time.Duration(i))
+			to := from.Add(bucketSpan)
+			if last.StartedAt.After(from) {
+				from = last.StartedAt
+			}
+			if last.FinishedAt.Before(to) {
+				to = last.FinishedAt
+			}
+			if overlap := to.Sub(from); overlap > 0 {
+				bytesPerBucket[i] += float64(result.ImageSize) * float64(overlap) / float64(pullTime)
+			}
+		}
+	}
+
+	var peak float64
+	for _, b := range bytesPerBucket {
+		peak = max(peak, b/bucketSpan.Seconds())
+	}
+	if peak == 0 {
+		return nil
+	}
+
+	chart := &throughputChart{
+		Width:  chartLabelWidth + chartPlotWidth + 20,
+		Height: throughputHeight + chartAxisHeight,
+		Ticks:  scale.ticks(),
+		Peak:   units.HumanSize(peak) + "/s",
+	}
+	barWidth := chartPlotWidth / throughputBuckets
+	for i, b := range bytesPerBucket {
+		rate := b / bucketSpan.Seconds()
+		if rate == 0 {
+			continue
+		}
+		height := rate / peak * throughputHeight
+		chart.Bars = append(chart.Bars, throughputBar{
+			X:      chartLabelWidth + float64(i)*barWidth,
+			Y:      chartAxisHeight + throughputHeight - height,
+			Width:  barWidth - 1,
+			Height: height,
+			Title:  fmt.Sprintf("%s/s at %v", units.HumanSize(rate), (bucketSpan * time.Duration(i)).Round(tickPrecision(bucketSpan))),
+		})
+	}
+	return chart
+}
+
+// truncateLabel shortens a label to at most n runes
+func truncateLabel(label string, n int) string {
+	runes := []rune(label)
+	if len(runes) <= n {
+		return label
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// px formats an SVG coordinate
+func px(v float64) string {
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"px":   px,
+	"rate": byteRate,
+	"ms":   func(d time.Duration) time.Duration { return d.Round(time.Millisecond) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="Content-Security-Policy" content="default-src 'none'; style-src 'unsafe-inline'; script-src 'unsafe-inline'">
+<title>docker-parallel-pull report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; font-size: 0.9em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; }
+th { background: #f6f8fa; }
+#results th { cursor: pointer; user-select: none; }
+#results th.asc::after { content: " ▲"; }
+#results th.desc::after { content: " ▼"; }
+td.num { text-align: right; }
+tr.failed td { background: #ffebe9; }
+svg { font-size: 11px; }
+svg .axis { stroke: #d0d7de; }
+svg .done { fill: #2da44e; }
+svg .retry { fill: #d4a72c; }
+svg .failed { fill: #cf222e; }
+svg .wait { fill: #afb8c1; }
+svg .verify { fill: #54aeff; }
+svg .rate { fill: #8250df; }
+.legend span { display: inline-block; margin-right: 1.5em; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; vertical-align: middle; }
+</style>
+</head>
+<body>
+<h1>🐳 docker-parallel-pull report</h1>
+<p>Generated {{.Generated}}</p>
+
+<h2>📊 Summary</h2>
+<table>
+{{- range .Summary}}
+<tr><th>{{index . 0}}</th><td class="num">{{index . 1}}</td></tr>
+{{- end}}
+</table>
+
+{{- with .Registries}}
+<h2>🌐 Per Registry</h2>
+<table>
+<tr><th>Registry</th><th>Images</th><th>Failed</th><th>Retries</th><th>p50</th><th>p90</th><th>p95</th><th>p99</th><th>Max</th><th>Throughput</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td class="num">{{.Metrics.Images}}</td><td class="num">{{.Metrics.FailureCount}}</td><td class="num">{{.Metrics.Retries}}</td>
+<td class="num">{{ms .Metrics.Durations.P50}}</td><td class="num">{{ms .Metrics.Durations.P90}}</td><td class="num">{{ms .Metrics.Durations.P95}}</td>
+<td class="num">{{ms .Metrics.Durations.P99}}</td><td class="num">{{ms .Metrics.Durations.Max}}</td><td class="num">{{rate .Metrics.Throughput}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- with .Categories}}
+<h2>🏷️ Failures by Category</h2>
+<table>
+<tr><th>Category</th><th>Images</th><th>Retries</th><th>p50</th><th>p95</th><th>Max</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td class="num">{{.Metrics.Images}}</td><td class="num">{{.Metrics.Retries}}</td>
+<td class="num">{{ms .Metrics.Durations.P50}}</td><td class="num">{{ms .Metrics.Durations.P95}}</td><td class="num">{{ms .Metrics.Durations.Max}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- with .Gantt}}
+<h2>🕒 Timeline</h2>
+<p class="legend">
+<span><i style="background:#2da44e"></i>attempt succeeded</span>
+<span><i style="background:#d4a72c"></i>attempt failed, retried</span>
+<span><i style="background:#cf222e"></i>failed</span>
+<span><i style="background:#afb8c1"></i>backoff</span>
+<span><i style="background:#54aeff"></i>post-pull checks</span>
+</p>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{px .Width}}" height="{{px .Height}}" viewBox="0 0 {{px .Width}} {{px .Height}}">
+{{- $height := .Height}}
+{{- range .Ticks}}
+<line class="axis" x1="{{px .X}}" y1="16" x2="{{px .X}}" y2="{{px $height}}"/>
+<text x="{{px .X}}" y="12" text-anchor="middle">{{.Label}}</text>
+{{- end}}
+{{- range .Rows}}
+<g><title>{{.Title}}</title>
+<text x="4" y="{{px .Y}}" dy="14">{{.Label}}</text>
+{{- $y := .BarY}}
+{{- range .Segments}}
+<rect class="{{.Class}}" x="{{px .X}}" y="{{px $y}}" width="{{px .Width}}" height="14"><title>{{.Title}}</title></rect>
+{{- end}}
+</g>
+{{- end}}
+</svg>
+{{- end}}
+
+{{- with .Throughput}}
+<h2>📈 Throughput</h2>
+<p>Image bytes pulled per second, peak {{.Peak}}</p>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{px .Width}}" height="{{px .Height}}" viewBox="0 0 {{px .Width}} {{px .Height}}">
+{{- $height := .Height}}
+{{- range .Ticks}}
+<line class="axis" x1="{{px .X}}" y1="16" x2="{{px .X}}" y2="{{px $height}}"/>
+<text x="{{px .X}}" y="12" text-anchor="middle">{{.Label}}</text>
+{{- end}}
+<text x="4" y="30">{{.Peak}}</text>
+{{- range .Bars}}
+<rect class="rate" x="{{px .X}}" y="{{px .Y}}" width="{{px .Width}}" height="{{px .Height}}"><title>{{.Title}}</title></rect>
+{{- end}}
+</svg>
+{{- end}}
+
+<h2>📋 Images</h2>
+<table id="results">
+<thead><tr>
+<th data-type="text">Image</th><th data-type="text">Status</th><th data-type="number">Worker</th>
+<th data-type="number">Attempts</th><th data-type="number">Duration</th><th data-type="number">Size</th>
+<th data-type="text">Started</th><th data-type="text">Finished</th><th data-type="text">Digest</th>
+<th data-type="text">Error category</th><th data-type="text">Error</th>
+</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr{{if not .Success}} class="failed"{{end}}>
+<td>{{.Image}}</td><td>{{if .Success}}✅ success{{else}}❌ failed{{end}}</td>
+<td class="num" data-value="{{.Worker}}">{{if .Worker}}{{.Worker}}{{end}}</td>
+<td class="num" data-value="{{.Attempts}}">{{.Attempts}}</td>
+<td class="num" data-value="{{.Seconds}}">{{.Duration}}</td>
+<td class="num" data-value="{{.SizeBytes}}">{{.Size}}</td>
+<td>{{.StartedAt}}</td><td>{{.FinishedAt}}</td>
+<td><code>{{.Digest}}</code></td><td>{{.Category}}</td><td>{{.Error}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+
+<script>
+document.querySelectorAll("#results th").forEach(function (th, column) {
+  th.addEventListener("click", function () {
+    var body = document.querySelector("#results tbody");
+    var ascending = !th.classList.contains("asc");
+    var numeric = th.dataset.type === "number";
+    var rows = Array.from(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[column], y = b.cells[column];
+      var order = numeric
+        ? Number(x.dataset.value) - Number(y.dataset.value)
+        : x.textContent.localeCompare(y.textContent);
+      return ascending ? order : -order;
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+    document.querySelectorAll("#results th").forEach(function (other) { other.classList.remove("asc", "desc"); });
+    th.classList.add(ascending ? "asc" : "desc");
+  });
+});
+</script>
+</body>
+</html>
+`))
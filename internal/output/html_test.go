@@ -0,0 +1,88 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestRenderHTML(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds float64) time.Time { return start.Add(time.Duration(seconds * float64(time.Second))) }
+
+	results := []types.PullResult{
+		{Image: "nginx:latest", Success: true, Worker: 1, Attempts: 2, Duration: 8 * time.Second, ImageSize: 50_000_000,
+			StartedAt: at(0), FinishedAt: at(10),
+			Timeline: []types.Attempt{
+				{Number: 1, StartedAt: at(0), FinishedAt: at(2), Error: "connection reset"},
+				{Number: 2, StartedAt: at(4), FinishedAt: at(8)},
+			}},
+		{Image: "<script>alert(1)</script>", Worker: 2, Attempts: 1, Duration: time.Second,
+			Error: "not found", ErrorCategory: types.ErrorCategoryNotFound,
+			StartedAt: at(1), FinishedAt: at(2),
+			Timeline: []types.Attempt{{Number: 1, StartedAt: at(1), FinishedAt: at(2), Error: "not found"}}},
+	}
+	metrics := types.PullMetrics{TotalImages: 2, SuccessCount: 1, FailureCount: 1, TotalRetries: 1, TotalDuration: 10 * time.Second}
+
+	data, err := RenderHTML(metrics, results, &config.Config{})
+	if err != nil {
+		t.Fatalf("RenderHTML() error = %v", err)
+	}
+	report := string(data)
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<rect class="retry"`,
+		`<rect class="wait"`,
+		`<rect class="done"`,
+		`<rect class="verify"`,
+		`<rect class="failed"`,
+		`<rect class="rate"`,
+		"W1  nginx:latest",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"not_found",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("RenderHTML() missing %q", want)
+		}
+	}
+	if strings.Contains(report, "<script>alert") {
+		t.Error("RenderHTML() did not escape the image name")
+	}
+	for _, external := range []string{"src=", "href=", "@import"} {
+		if strings.Contains(report, external) {
+			t.Errorf("RenderHTML() references an external asset (%s)", external)
+		}
+	}
+}
+
+func TestGanttSegments(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	scale := timeScale{start: start, span: 10 * time.Second}
+
+	result := types.PullResult{
+		Success: true, StartedAt: start, FinishedAt: start.Add(10 * time.Second),
+		Timeline: []types.Attempt{
+			{Number: 1, StartedAt: start, FinishedAt: start.Add(2 * time.Second), Error: "timeout"},
+			{Number: 2, StartedAt: start.Add(5 * time.Second), FinishedAt: start.Add(9 * time.Second)},
+		},
+	}
+
+	segments := ganttSegments(result, scale)
+	var classes []string
+	for _, segment := range segments {
+		classes = append(classes, segment.Class)
+	}
+	if got := strings.Join(classes, ","); got != "retry,wait,done,verify" {
+		t.Fatalf("ganttSegments() classes = %s", got)
+	}
+	if segments[0].X != chartLabelWidth || segments[0].Width != chartPlotWidth*0.2 {
+		t.Errorf("ganttSegments() first segment = %+v", segments[0])
+	}
+	if end := segments[3].X + segments[3].Width; end != chartLabelWidth+chartPlotWidth {
+		t.Errorf("ganttSegments() last segment ends at %v", end)
+	}
+}
@@ -0,0 +1,132 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// junitSuiteName names the test suite of a pull run
+const junitSuiteName = "docker-parallel-pull"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	ClassName  string          `xml:"classname,attr"`
+	Name       string          `xml:"name,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitFailure   `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// RenderJUnit renders pull results as a JUnit XML report with one test case per image
+func RenderJUnit(metrics types.PullMetrics, results []types.PullResult) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:     junitSuiteName,
+		Tests:    metrics.TotalImages,
+		Failures: metrics.FailureCount,
+		Time:     seconds(metrics.TotalDuration),
+		Properties: []junitProperty{
+			{Name: "concurrency", Value: strconv.Itoa(metrics.Concurrency)},
+			{Name: "total_retries", Value: strconv.Itoa(metrics.TotalRetries)},
+			{Name: "average_duration", Value: seconds(metrics.AverageDuration)},
+			{Name: "p50_duration", Value: seconds(metrics.Durations.P50)},
+			{Name: "p95_duration", Value: seconds(metrics.Durations.P95)},
+			{Name: "p99_duration", Value: seconds(metrics.Durations.P99)},
+			{Name: "max_duration", Value: seconds(metrics.Durations.Max)},
+		},
+	}
+
+	var started time.Time
+	for _, result := range results {
+		if !result.StartedAt.IsZero() && (started.IsZero() || result.StartedAt.Before(started)) {
+			started = result.StartedAt
+		}
+
+		testCase := junitTestCase{
+			ClassName: "pull",
+			Name:      security.SanitizeLogMessage(result.Image),
+			Time:      seconds(result.Duration),
+			Properties: []junitProperty{
+				{Name: "attempts", Value: strconv.Itoa(result.Attempts)},
+			},
+		}
+		if result.CanonicalImage != "" {
+			testCase.Properties = append(testCase.Properties, junitProperty{Name: "canonical_image", Value: security.SanitizeLogMessage(result.CanonicalImage)})
+		}
+		if result.Digest != "" {
+			testCase.Properties = append(testCase.Properties, junitProperty{Name: "digest", Value: result.Digest})
+		}
+		if result.ImageSize > 0 {
+			testCase.Properties = append(testCase.Properties, junitProperty{Name: "image_size", Value: strconv.FormatInt(result.ImageSize, 10)})
+		}
+		if !result.Success {
+			message := result.Error
+			if message == "" {
+				message = "pull failed"
+			}
+			testCase.Failure = &junitFailure{
+				Message: message,
+				Type:    "PullFailure",
+				Text:    fmt.Sprintf("%s failed after %d attempts: %s", security.SanitizeLogMessage(result.Image), result.Attempts, message),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	if !started.IsZero() {
+		suite.Timestamp = started.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	report := junitTestSuites{
+		Name:     junitSuiteName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// seconds formats a duration as fractional seconds, as used by JUnit time attributes
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
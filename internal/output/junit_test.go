@@ -0,0 +1,46 @@
+package output
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestRenderJUnit(t *testing.T) {
+	results := []types.PullResult{
+		{Image: "nginx:latest", CanonicalImage: "docker.io/library/nginx:latest", Success: true,
+			Duration: 1500 * time.Millisecond, Attempts: 1, Digest: "sha256:abc"},
+		{Image: "ghcr.io/org/missing:1", Success: false, Duration: 3 * time.Second, Attempts: 4,
+			Error: "manifest unknown <&>"},
+	}
+	metrics := types.PullMetrics{TotalImages: 2, SuccessCount: 1, FailureCount: 1, TotalDuration: 4 * time.Second, Concurrency: 5}
+
+	data, err := RenderJUnit(metrics, results)
+	if err != nil {
+		t.Fatalf("RenderJUnit() error = %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("RenderJUnit() produced invalid XML: %v\n%s", err, data)
+	}
+	if report.Tests != 2 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("RenderJUnit() summary = %+v", report)
+	}
+
+	cases := report.Suites[0].TestCases
+	if len(cases) != 2 {
+		t.Fatalf("RenderJUnit() test cases = %d, want 2", len(cases))
+	}
+	if cases[0].Time != "1.500" || cases[0].Failure != nil {
+		t.Errorf("RenderJUnit() successful case = %+v", cases[0])
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Message != "manifest unknown <&>" {
+		t.Errorf("RenderJUnit() failed case = %+v", cases[1])
+	}
+	if cases[1].Properties[0].Name != "attempts" || cases[1].Properties[0].Value != "4" {
+		t.Errorf("RenderJUnit() properties = %+v", cases[1].Properties)
+	}
+}
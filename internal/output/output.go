@@ -3,10 +3,16 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"time"
 
+	"github.com/docker/go-units"
+
+	"github.com/guessi/docker-parallel-pull/internal/audit"
 	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/history"
 	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/stats"
 	"github.com/guessi/docker-parallel-pull/internal/types"
 )
 
@@ -27,6 +33,9 @@ func SecureLogMessage(config *config.Config, level, message string) {
 		if data, err := json.Marshal(logEntry); err == nil {
 			fmt.Println(string(data))
 		}
+	} else if config.OutputFormat != "text" {
+		// Keep standard output a valid report document
+		fmt.Fprintf(os.Stderr, "[%s] %s: %s\n", time.Now().Format("15:04:05"), level, sanitizedMessage)
 	} else {
 		fmt.Printf("[%s] %s: %s\n", time.Now().Format("15:04:05"), level, sanitizedMessage)
 	}
@@ -38,7 +47,7 @@ func CalculateMetrics(results []types.PullResult, config *config.Config, totalDu
 		return types.PullMetrics{}
 	}
 
-	var successful, failed, totalRetries int
+	var successful, failed, totalRetries, drifted, policyViolations, unverified, pushed, pushFailed, vulnerable int
 	var totalPullDuration time.Duration
 
 	for _, result := range results {
@@ -49,45 +58,227 @@ func CalculateMetrics(results []types.PullResult, config *config.Config, totalDu
 		}
 		totalRetries += result.Attempts - 1
 		totalPullDuration += result.Duration
+		if result.Drifted {
+			drifted++
+		}
+		if len(result.PolicyViolations) > 0 {
+			policyViolations++
+		}
+		if result.Signature != "" && result.Signature != "verified" {
+			unverified++
+		}
+		if result.Vulnerabilities != nil && len(result.Vulnerabilities.Blocking) > 0 {
+			vulnerable++
+		}
+		if result.Push != nil {
+			if result.Push.Success {
+				pushed++
+			} else {
+				pushFailed++
+			}
+		}
 	}
 
-	avgDuration := time.Duration(0)
-	if len(results) > 0 {
-		avgDuration = totalPullDuration / time.Duration(len(results))
-	}
+	// The average covers the attempted pulls, like the percentiles
+	overall := stats.Group(results)
 
 	return types.PullMetrics{
-		TotalImages:     len(results),
-		SuccessCount:    successful,
-		FailureCount:    failed,
-		TotalDuration:   totalDuration,
-		AverageDuration: avgDuration,
-		TotalRetries:    totalRetries,
-		Concurrency:     config.MaxConcurrency,
+		TotalImages:          len(results),
+		SuccessCount:         successful,
+		FailureCount:         failed,
+		TotalDuration:        totalDuration,
+		CumulativeDuration:   totalPullDuration,
+		AverageDuration:      overall.Durations.Mean,
+		Durations:            overall.Durations,
+		TotalBytes:           overall.TotalBytes,
+		Throughput:           overall.Throughput,
+		TotalRetries:         totalRetries,
+		Concurrency:          config.MaxConcurrency,
+		DriftedCount:         drifted,
+		PolicyViolationCount: policyViolations,
+		UnverifiedCount:      unverified,
+		PushedCount:          pushed,
+		PushFailedCount:      pushFailed,
+		VulnerableCount:      vulnerable,
+		ByRegistry:           groupMetrics(results, stats.Registry),
+		ByErrorCategory:      groupMetrics(failedResults(results), errorCategory),
 	}
 }
 
-// OutputResults displays the final results based on output format
-func OutputResults(metrics types.PullMetrics, results []types.PullResult, config *config.Config) {
+// groupMetrics computes the statistics of the results sharing each key
+func groupMetrics[K comparable](results []types.PullResult, key func(types.PullResult) K) map[K]types.GroupMetrics {
+	if len(results) == 0 {
+		return nil
+	}
+	groups := make(map[K][]types.PullResult)
+	for _, result := range results {
+		groups[key(result)] = append(groups[key(result)], result)
+	}
+	metrics := make(map[K]types.GroupMetrics, len(groups))
+	for k, group := range groups {
+		metrics[k] = stats.Group(group)
+	}
+	return metrics
+}
+
+// failedResults returns the results of the images that failed
+func failedResults(results []types.PullResult) []types.PullResult {
+	var failed []types.PullResult
+	for _, result := range results {
+		if !result.Success {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// OutputImagePlan displays the resolved images without pulling them (dry run)
+func OutputImagePlan(images []types.ImageRef, config *config.Config) {
 	if config == nil {
 		return
 	}
 
 	if config.OutputFormat == "json" {
 		output := map[string]interface{}{
-			"metrics": metrics,
-			"results": results,
+			"dry_run": true,
+			"images":  images,
 		}
 		if data, err := json.MarshalIndent(output, "", "  "); err == nil {
 			fmt.Println(string(data))
 		}
 	} else {
-		fmt.Printf("\n📊 Pull Summary:\n")
-		fmt.Printf("   ✅ Successful: %d\n", metrics.SuccessCount)
-		fmt.Printf("   ❌ Failed: %d\n", metrics.FailureCount)
-		fmt.Printf("   🔄 Total retries: %d\n", metrics.TotalRetries)
-		fmt.Printf("   ⏱️  Total time: %v\n", metrics.TotalDuration.Round(time.Second))
-		fmt.Printf("   📈 Average time per image: %v\n", metrics.AverageDuration.Round(time.Second))
-		fmt.Printf("   🚀 Concurrency: %d\n", metrics.Concurrency)
+		fmt.Printf("\n📋 Resolved %d images (dry run, nothing pulled):\n", len(images))
+		for _, image := range images {
+			fmt.Printf("   • %s (%s)\n", security.SanitizeLogMessage(image.Name), security.SanitizeLogMessage(image.Canonical))
+			for _, alias := range image.Aliases {
+				fmt.Printf("       also listed as %s\n", security.SanitizeLogMessage(alias))
+			}
+			for _, source := range image.Sources {
+				fmt.Printf("       from %s\n", security.SanitizeLogMessage(source))
+			}
+			for _, violation := range image.PolicyViolations {
+				fmt.Printf("       🚫 %s\n", violation)
+			}
+		}
+	}
+}
+
+// OutputCheckResults displays the comparison of local images to their remote tags
+func OutputCheckResults(results []types.CheckResult, config *config.Config) {
+	if config == nil {
+		return
+	}
+
+	counts := make(map[types.CheckStatus]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	if config.OutputFormat == "json" {
+		output := map[string]interface{}{
+			"summary": counts,
+			"results": results,
+		}
+		if data, err := json.MarshalIndent(output, "", "  "); err == nil {
+			fmt.Println(string(data))
+		}
+		return
+	}
+
+	icons := map[types.CheckStatus]string{
+		types.CheckStatusUpToDate: "✅",
+		types.CheckStatusStale:    "🔄",
+		types.CheckStatusMissing:  "📭",
+		types.CheckStatusError:    "❌",
+	}
+
+	fmt.Printf("\n🔎 Image Check:\n")
+	for _, result := range results {
+		fmt.Printf("   %s %s: %s\n", icons[result.Status], security.SanitizeLogMessage(result.Image), result.Status)
+		if result.Error != "" {
+			fmt.Printf("      %s\n", result.Error)
+		}
+	}
+	fmt.Printf("\n   Up to date: %d, stale: %d, missing: %d, errors: %d\n",
+		counts[types.CheckStatusUpToDate], counts[types.CheckStatusStale],
+		counts[types.CheckStatusMissing], counts[types.CheckStatusError])
+}
+
+// OutputAuditVerification reports the result of verifying an audit log
+func OutputAuditVerification(head audit.Head, verifyErr error, config *config.Config) {
+	if config == nil {
+		return
+	}
+
+	if config.OutputFormat == "json" {
+		output := map[string]interface{}{
+			"valid":   verifyErr == nil,
+			"entries": head.Seq,
+			"head":    head.Hash,
+		}
+		if verifyErr != nil {
+			output["error"] = security.SanitizeErrorMessage(verifyErr)
+		}
+		if data, err := json.MarshalIndent(output, "", "  "); err == nil {
+			fmt.Println(string(data))
+		}
+		return
+	}
+
+	if verifyErr != nil {
+		fmt.Printf("\n❌ Audit log verification failed: %s\n", security.SanitizeErrorMessage(verifyErr))
+		return
+	}
+	fmt.Printf("\n✅ Audit log intact: %d entries\n", head.Seq)
+	if head.Hash != "" {
+		fmt.Printf("   Head hash: %s\n", head.Hash)
+	}
+}
+
+// OutputComparison displays the latest run compared to its baseline runs
+func OutputComparison(comparison history.Comparison, config *config.Config) {
+	if config == nil {
+		return
+	}
+
+	if config.OutputFormat == "json" {
+		if data, err := json.MarshalIndent(comparison, "", "  "); err == nil {
+			fmt.Println(string(data))
+		}
+		return
+	}
+
+	fmt.Printf("\n📈 Run %s compared to %d previous runs:\n", comparison.RunID, len(comparison.BaselineRuns))
+	if len(comparison.BaselineRuns) > 0 {
+		icon := "✅"
+		if comparison.Regressed {
+			icon = "🔺"
+		}
+		fmt.Printf("   %s Total time: %v (baseline %v, %+.1f%%)\n", icon, comparison.Duration.Round(time.Millisecond),
+			comparison.BaselineDuration.Round(time.Millisecond), comparison.DurationChange)
+	}
+
+	icons := map[string]string{
+		history.StatusOK:        "✅",
+		history.StatusRegressed: "🔺",
+		history.StatusNew:       "🆕",
+		history.StatusFailed:    "❌",
+	}
+	for _, image := range comparison.Images {
+		fmt.Printf("   %s %s: %s\n", icons[image.Status], security.SanitizeLogMessage(image.Image), image.Status)
+		switch image.Status {
+		case history.StatusOK, history.StatusRegressed:
+			fmt.Printf("      pull time %v (baseline %v, %+.1f%%)\n", image.Duration.Round(time.Millisecond),
+				image.BaselineDuration.Round(time.Millisecond), image.DurationChange)
+			if image.BaselineSize > 0 {
+				fmt.Printf("      size %s (baseline %s, %+.1f%%)\n", units.HumanSize(float64(image.Size)),
+					units.HumanSize(float64(image.BaselineSize)), image.SizeChange)
+			}
+		case history.StatusFailed:
+			fmt.Printf("      error category: %s\n", image.ErrorCategory)
+		}
 	}
+	fmt.Printf("\n   Regressed: %d, ok: %d, new: %d, failed: %d\n",
+		comparison.Counts[history.StatusRegressed], comparison.Counts[history.StatusOK],
+		comparison.Counts[history.StatusNew], comparison.Counts[history.StatusFailed])
 }
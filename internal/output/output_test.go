@@ -0,0 +1,55 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestCalculateMetrics(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	results := []types.PullResult{
+		{Image: "nginx:latest", CanonicalImage: "docker.io/library/nginx:latest", Success: true,
+			Attempts: 1, Duration: 2 * time.Second, ImageSize: 40_000_000, StartedAt: start, FinishedAt: start.Add(2 * time.Second)},
+		{Image: "ghcr.io/org/app:1", CanonicalImage: "ghcr.io/org/app:1", Success: true,
+			Attempts: 2, Duration: 6 * time.Second, ImageSize: 60_000_000, StartedAt: start.Add(4 * time.Second), FinishedAt: start.Add(10 * time.Second)},
+		{Image: "ghcr.io/org/missing:1", CanonicalImage: "ghcr.io/org/missing:1",
+			Attempts: 4, Duration: 4 * time.Second, ErrorCategory: types.ErrorCategoryNotFound, StartedAt: start, FinishedAt: start.Add(4 * time.Second)},
+		{Image: "bad image", Attempts: 1, Duration: time.Millisecond, StartedAt: start, FinishedAt: start.Add(time.Millisecond)},
+		{Image: "redis:latest", CanonicalImage: "docker.io/library/redis:latest", ErrorCategory: types.ErrorCategoryPolicy,
+			PolicyViolations: []string{"latest tag is forbidden"}, StartedAt: start, FinishedAt: start},
+	}
+
+	// The run took longer than its pulls, e.g. to resolve the images first
+	metrics := CalculateMetrics(results, &config.Config{MaxConcurrency: 2}, 20*time.Second)
+
+	if metrics.CumulativeDuration != 12*time.Second+time.Millisecond {
+		t.Errorf("CumulativeDuration = %v", metrics.CumulativeDuration)
+	}
+	// The policy-denied image was never pulled and is left out of the percentiles
+	if metrics.Durations.P50 != 2*time.Second || metrics.Durations.Max != 6*time.Second || metrics.Durations.Mean != (12*time.Second+time.Millisecond)/4 {
+		t.Errorf("Durations = %+v", metrics.Durations)
+	}
+	if metrics.AverageDuration != metrics.Durations.Mean {
+		t.Errorf("AverageDuration = %v, want the mean of the attempted pulls %v", metrics.AverageDuration, metrics.Durations.Mean)
+	}
+	if metrics.TotalBytes != 100_000_000 || metrics.Throughput != 10_000_000 {
+		t.Errorf("TotalBytes = %d, Throughput = %v", metrics.TotalBytes, metrics.Throughput)
+	}
+
+	ghcr := metrics.ByRegistry["ghcr.io"]
+	if ghcr.Images != 2 || ghcr.FailureCount != 1 || ghcr.Retries != 4 || ghcr.Durations.Max != 6*time.Second {
+		t.Errorf("ByRegistry[ghcr.io] = %+v", ghcr)
+	}
+	if metrics.ByRegistry["registry-1.docker.io"].Images != 2 || metrics.ByRegistry["unknown"].Images != 1 {
+		t.Errorf("ByRegistry = %+v", metrics.ByRegistry)
+	}
+
+	if len(metrics.ByErrorCategory) != 3 ||
+		metrics.ByErrorCategory[types.ErrorCategoryNotFound].Images != 1 ||
+		metrics.ByErrorCategory[types.ErrorCategoryUnknown].Images != 1 {
+		t.Errorf("ByErrorCategory = %+v", metrics.ByErrorCategory)
+	}
+}
@@ -0,0 +1,203 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// renderer renders pull results in one report format
+type renderer func(metrics types.PullMetrics, results []types.PullResult, config *config.Config) ([]byte, error)
+
+// renderers maps each report format to its renderer
+var renderers = map[string]renderer{
+	"text": RenderText,
+	"json": RenderJSON,
+	"junit": func(metrics types.PullMetrics, results []types.PullResult, _ *config.Config) ([]byte, error) {
+		return RenderJUnit(metrics, results)
+	},
+	"csv":      RenderCSV,
+	"markdown": RenderMarkdown,
+	"html":     RenderHTML,
+}
+
+// OutputResults prints the report in the configured output format and writes
+// every configured reporter's report to its file
+func OutputResults(metrics types.PullMetrics, results []types.PullResult, config *config.Config) {
+	if config == nil {
+		return
+	}
+
+	if data, err := renderers[config.OutputFormat](metrics, results, config); err != nil {
+		SecureLogMessage(config, "ERROR", err.Error())
+	} else {
+		fmt.Print(string(data))
+	}
+
+	for _, reporter := range config.Reporters {
+		if err := writeReport(reporter, metrics, results, config); err != nil {
+			SecureLogMessage(config, "ERROR", fmt.Sprintf("Failed to write %s report: %v", reporter.Format, err))
+			continue
+		}
+		SecureLogMessage(config, "INFO", fmt.Sprintf("Wrote %s report to %s", reporter.Format, reporter.Path))
+	}
+}
+
+// writeReport renders the report of one reporter and atomically replaces its file
+func writeReport(reporter config.ReporterConfig, metrics types.PullMetrics, results []types.PullResult, config *config.Config) error {
+	render, ok := renderers[reporter.Format]
+	if !ok {
+		return fmt.Errorf("unsupported report format: %s", reporter.Format)
+	}
+	data, err := render(metrics, results, config)
+	if err != nil {
+		return err
+	}
+	return security.WriteFileAtomic(reporter.Path, data)
+}
+
+// RenderJSON renders the metrics and results as an indented JSON document
+func RenderJSON(metrics types.PullMetrics, results []types.PullResult, _ *config.Config) ([]byte, error) {
+	output := map[string]interface{}{
+		"metrics": metrics,
+		"results": results,
+	}
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON report: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// RenderText renders the human readable pull summary
+func RenderText(metrics types.PullMetrics, results []types.PullResult, config *config.Config) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "\n📊 Pull Summary:\n")
+	fmt.Fprintf(&b, "   ✅ Successful: %d\n", metrics.SuccessCount)
+	fmt.Fprintf(&b, "   ❌ Failed: %d\n", metrics.FailureCount)
+	fmt.Fprintf(&b, "   🔄 Total retries: %d\n", metrics.TotalRetries)
+	fmt.Fprintf(&b, "   ⏱️  Total time: %v\n", metrics.TotalDuration.Round(time.Second))
+	fmt.Fprintf(&b, "   🧮 Cumulative pull time: %v\n", metrics.CumulativeDuration.Round(time.Second))
+	fmt.Fprintf(&b, "   📈 Average time per image: %v\n", metrics.AverageDuration.Round(time.Second))
+	fmt.Fprintf(&b, "   📉 Pull time p50/p90/p95/p99/max: %s\n", percentiles(metrics.Durations))
+	if metrics.TotalBytes > 0 {
+		fmt.Fprintf(&b, "   📦 Pulled: %s (%s)\n", units.HumanSize(float64(metrics.TotalBytes)), byteRate(metrics.Throughput))
+	}
+	fmt.Fprintf(&b, "   🚀 Concurrency: %d\n", metrics.Concurrency)
+	if groups := registryGroups(metrics); len(groups) > 1 {
+		fmt.Fprintf(&b, "   🌐 Per registry:\n")
+		for _, group := range groups {
+			fmt.Fprintf(&b, "      - %s: %d images, %d failed, p50 %v, p95 %v, max %v, %s\n", group.Name,
+				group.Metrics.Images, group.Metrics.FailureCount, group.Metrics.Durations.P50.Round(time.Millisecond),
+				group.Metrics.Durations.P95.Round(time.Millisecond), group.Metrics.Durations.Max.Round(time.Millisecond),
+				byteRate(group.Metrics.Throughput))
+		}
+	}
+	if groups := categoryGroups(metrics); len(groups) > 0 {
+		fmt.Fprintf(&b, "   🏷️  Failures by category:\n")
+		for _, group := range groups {
+			fmt.Fprintf(&b, "      - %s: %d images, p50 %v, max %v\n", group.Name, group.Metrics.Images,
+				group.Metrics.Durations.P50.Round(time.Millisecond), group.Metrics.Durations.Max.Round(time.Millisecond))
+		}
+	}
+	if config.PolicyFile != "" {
+		fmt.Fprintf(&b, "   🚫 Policy violations: %d\n", metrics.PolicyViolationCount)
+		for _, result := range results {
+			for _, violation := range result.PolicyViolations {
+				fmt.Fprintf(&b, "      - %s: %s\n", security.SanitizeLogMessage(result.Image), violation)
+			}
+		}
+	}
+	if config.Signature.Enabled {
+		fmt.Fprintf(&b, "   🔏 Unverified signatures: %d\n", metrics.UnverifiedCount)
+		for _, result := range results {
+			if result.Signature != "" && result.Signature != "verified" {
+				fmt.Fprintf(&b, "      - %s: %s\n", security.SanitizeLogMessage(result.Image), result.Signature)
+			}
+		}
+	}
+	if config.Vulnerability.Enabled {
+		fmt.Fprintf(&b, "   🐞 Failed vulnerability gate: %d\n", metrics.VulnerableCount)
+		for _, result := range results {
+			if result.Vulnerabilities == nil {
+				continue
+			}
+			for _, finding := range result.Vulnerabilities.Blocking {
+				fmt.Fprintf(&b, "      - %s: %s\n", security.SanitizeLogMessage(result.Image), finding)
+			}
+		}
+	}
+	if config.SBOM.Enabled {
+		written := 0
+		for _, result := range results {
+			if result.SBOM != "" {
+				written++
+			}
+		}
+		fmt.Fprintf(&b, "   📄 SBOMs written: %d (%s)\n", written, config.SBOM.Format)
+	}
+	if config.Locked {
+		fmt.Fprintf(&b, "   🔀 Drifted from lockfile: %d\n", metrics.DriftedCount)
+		for _, result := range results {
+			if result.Drifted {
+				fmt.Fprintf(&b, "      - %s: %s\n", security.SanitizeLogMessage(result.Image), result.Error)
+			}
+		}
+	}
+	if config.Mirror.Enabled {
+		fmt.Fprintf(&b, "   📤 Pushed to mirror: %d\n", metrics.PushedCount)
+		fmt.Fprintf(&b, "   ⚠️  Push failures: %d\n", metrics.PushFailedCount)
+	}
+	return b.Bytes(), nil
+}
+
+// metricGroup is one entry of the per-registry or per-error-category breakdown
+type metricGroup struct {
+	Name    string
+	Metrics types.GroupMetrics
+}
+
+// registryGroups returns the per-registry statistics ordered by registry
+func registryGroups(metrics types.PullMetrics) []metricGroup {
+	groups := make([]metricGroup, 0, len(metrics.ByRegistry))
+	for name, group := range metrics.ByRegistry {
+		groups = append(groups, metricGroup{Name: name, Metrics: group})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// categoryGroups returns the per-error-category statistics, most frequent first
+func categoryGroups(metrics types.PullMetrics) []metricGroup {
+	groups := make([]metricGroup, 0, len(metrics.ByErrorCategory))
+	for category, group := range metrics.ByErrorCategory {
+		groups = append(groups, metricGroup{Name: string(category), Metrics: group})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Metrics.Images != groups[j].Metrics.Images {
+			return groups[i].Metrics.Images > groups[j].Metrics.Images
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// percentiles formats the p50, p90, p95, p99 and maximum pull times
+func percentiles(durations types.DurationStats) string {
+	return fmt.Sprintf("%v / %v / %v / %v / %v",
+		durations.P50.Round(time.Millisecond), durations.P90.Round(time.Millisecond), durations.P95.Round(time.Millisecond),
+		durations.P99.Round(time.Millisecond), durations.Max.Round(time.Millisecond))
+}
+
+// byteRate formats a throughput in bytes per second
+func byteRate(rate float64) string {
+	return units.HumanSize(rate) + "/s"
+}
@@ -0,0 +1,58 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestWriteReport(t *testing.T) {
+	dir := t.TempDir()
+	if err := security.SetPathPolicy(security.PathPolicy{AllowedRoots: []string{dir}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = security.SetPathPolicy(security.DefaultPathPolicy()) })
+
+	results := []types.PullResult{{Image: "nginx:latest", Success: true, Duration: time.Second, Attempts: 1}}
+	metrics := types.PullMetrics{TotalImages: 1, SuccessCount: 1, TotalDuration: time.Second}
+	cfg := &config.Config{OutputFormat: "text"}
+
+	tests := []struct {
+		format string
+		check  func(data []byte) bool
+	}{
+		{format: "text", check: func(data []byte) bool { return strings.Contains(string(data), "Pull Summary") }},
+		{format: "json", check: func(data []byte) bool { return json.Valid(data) }},
+		{format: "junit", check: func(data []byte) bool { return strings.Contains(string(data), "<testsuites") }},
+		{format: "csv", check: func(data []byte) bool { return strings.HasPrefix(string(data), "record,image,status,") }},
+		{format: "markdown", check: func(data []byte) bool { return strings.Contains(string(data), "| nginx:latest |") }},
+		{format: "html", check: func(data []byte) bool { return strings.Contains(string(data), "<td>nginx:latest</td>") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(dir, "report."+tt.format)
+			if err := writeReport(config.ReporterConfig{Format: tt.format, Path: path}, metrics, results, cfg); err != nil {
+				t.Fatalf("writeReport() error = %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(data) {
+				t.Errorf("writeReport() wrote unexpected %s report:\n%s", tt.format, data)
+			}
+		})
+	}
+
+	if err := writeReport(config.ReporterConfig{Format: "xml", Path: filepath.Join(dir, "report.xml")}, metrics, results, cfg); err == nil {
+		t.Error("writeReport() accepted an unknown format")
+	}
+}
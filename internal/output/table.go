@@ -0,0 +1,226 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// Report sort orders
+const (
+	SortDuration = "duration" // Slowest pull first
+	SortImage    = "image"    // Alphabetical by image reference
+)
+
+// csvColumns are the fixed columns of the CSV report. The record column tells image rows,
+// which fill the image columns, from run metric rows, which fill metric and value.
+var csvColumns = []string{"record", "image", "status", "attempts", "duration_seconds", "size_bytes", "digest", "error_category", "metric", "value"}
+
+// RenderCSV renders one row per image followed by one row per run metric. The columns
+// are the same for every run, however many registries and error categories it saw.
+func RenderCSV(metrics types.PullMetrics, results []types.PullResult, config *config.Config) ([]byte, error) {
+	var b bytes.Buffer
+	writer := csv.NewWriter(&b)
+
+	_ = writer.Write(csvColumns)
+	for _, result := range sortResults(results, config.ReportSort) {
+		_ = writer.Write([]string{
+			"image",
+			csvCell(security.SanitizeLogMessage(result.Image)),
+			resultStatus(result),
+			strconv.Itoa(result.Attempts),
+			seconds(result.Duration),
+			strconv.FormatInt(result.ImageSize, 10),
+			result.Digest,
+			string(errorCategory(result)),
+			"", "",
+		})
+	}
+	for _, row := range metricRows(metrics) {
+		_ = writer.Write([]string{"metric", "", "", "", "", "", "", "", csvCell(row[0]), row[1]})
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to encode CSV report: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// RenderMarkdown renders the run summary and per-image results as GitHub-flavored Markdown tables
+func RenderMarkdown(metrics types.PullMetrics, results []types.PullResult, config *config.Config) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("## Pull Summary\n\n")
+	b.WriteString("| Metric | Value |\n|:--|--:|\n")
+	fmt.Fprintf(&b, "| Images | %d |\n", metrics.TotalImages)
+	fmt.Fprintf(&b, "| ✅ Successful | %d |\n", metrics.SuccessCount)
+	fmt.Fprintf(&b, "| ❌ Failed | %d |\n", metrics.FailureCount)
+	fmt.Fprintf(&b, "| 🔄 Total retries | %d |\n", metrics.TotalRetries)
+	fmt.Fprintf(&b, "| ⏱️ Total time | %v |\n", metrics.TotalDuration.Round(time.Millisecond))
+	fmt.Fprintf(&b, "| 🧮 Cumulative pull time | %v |\n", metrics.CumulativeDuration.Round(time.Millisecond))
+	fmt.Fprintf(&b, "| 📈 Average time per image | %v |\n", metrics.AverageDuration.Round(time.Millisecond))
+	fmt.Fprintf(&b, "| 📉 Pull time p50 / p90 / p95 / p99 / max | %s |\n", percentiles(metrics.Durations))
+	fmt.Fprintf(&b, "| 📦 Pulled | %s (%s) |\n", units.HumanSize(float64(metrics.TotalBytes)), byteRate(metrics.Throughput))
+	fmt.Fprintf(&b, "| 🚀 Concurrency | %d |\n", metrics.Concurrency)
+
+	if groups := registryGroups(metrics); len(groups) > 0 {
+		b.WriteString("\n## Per Registry\n\n")
+		b.WriteString("| Registry | Images | Failed | Retries | p50 | p90 | p95 | p99 | Max | Throughput |\n")
+		b.WriteString("|:--|--:|--:|--:|--:|--:|--:|--:|--:|--:|\n")
+		for _, group := range groups {
+			d := group.Metrics.Durations
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %v | %v | %v | %v | %v | %s |\n", markdownCell(group.Name),
+				group.Metrics.Images, group.Metrics.FailureCount, group.Metrics.Retries,
+				d.P50.Round(time.Millisecond), d.P90.Round(time.Millisecond), d.P95.Round(time.Millisecond),
+				d.P99.Round(time.Millisecond), d.Max.Round(time.Millisecond), byteRate(group.Metrics.Throughput))
+		}
+	}
+
+	if groups := categoryGroups(metrics); len(groups) > 0 {
+		b.WriteString("\n## Failures by Category\n\n")
+		b.WriteString("| Category | Images | Retries | p50 | p95 | Max |\n")
+		b.WriteString("|:--|--:|--:|--:|--:|--:|\n")
+		for _, group := range groups {
+			d := group.Metrics.Durations
+			fmt.Fprintf(&b, "| %s | %d | %d | %v | %v | %v |\n", group.Name, group.Metrics.Images, group.Metrics.Retries,
+				d.P50.Round(time.Millisecond), d.P95.Round(time.Millisecond), d.Max.Round(time.Millisecond))
+		}
+	}
+
+	b.WriteString("\n## Images\n\n")
+	b.WriteString("| Image | Status | Attempts | Duration | Size | Digest | Error category |\n")
+	b.WriteString("|:--|:--|--:|--:|--:|:--|:--|\n")
+	for _, result := range sortResults(results, config.ReportSort) {
+		status := "✅ success"
+		if !result.Success {
+			status = "❌ failed"
+		}
+		size, digest := "", ""
+		if result.ImageSize > 0 {
+			size = units.HumanSize(float64(result.ImageSize))
+		}
+		if result.Digest != "" {
+			digest = "`" + shortDigest(result.Digest) + "`"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %v | %s | %s | %s |\n",
+			markdownCell(security.SanitizeLogMessage(result.Image)), status, result.Attempts,
+			result.Duration.Round(time.Millisecond), size, digest, errorCategory(result))
+	}
+	return b.Bytes(), nil
+}
+
+// sortResults returns a copy of the results in the requested report order;
+// an empty order keeps the order in which the images were listed
+func sortResults(results []types.PullResult, order string) []types.PullResult {
+	sorted := append([]types.PullResult(nil), results...)
+	switch order {
+	case SortDuration:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if sorted[i].Duration != sorted[j].Duration {
+				return sorted[i].Duration > sorted[j].Duration
+			}
+			return sorted[i].Image < sorted[j].Image
+		})
+	case SortImage:
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Image < sorted[j].Image })
+	}
+	return sorted
+}
+
+// metricRows lists the run metric rows of the CSV report, followed by the
+// per-registry and per-error-category statistics prefixed with their group
+func metricRows(metrics types.PullMetrics) [][2]string {
+	rows := [][2]string{
+		{"total_images", strconv.Itoa(metrics.TotalImages)},
+		{"successful", strconv.Itoa(metrics.SuccessCount)},
+		{"failed", strconv.Itoa(metrics.FailureCount)},
+		{"total_retries", strconv.Itoa(metrics.TotalRetries)},
+		{"total_duration_seconds", seconds(metrics.TotalDuration)},
+		{"cumulative_duration_seconds", seconds(metrics.CumulativeDuration)},
+		{"average_duration_seconds", seconds(metrics.AverageDuration)},
+	}
+	rows = append(rows, durationRows("", metrics.Durations)...)
+	rows = append(rows,
+		[2]string{"total_bytes", strconv.FormatInt(metrics.TotalBytes, 10)},
+		[2]string{"throughput_bytes_per_second", strconv.FormatFloat(metrics.Throughput, 'f', 0, 64)},
+		[2]string{"concurrency", strconv.Itoa(metrics.Concurrency)},
+	)
+
+	for _, group := range registryGroups(metrics) {
+		prefix := "registry:" + group.Name + ":"
+		rows = append(rows,
+			[2]string{prefix + "images", strconv.Itoa(group.Metrics.Images)},
+			[2]string{prefix + "failed", strconv.Itoa(group.Metrics.FailureCount)},
+		)
+		rows = append(rows, durationRows(prefix, group.Metrics.Durations)...)
+		rows = append(rows, [2]string{prefix + "throughput_bytes_per_second", strconv.FormatFloat(group.Metrics.Throughput, 'f', 0, 64)})
+	}
+	for _, group := range categoryGroups(metrics) {
+		prefix := "error_category:" + group.Name + ":"
+		rows = append(rows, [2]string{prefix + "images", strconv.Itoa(group.Metrics.Images)})
+		rows = append(rows, durationRows(prefix, group.Metrics.Durations)...)
+	}
+	return rows
+}
+
+// durationRows lists the pull time percentiles of the CSV report
+func durationRows(prefix string, durations types.DurationStats) [][2]string {
+	return [][2]string{
+		{prefix + "p50_duration_seconds", seconds(durations.P50)},
+		{prefix + "p90_duration_seconds", seconds(durations.P90)},
+		{prefix + "p95_duration_seconds", seconds(durations.P95)},
+		{prefix + "p99_duration_seconds", seconds(durations.P99)},
+		{prefix + "max_duration_seconds", seconds(durations.Max)},
+	}
+}
+
+// resultStatus names the outcome of a pull
+func resultStatus(result types.PullResult) string {
+	if result.Success {
+		return "success"
+	}
+	return "failed"
+}
+
+// errorCategory returns the category of a failed pull, or nothing for a successful one
+func errorCategory(result types.PullResult) types.ErrorCategory {
+	if result.Success {
+		return ""
+	}
+	if result.ErrorCategory == "" {
+		return types.ErrorCategoryUnknown
+	}
+	return result.ErrorCategory
+}
+
+// shortDigest abbreviates a digest to its algorithm and first 12 hex characters
+func shortDigest(digest string) string {
+	if algorithm, hex, ok := strings.Cut(digest, ":"); ok && len(hex) > 12 {
+		return algorithm + ":" + hex[:12]
+	}
+	return digest
+}
+
+// csvCell prevents spreadsheets from evaluating a cell as a formula
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// markdownCell escapes characters that would break a Markdown table row
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(value)
+}
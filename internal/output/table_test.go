@@ -0,0 +1,95 @@
+package output
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/config"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+var tableResults = []types.PullResult{
+	{Image: "nginx:latest", Success: true, Duration: 1500 * time.Millisecond, Attempts: 1,
+		ImageSize: 2048, Digest: "sha256:0123456789abcdef0123456789abcdef"},
+	{Image: "ghcr.io/org/missing:1", Duration: 3 * time.Second, Attempts: 4,
+		Error: "manifest unknown", ErrorCategory: types.ErrorCategoryNotFound},
+	{Image: "alpine:3", Success: true, Duration: 200 * time.Millisecond, Attempts: 2},
+}
+
+var tableMetrics = types.PullMetrics{TotalImages: 3, SuccessCount: 2, FailureCount: 1, TotalRetries: 4,
+	TotalDuration: 3 * time.Second, AverageDuration: 1566 * time.Millisecond, Concurrency: 5}
+
+func TestRenderCSV(t *testing.T) {
+	data, err := RenderCSV(tableMetrics, tableResults, &config.Config{ReportSort: "duration"})
+	if err != nil {
+		t.Fatalf("RenderCSV() error = %v", err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		t.Fatalf("RenderCSV() is not valid rectangular CSV: %v", err)
+	}
+	want := [][]string{
+		csvColumns,
+		{"image", "ghcr.io/org/missing:1", "failed", "4", "3.000", "0", "", "not_found", "", ""},
+		{"image", "nginx:latest", "success", "1", "1.500", "2048", "sha256:0123456789abcdef0123456789abcdef", "", "", ""},
+		{"image", "alpine:3", "success", "2", "0.200", "0", "", "", "", ""},
+		{"metric", "", "", "", "", "", "", "", "total_images", "3"},
+	}
+	metrics := metricRows(tableMetrics)
+	if len(rows) != len(want)-1+len(metrics) {
+		t.Fatalf("RenderCSV() rows = %v", rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("RenderCSV() row %d = %v, want %v", i, rows[i], want[i])
+		}
+	}
+
+	// Metric rows follow the image rows, one per run metric
+	for i, metric := range metrics {
+		row := rows[len(want)-1+i]
+		if row[0] != "metric" || row[8] != metric[0] || row[9] != metric[1] {
+			t.Errorf("RenderCSV() metric row %d = %v, want %v", i, row, metric)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	results := append(tableResults, types.PullResult{Image: "odd|name", Attempts: 1})
+	data, err := RenderMarkdown(tableMetrics, results, &config.Config{ReportSort: "image"})
+	if err != nil {
+		t.Fatalf("RenderMarkdown() error = %v", err)
+	}
+	report := string(data)
+
+	for _, want := range []string{
+		"| ❌ Failed | 1 |",
+		"| nginx:latest | ✅ success | 1 | 1.5s | 2.048kB | `sha256:0123456789ab` |  |",
+		"| ghcr.io/org/missing:1 | ❌ failed | 4 | 3s |  |  | not_found |",
+		"| odd\\|name | ❌ failed | 1 | 0s |  |  | unknown |",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("RenderMarkdown() missing %q in:\n%s", want, report)
+		}
+	}
+	if strings.Index(report, "alpine:3") > strings.Index(report, "nginx:latest") {
+		t.Errorf("RenderMarkdown() did not sort by image:\n%s", report)
+	}
+}
+
+func TestCSVCell(t *testing.T) {
+	tests := map[string]string{
+		"nginx:latest":      "nginx:latest",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"":                  "",
+	}
+	for input, want := range tests {
+		if got := csvCell(input); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
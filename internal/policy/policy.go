@@ -0,0 +1,129 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/go-units"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+)
+
+// Policy holds image admission rules loaded from a policy file
+type Policy struct {
+	AllowedRegistries  []string `yaml:"allowed_registries"`  // Registry hosts images may come from (empty allows all)
+	DeniedRepositories []string `yaml:"denied_repositories"` // Repository patterns that may not be pulled
+	ForbidLatest       bool     `yaml:"forbid_latest"`       // Reject explicit or implicit "latest" tags
+	RequireDigest      []string `yaml:"require_digest"`      // Repository patterns that must be pinned by digest
+	MaxImageSize       string   `yaml:"max_image_size"`      // Maximum image size after pull, e.g. "500MB"
+
+	maxImageSizeBytes int64
+}
+
+// Load reads and validates a policy file
+func Load(filename string) (*Policy, error) {
+	data, err := security.SecureReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policy Policy
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true) // Reject unknown fields for security
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+
+	for _, pattern := range append(policy.DeniedRepositories, policy.RequireDigest...) {
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+			return nil, fmt.Errorf("invalid repository pattern %q: %w", pattern, err)
+		}
+	}
+
+	if policy.MaxImageSize != "" {
+		policy.maxImageSizeBytes, err = units.FromHumanSize(policy.MaxImageSize)
+		if err != nil || policy.maxImageSizeBytes <= 0 {
+			return nil, fmt.Errorf("invalid max_image_size %q", policy.MaxImageSize)
+		}
+	}
+
+	return &policy, nil
+}
+
+// matchRepository matches a fully-qualified repository name against a pattern.
+// Patterns use path.Match syntax; a trailing "/**" matches everything below a namespace.
+func matchRepository(pattern, repository string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return strings.HasPrefix(repository, prefix+"/")
+	}
+	matched, _ := path.Match(pattern, repository)
+	return matched
+}
+
+// Evaluate checks an image reference before pulling. pinnedDigest is the digest the image
+// will be pulled by when it is not part of the reference (e.g. from a lockfile).
+func (p *Policy) Evaluate(imageName, pinnedDigest string) []string {
+	if p == nil {
+		return nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot parse image reference: %v", err)}
+	}
+	repository := named.Name()
+	_, digested := named.(reference.Digested)
+	pinned := digested || pinnedDigest != ""
+
+	var violations []string
+
+	if len(p.AllowedRegistries) > 0 {
+		domain := reference.Domain(named)
+		allowed := false
+		for _, registry := range p.AllowedRegistries {
+			if registry == domain {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			violations = append(violations, fmt.Sprintf("registry %s is not allowed", domain))
+		}
+	}
+
+	for _, pattern := range p.DeniedRepositories {
+		if matchRepository(pattern, repository) {
+			violations = append(violations, fmt.Sprintf("repository %s is denied by %q", repository, pattern))
+			break
+		}
+	}
+
+	if p.ForbidLatest && !pinned {
+		if tagged, ok := reference.TagNameOnly(named).(reference.Tagged); ok && tagged.Tag() == "latest" {
+			violations = append(violations, "the latest tag is forbidden")
+		}
+	}
+
+	if !pinned {
+		for _, pattern := range p.RequireDigest {
+			if matchRepository(pattern, repository) {
+				violations = append(violations, fmt.Sprintf("repository %s must be pinned by digest (%q)", repository, pattern))
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// EvaluatePulled checks the inspect data of a pulled image
+func (p *Policy) EvaluatePulled(size int64) []string {
+	if p == nil || p.maxImageSizeBytes == 0 || size <= p.maxImageSizeBytes {
+		return nil
+	}
+	return []string{fmt.Sprintf("image size %s exceeds the maximum of %s",
+		units.HumanSize(float64(size)), units.HumanSize(float64(p.maxImageSizeBytes)))}
+}
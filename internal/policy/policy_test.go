@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	policy := &Policy{
+		AllowedRegistries:  []string{"docker.io", "ghcr.io"},
+		DeniedRepositories: []string{"docker.io/library/busybox", "ghcr.io/untrusted/**"},
+		ForbidLatest:       true,
+		RequireDigest:      []string{"ghcr.io/myorg/**"},
+	}
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		name         string
+		image        string
+		pinnedDigest string
+		violations   int
+	}{
+		{name: "allowed tagged image", image: "nginx:stable", violations: 0},
+		{name: "implicit latest", image: "nginx", violations: 1},
+		{name: "latest pinned by digest", image: "nginx:latest@" + digest, violations: 0},
+		{name: "registry not allowed", image: "quay.io/coreos/etcd:v3.5.0", violations: 1},
+		{name: "denied repository", image: "busybox:1.36", violations: 1},
+		{name: "denied namespace", image: "ghcr.io/untrusted/tool:1.0", violations: 1},
+		{name: "digest required", image: "ghcr.io/myorg/app:1.0", violations: 1},
+		{name: "digest required and pinned by lockfile", image: "ghcr.io/myorg/app:1.0", pinnedDigest: digest, violations: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := policy.Evaluate(tt.image, tt.pinnedDigest)
+			if len(violations) != tt.violations {
+				t.Errorf("Evaluate(%q) = %v, want %d violations", tt.image, violations, tt.violations)
+			}
+		})
+	}
+}
+
+func TestEvaluatePulled(t *testing.T) {
+	policy := &Policy{maxImageSizeBytes: 1000}
+	if violations := policy.EvaluatePulled(1000); len(violations) != 0 {
+		t.Errorf("EvaluatePulled(1000) = %v, want none", violations)
+	}
+	if violations := policy.EvaluatePulled(1001); len(violations) != 1 {
+		t.Errorf("EvaluatePulled(1001) = %v, want 1 violation", violations)
+	}
+
+	var noPolicy *Policy
+	if violations := noPolicy.EvaluatePulled(1 << 40); len(violations) != 0 {
+		t.Errorf("nil policy EvaluatePulled() = %v, want none", violations)
+	}
+}
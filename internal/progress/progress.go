@@ -47,7 +47,7 @@ func UpdateProgress(config *config.Config, tracker *ProgressTracker) {
 	if config == nil || tracker == nil {
 		return
 	}
-	if !config.ShowProgress || config.OutputFormat == "json" {
+	if !config.ShowProgress || config.OutputFormat != "text" {
 		return
 	}
 
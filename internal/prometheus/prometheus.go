@@ -0,0 +1,273 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/stats"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// namespace prefixes every exported metric name
+const namespace = "docker_parallel_pull"
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Histogram bucket upper bounds
+var (
+	durationBuckets = []float64{1, 2.5, 5, 10, 30, 60, 120, 300, 600}            // Seconds
+	sizeBuckets     = []float64{1e6, 1e7, 5e7, 1e8, 2.5e8, 5e8, 1e9, 2.5e9, 5e9} // Bytes
+)
+
+// PushOptions select the Pushgateway grouping key and credentials
+type PushOptions struct {
+	URL      string // Base URL, e.g. http://pushgateway:9091
+	Job      string
+	Instance string // Optional
+	Username string // Optional basic auth
+	Password string
+	Timeout  time.Duration
+}
+
+// Render formats the results of a run in the Prometheus text exposition format.
+// Every series describes the last run only, so per-image values are gauges named
+// last_run_*, labelled by registry and image; histograms are labelled by
+// registry only to keep the number of series bounded.
+func Render(metrics types.PullMetrics, results []types.PullResult, finished time.Time) []byte {
+	type imageSeries struct {
+		registry, image string
+		result          types.PullResult
+	}
+	series := make([]imageSeries, 0, len(results))
+	for _, result := range results {
+		series = append(series, imageSeries{
+			registry: stats.Registry(result),
+			image:    security.SanitizeLogMessage(result.Image),
+			result:   result,
+		})
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].registry != series[j].registry {
+			return series[i].registry < series[j].registry
+		}
+		return series[i].image < series[j].image
+	})
+
+	var b bytes.Buffer
+
+	header(&b, "last_run_pulls", "gauge", "Image pulls of the last run by outcome.")
+	for _, s := range series {
+		success, failure := 0, 0
+		if s.result.Success {
+			success = 1
+		} else {
+			failure = 1
+		}
+		sample(&b, "last_run_pulls", labels("registry", s.registry, "image", s.image, "status", "success"), float64(success))
+		sample(&b, "last_run_pulls", labels("registry", s.registry, "image", s.image, "status", "failure"), float64(failure))
+	}
+
+	header(&b, "last_run_pull_failures", "gauge", "Failed image pulls of the last run by error category.")
+	for _, s := range series {
+		if !s.result.Success {
+			category := s.result.ErrorCategory
+			if category == "" {
+				category = types.ErrorCategoryUnknown
+			}
+			sample(&b, "last_run_pull_failures", labels("registry", s.registry, "image", s.image, "error_category", string(category)), 1)
+		}
+	}
+
+	header(&b, "last_run_pull_retries", "gauge", "Pull attempts after the first of the last run.")
+	for _, s := range series {
+		sample(&b, "last_run_pull_retries", labels("registry", s.registry, "image", s.image), float64(max(s.result.Attempts-1, 0)))
+	}
+
+	header(&b, "last_run_pull_seconds", "gauge", "Time spent pulling each image in the last run.")
+	for _, s := range series {
+		sample(&b, "last_run_pull_seconds", labels("registry", s.registry, "image", s.image), s.result.Duration.Seconds())
+	}
+
+	header(&b, "last_run_pulled_bytes", "gauge", "Size of each image pulled in the last run.")
+	for _, s := range series {
+		if s.result.Success {
+			sample(&b, "last_run_pulled_bytes", labels("registry", s.registry, "image", s.image), float64(s.result.ImageSize))
+		}
+	}
+
+	durations := make(map[string][]float64)
+	sizes := make(map[string][]float64)
+	for _, s := range series {
+		durations[s.registry] = append(durations[s.registry], s.result.Duration.Seconds())
+		if s.result.Success && s.result.ImageSize > 0 {
+			sizes[s.registry] = append(sizes[s.registry], float64(s.result.ImageSize))
+		}
+	}
+	header(&b, "last_run_pull_duration_seconds", "histogram", "Pull time per image of the last run.")
+	histograms(&b, "last_run_pull_duration_seconds", durations, durationBuckets)
+	header(&b, "last_run_image_size_bytes", "histogram", "Size of the images pulled in the last run.")
+	histograms(&b, "last_run_image_size_bytes", sizes, sizeBuckets)
+
+	runSuccess := 0
+	if metrics.FailureCount == 0 {
+		runSuccess = 1
+	}
+	header(&b, "last_run_duration_seconds", "gauge", "Wall clock time of the last run.")
+	sample(&b, "last_run_duration_seconds", "", metrics.TotalDuration.Seconds())
+	header(&b, "last_run_success", "gauge", "Whether every image of the last run was pulled successfully.")
+	sample(&b, "last_run_success", "", float64(runSuccess))
+	header(&b, "last_run_timestamp_seconds", "gauge", "Unix time the last run finished.")
+	sample(&b, "last_run_timestamp_seconds", "", float64(finished.Unix()))
+
+	return b.Bytes()
+}
+
+// WriteTextfile atomically replaces a node exporter textfile collector file,
+// so the collector never reads a partially written file
+func WriteTextfile(filename string, data []byte) error {
+	if err := security.WriteFileAtomic(filename, data); err != nil {
+		return fmt.Errorf("failed to write metrics textfile: %w", err)
+	}
+	return nil
+}
+
+// Push sends the metrics to a Pushgateway, replacing the metrics of the same
+// names in the job's group
+func Push(ctx context.Context, options PushOptions, data []byte) error {
+	endpoint, err := PushURL(options.URL, options.Job, options.Instance)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if options.Username != "" {
+		req.SetBasicAuth(options.Username, options.Password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// PushURL returns the Pushgateway endpoint of a job and optional instance grouping key
+func PushURL(base, job, instance string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid pushgateway URL: %w", err)
+	}
+	if job == "" {
+		return "", fmt.Errorf("pushgateway job is required")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/") + "/metrics/" + groupingKey("job", job)
+	if instance != "" {
+		path += "/" + groupingKey("instance", instance)
+	}
+	u.Path = path
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// groupingKey encodes one label of a Pushgateway grouping key. Values containing
+// a slash use the base64 form, as they cannot appear in a path segment.
+func groupingKey(name, value string) string {
+	if strings.Contains(value, "/") {
+		return name + "@base64/" + base64.URLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + value
+}
+
+// header writes the HELP and TYPE lines of a metric family
+func header(b *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s_%s %s\n", namespace, name, help)
+	fmt.Fprintf(b, "# TYPE %s_%s %s\n", namespace, name, kind)
+}
+
+// sample writes one sample line
+func sample(b *bytes.Buffer, name, labels string, value float64) {
+	fmt.Fprintf(b, "%s_%s%s %s\n", namespace, name, labels, formatValue(value))
+}
+
+// histograms writes one cumulative histogram per registry
+func histograms(b *bytes.Buffer, name string, observations map[string][]float64, buckets []float64) {
+	registries := make([]string, 0, len(observations))
+	for registry := range observations {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+
+	for _, registry := range registries {
+		values := observations[registry]
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		for _, bound := range buckets {
+			count := 0
+			for _, v := range values {
+				if v <= bound {
+					count++
+				}
+			}
+			sample(b, name+"_bucket", labels("registry", registry, "le", formatValue(bound)), float64(count))
+		}
+		sample(b, name+"_bucket", labels("registry", registry, "le", "+Inf"), float64(len(values)))
+		sample(b, name+"_sum", labels("registry", registry), sum)
+		sample(b, name+"_count", labels("registry", registry), float64(len(values)))
+	}
+}
+
+// labels formats name and value pairs as a label set
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, pairs[i], escapeLabel(pairs[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// escapeLabel escapes a label value as required by the exposition format
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatValue formats a sample value, writing whole numbers such as byte
+// counts and timestamps without an exponent
+func formatValue(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
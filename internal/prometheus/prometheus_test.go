@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestRender(t *testing.T) {
+	results := []types.PullResult{
+		{Image: "nginx:latest", CanonicalImage: "docker.io/library/nginx:latest", Success: true,
+			Attempts: 2, Duration: 3 * time.Second, ImageSize: 60_000_000},
+		{Image: `ghcr.io/org/app:"1"`, CanonicalImage: "ghcr.io/org/app:1", Attempts: 4,
+			Duration: 20 * time.Second, ErrorCategory: types.ErrorCategoryTimeout},
+	}
+	metrics := types.PullMetrics{TotalImages: 2, SuccessCount: 1, FailureCount: 1, TotalDuration: 21 * time.Second}
+
+	data := string(Render(metrics, results, time.Unix(1714564800, 0)))
+
+	for _, want := range []string{
+		"# TYPE docker_parallel_pull_last_run_pulls gauge\n",
+		`docker_parallel_pull_last_run_pulls{registry="registry-1.docker.io",image="nginx:latest",status="success"} 1`,
+		`docker_parallel_pull_last_run_pulls{registry="ghcr.io",image="ghcr.io/org/app:\"1\"",status="failure"} 1`,
+		`docker_parallel_pull_last_run_pull_failures{registry="ghcr.io",image="ghcr.io/org/app:\"1\"",error_category="timeout"} 1`,
+		`docker_parallel_pull_last_run_pull_retries{registry="registry-1.docker.io",image="nginx:latest"} 1`,
+		`docker_parallel_pull_last_run_pulled_bytes{registry="registry-1.docker.io",image="nginx:latest"} 60000000`,
+		"# TYPE docker_parallel_pull_last_run_pull_duration_seconds histogram\n",
+		`docker_parallel_pull_last_run_pull_duration_seconds_bucket{registry="ghcr.io",le="10"} 0`,
+		`docker_parallel_pull_last_run_pull_duration_seconds_bucket{registry="ghcr.io",le="30"} 1`,
+		`docker_parallel_pull_last_run_pull_duration_seconds_bucket{registry="registry-1.docker.io",le="+Inf"} 1`,
+		`docker_parallel_pull_last_run_pull_duration_seconds_sum{registry="registry-1.docker.io"} 3`,
+		`docker_parallel_pull_last_run_image_size_bytes_bucket{registry="registry-1.docker.io",le="50000000"} 0`,
+		`docker_parallel_pull_last_run_image_size_bytes_bucket{registry="registry-1.docker.io",le="100000000"} 1`,
+		"docker_parallel_pull_last_run_success 0\n",
+		"docker_parallel_pull_last_run_timestamp_seconds 1714564800\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Render() missing %q in:\n%s", want, data)
+		}
+	}
+	if strings.Contains(data, `last_run_image_size_bytes_count{registry="ghcr.io"}`) {
+		t.Error("Render() observed the size of a failed pull")
+	}
+}
+
+func TestPushURL(t *testing.T) {
+	tests := []struct {
+		base, job, instance string
+		want                string
+	}{
+		{base: "http://gateway:9091", job: "pulls", want: "http://gateway:9091/metrics/job/pulls"},
+		{base: "http://gateway:9091/", job: "pulls", instance: "node-1", want: "http://gateway:9091/metrics/job/pulls/instance/node-1"},
+		{base: "https://proxy/pushgateway", job: "pulls", want: "https://proxy/pushgateway/metrics/job/pulls"},
+		{base: "http://gateway:9091", job: "a/b", want: "http://gateway:9091/metrics/job@base64/YS9i"},
+		{base: "http://gateway:9091", job: "pulls", instance: "rack 1", want: "http://gateway:9091/metrics/job/pulls/instance/rack%201"},
+	}
+	for _, tt := range tests {
+		got, err := PushURL(tt.base, tt.job, tt.instance)
+		if err != nil || got != tt.want {
+			t.Errorf("PushURL(%q, %q, %q) = %q, %v, want %q", tt.base, tt.job, tt.instance, got, err, tt.want)
+		}
+	}
+	if _, err := PushURL("http://gateway:9091", "", ""); err == nil {
+		t.Error("PushURL() accepted an empty job")
+	}
+}
+
+func TestPush(t *testing.T) {
+	var gotPath, gotType, gotUser, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Push() used method %s", r.Method)
+		}
+		gotPath = r.URL.Path
+		gotType = r.Header.Get("Content-Type")
+		gotUser, _, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	data := []byte("docker_parallel_pull_last_run_success 1\n")
+	options := PushOptions{URL: server.URL, Job: "pulls", Instance: "node-1", Username: "ci", Password: "secret", Timeout: 5 * time.Second}
+	if err := Push(context.Background(), options, data); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if gotPath != "/metrics/job/pulls/instance/node-1" || gotType != ContentType || gotUser != "ci" || gotBody != string(data) {
+		t.Errorf("Push() sent path %q, type %q, user %q, body %q", gotPath, gotType, gotUser, gotBody)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "text format parsing error", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	options.URL = failing.URL
+	if err := Push(context.Background(), options, data); err == nil || !strings.Contains(err.Error(), "parsing error") {
+		t.Errorf("Push() error = %v, want the gateway's response", err)
+	}
+}
@@ -0,0 +1,198 @@
+package redact
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// Rule names
+const (
+	RuleCredentials = "credentials"  // key=value secrets and registry auth JSON fields
+	RuleAuthHeader  = "auth_header"  // Bearer and Basic authorization values
+	RuleURLUserinfo = "url_userinfo" // user:password@ in URLs
+	RuleIPv4        = "ipv4"
+	RuleIPv6        = "ipv6"
+	RuleHomeDir     = "home_dir" // User names in home directory paths
+	RulePaths       = "paths"    // Absolute filesystem paths
+)
+
+// Rule is a named redaction applied to every message
+type Rule struct {
+	Name     string
+	patterns []pattern
+}
+
+// pattern replaces every match of re using replace
+type pattern struct {
+	re      *regexp.Regexp
+	replace func(match string) string
+}
+
+// expand returns a replacement function expanding a regexp template such as "$1=[REDACTED]"
+func expand(re *regexp.Regexp, template string) func(string) string {
+	return func(match string) string {
+		return re.ReplaceAllString(match, template)
+	}
+}
+
+// newPattern compiles expr with a template replacement
+func newPattern(expr, template string) pattern {
+	re := regexp.MustCompile(expr)
+	return pattern{re: re, replace: expand(re, template)}
+}
+
+var (
+	ipv6Candidate  = regexp.MustCompile(`(?i)[0-9a-f]*:[0-9a-f]*:[0-9a-f:.]*(%[0-9a-z]+)?`)
+	imageCandidate = regexp.MustCompile(`(^|[^a-zA-Z0-9._/~-])([a-zA-Z0-9][a-zA-Z0-9._:@/-]*/[a-zA-Z0-9._:@/-]*[a-zA-Z0-9])`)
+)
+
+// builtinRules are all available rules in the order they are applied.
+// Rules removing secrets run first so later rules cannot split them.
+var builtinRules = []Rule{
+	{Name: RuleCredentials, patterns: []pattern{
+		newPattern(`(?i)\b(password|passwd|token|secret|api[_-]?key|key)=[^\s&"',;]+`, "$1=[REDACTED]"),
+		newPattern(`(?i)("(?:password|auth|identitytoken|registrytoken|access_token|token)"\s*:\s*)"[^"]*"`, `$1"[REDACTED]"`),
+	}},
+	{Name: RuleAuthHeader, patterns: []pattern{
+		newPattern(`(?i)\b(Bearer|Basic)\s+[A-Za-z0-9._~+/=-]+`, "$1 [REDACTED]"),
+	}},
+	{Name: RuleURLUserinfo, patterns: []pattern{
+		newPattern(`\b([a-zA-Z][a-zA-Z0-9+.-]*://)[^/\s@]+@`, "${1}[REDACTED]@"),
+	}},
+	{Name: RuleIPv4, patterns: []pattern{
+		newPattern(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`, "[IP_REDACTED]"),
+	}},
+	{Name: RuleIPv6, patterns: []pattern{
+		{re: ipv6Candidate, replace: redactIPv6},
+	}},
+	{Name: RuleHomeDir, patterns: []pattern{
+		newPattern(`(/home/|/Users/)[^/\s"']+`, "${1}[USER]"),
+	}},
+	{Name: RulePaths, patterns: []pattern{
+		newPattern(`(^|[\s"'=(,\[])/[a-zA-Z0-9/_.~\[\]-]+`, "${1}[PATH_REDACTED]"),
+	}},
+}
+
+// redactIPv6 replaces a candidate only when it parses as an IPv6 address
+func redactIPv6(match string) string {
+	host, _, _ := strings.Cut(match, "%")
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return "[IP_REDACTED]"
+	}
+	return match
+}
+
+// RuleNames returns the names of all built-in rules
+func RuleNames() []string {
+	names := make([]string, len(builtinRules))
+	for i, rule := range builtinRules {
+		names[i] = rule.Name
+	}
+	return names
+}
+
+// Options selects the rules a Redactor applies
+type Options struct {
+	DisabledRules         []string // Rule names to skip
+	RedactImageReferences bool     // Apply rules inside image references such as registry.local:5000/app
+}
+
+// Redactor removes sensitive information from messages
+type Redactor struct {
+	rules         []Rule
+	keepImageRefs bool
+}
+
+// New creates a Redactor with every built-in rule except the disabled ones
+func New(opts Options) (*Redactor, error) {
+	disabled := make(map[string]bool, len(opts.DisabledRules))
+	known := RuleNames()
+	for _, name := range opts.DisabledRules {
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("unknown redaction rule %q, valid rules: %s", name, strings.Join(known, ", "))
+		}
+		disabled[name] = true
+	}
+
+	r := &Redactor{keepImageRefs: !opts.RedactImageReferences}
+	for _, rule := range builtinRules {
+		if !disabled[rule.Name] {
+			r.rules = append(r.rules, rule)
+		}
+	}
+	return r, nil
+}
+
+// Default returns a Redactor applying every built-in rule and keeping image references readable
+func Default() *Redactor {
+	r, _ := New(Options{})
+	return r
+}
+
+// Rules returns the names of the rules the Redactor applies
+func (r *Redactor) Rules() []string {
+	names := make([]string, len(r.rules))
+	for i, rule := range r.rules {
+		names[i] = rule.Name
+	}
+	return names
+}
+
+// Redact applies the configured rules to message
+func (r *Redactor) Redact(message string) string {
+	var kept []string
+	if r.keepImageRefs {
+		message, kept = r.protectImageReferences(message)
+	}
+
+	for _, rule := range r.rules {
+		for _, p := range rule.patterns {
+			message = p.re.ReplaceAllStringFunc(message, p.replace)
+		}
+	}
+
+	for i := len(kept) - 1; i >= 0; i-- {
+		message = strings.ReplaceAll(message, r.placeholder(i), kept[i])
+	}
+	return message
+}
+
+// protectImageReferences swaps image references for placeholders no rule matches
+func (r *Redactor) protectImageReferences(message string) (string, []string) {
+	var kept []string
+	message = imageCandidate.ReplaceAllStringFunc(message, func(match string) string {
+		groups := imageCandidate.FindStringSubmatch(match)
+		prefix, candidate := groups[1], groups[2]
+		if !isImageReference(candidate) {
+			return match
+		}
+		kept = append(kept, candidate)
+		return prefix + r.placeholder(len(kept)-1)
+	})
+	return message, kept
+}
+
+// placeholder returns the marker substituted for the i-th kept reference.
+// NUL bytes never occur in matches of the built-in rules.
+func (r *Redactor) placeholder(i int) string {
+	return "\x00img" + strconv.Itoa(i) + "\x00"
+}
+
+// isImageReference reports whether s is a repository reference with a namespace or registry.
+// References with embedded credentials are never treated as image references.
+func isImageReference(s string) bool {
+	if strings.Contains(s, "://") {
+		return false
+	}
+	if at := strings.Index(s, "@"); at >= 0 && !strings.HasPrefix(s[at+1:], "sha256:") {
+		return false
+	}
+	_, err := reference.ParseNormalizedNamed(s)
+	return err == nil
+}
@@ -0,0 +1,198 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+)
+
+// dockerHubAuthKey is the key the Docker CLI stores Docker Hub credentials under
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// identityTokenUsername is the username credential helpers return with an identity token
+const identityTokenUsername = "<token>"
+
+// Credential is a registry login: a username and password, or an OAuth2 identity token
+type Credential struct {
+	Username      string
+	Password      string
+	IdentityToken string
+}
+
+// IsZero reports whether the credential is empty, meaning anonymous access
+func (c Credential) IsZero() bool {
+	return c.Username == "" && c.Password == "" && c.IdentityToken == ""
+}
+
+// CredentialStore looks up the credential of a registry API host
+type CredentialStore interface {
+	Credential(ctx context.Context, host string) (Credential, error)
+}
+
+// DockerConfig holds the credential settings of a Docker CLI config.json
+type DockerConfig struct {
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+}
+
+// dockerAuth is a credential stored in the auths section of a Docker CLI config
+type dockerAuth struct {
+	Auth          string `json:"auth"` // base64 of "username:password"
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// helperNamePattern restricts credential helper names to the characters of executable names
+var helperNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// DockerConfigDir returns the Docker CLI config directory: dir if set, else
+// DOCKER_CONFIG, else ~/.docker
+func DockerConfigDir(dir string) string {
+	if dir != "" {
+		return dir
+	}
+	if env := os.Getenv("DOCKER_CONFIG"); env != "" {
+		return env
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// LoadDockerConfig reads config.json from a Docker CLI config directory. A missing file
+// is an empty config. Like the Docker CLI, the file is read from the user's config directory
+// rather than the allowed directories of the path policy.
+func LoadDockerConfig(dir string) (*DockerConfig, error) {
+	config := &DockerConfig{}
+	if dir == "" {
+		return config, nil
+	}
+
+	file, err := os.Open(filepath.Join(dir, "config.json"))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read docker config: %s", security.SanitizeErrorMessage(err))
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, security.MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read docker config: %s", security.SanitizeErrorMessage(err))
+	}
+	if len(data) > security.MaxFileSize {
+		return nil, fmt.Errorf("docker config exceeds size limit")
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %w", err)
+	}
+	return config, nil
+}
+
+// Credential returns the credential for a registry API host from a credential helper
+// or the auths section, or an empty credential for anonymous access
+func (c *DockerConfig) Credential(ctx context.Context, host string) (Credential, error) {
+	serverURL := host
+	if host == dockerHubHost {
+		serverURL = dockerHubAuthKey
+	}
+
+	if helper := c.CredHelpers[authKeyHost(serverURL)]; helper != "" {
+		return helperCredential(ctx, helper, serverURL)
+	}
+
+	for key, auth := range c.Auths {
+		if authKeyHost(key) != authKeyHost(serverURL) {
+			continue
+		}
+		credential, err := auth.credential()
+		if err != nil || !credential.IsZero() {
+			return credential, err
+		}
+	}
+
+	if c.CredsStore != "" {
+		return helperCredential(ctx, c.CredsStore, serverURL)
+	}
+	return Credential{}, nil
+}
+
+// authKeyHost reduces a Docker config key such as "https://harbor.local/v2/" to its host.
+// The Docker Hub keys index.docker.io, docker.io and registry-1.docker.io are the same registry.
+func authKeyHost(key string) string {
+	host := key
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", dockerHubDomain, dockerHubHost:
+		return dockerHubHost
+	}
+	return host
+}
+
+// credential decodes a stored credential
+func (a dockerAuth) credential() (Credential, error) {
+	credential := Credential{Username: a.Username, Password: a.Password, IdentityToken: a.IdentityToken}
+	if a.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return Credential{}, fmt.Errorf("invalid auth in docker config")
+		}
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return Credential{}, fmt.Errorf("invalid auth in docker config")
+		}
+		credential.Username, credential.Password = username, password
+	}
+	return credential, nil
+}
+
+// helperCredential runs docker-credential-<helper> get for serverURL.
+// Credentials the helper does not have are an empty credential.
+func helperCredential(ctx context.Context, helper, serverURL string) (Credential, error) {
+	if !helperNamePattern.MatchString(helper) {
+		return Credential{}, fmt.Errorf("invalid credential helper name: %s", security.SanitizeLogMessage(helper))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// Helpers report unknown servers on standard output and exit with an error
+		if strings.Contains(stdout.String()+stderr.String(), "credentials not found") {
+			return Credential{}, nil
+		}
+		return Credential{}, fmt.Errorf("credential helper %s failed: %s", helper, security.SanitizeErrorMessage(err))
+	}
+
+	var body struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &body); err != nil {
+		return Credential{}, fmt.Errorf("credential helper %s returned invalid output", helper)
+	}
+	if body.Username == identityTokenUsername {
+		return Credential{IdentityToken: body.Secret}, nil
+	}
+	return Credential{Username: body.Username, Password: body.Secret}, nil
+}
@@ -0,0 +1,107 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLoadDockerConfig(t *testing.T) {
+	dir := t.TempDir()
+	config, err := LoadDockerConfig(dir)
+	if err != nil || len(config.Auths) != 0 {
+		t.Errorf("LoadDockerConfig() without config.json = %+v, %v", config, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDockerConfig(dir); err == nil {
+		t.Error("LoadDockerConfig() of invalid JSON succeeded")
+	}
+}
+
+func TestDockerConfigCredential(t *testing.T) {
+	config := &DockerConfig{
+		Auths: map[string]dockerAuth{
+			"https://index.docker.io/v1/": {Auth: "aHViOmh1Yi1wYXNz"}, // hub:hub-pass
+			"https://harbor.local/v2/":    {Username: "robot", Password: "secret"},
+			"ghcr.io":                     {IdentityToken: "refresh"},
+			"broken.example":              {Auth: "not base64!"},
+		},
+	}
+
+	tests := []struct {
+		host      string
+		expected  Credential
+		wantError bool
+	}{
+		{host: "registry-1.docker.io", expected: Credential{Username: "hub", Password: "hub-pass"}},
+		{host: "harbor.local", expected: Credential{Username: "robot", Password: "secret"}},
+		{host: "ghcr.io", expected: Credential{IdentityToken: "refresh"}},
+		{host: "quay.io", expected: Credential{}},
+		{host: "broken.example", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := config.Credential(context.Background(), tt.host)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Credential() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.expected {
+				t.Errorf("Credential() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDockerConfigCredentialHelpers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helper stubs are shell scripts")
+	}
+
+	dir := t.TempDir()
+	helper := `#!/bin/sh
+read server
+case "$server" in
+  harbor.local) echo '{"ServerURL":"harbor.local","Username":"robot","Secret":"secret"}' ;;
+  https://index.docker.io/v1/) echo '{"ServerURL":"https://index.docker.io/v1/","Username":"<token>","Secret":"refresh"}' ;;
+  *) echo "credentials not found in native keychain"; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker-credential-stub"), []byte(helper), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	config := &DockerConfig{
+		Auths:       map[string]dockerAuth{"quay.io": {Username: "stored", Password: "pass"}},
+		CredsStore:  "stub",
+		CredHelpers: map[string]string{"harbor.local": "stub", "bad.example": "../stub"},
+	}
+
+	tests := []struct {
+		host      string
+		expected  Credential
+		wantError bool
+	}{
+		{host: "harbor.local", expected: Credential{Username: "robot", Password: "secret"}},
+		{host: "registry-1.docker.io", expected: Credential{IdentityToken: "refresh"}},
+		{host: "quay.io", expected: Credential{Username: "stored", Password: "pass"}},
+		{host: "ghcr.io", expected: Credential{}},
+		{host: "bad.example", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := config.Credential(context.Background(), tt.host)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Credential() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.expected {
+				t.Errorf("Credential() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
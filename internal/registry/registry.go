@@ -0,0 +1,564 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+)
+
+// Registry API limits
+const (
+	dockerHubDomain = "docker.io"
+	dockerHubHost   = "registry-1.docker.io"
+	maxTagPages     = 100 // Maximum number of tag list pages to follow
+	tagPageSize     = 1000
+	clientID        = "docker-parallel-pull" // OAuth2 client ID of identity token exchanges
+)
+
+// Client is a minimal Docker Registry HTTP API v2 client supporting basic and bearer token auth
+type Client struct {
+	httpClient     *http.Client // Verifies TLS certificates
+	insecureClient *http.Client // Skips TLS verification for insecure registries
+	credentials    CredentialStore
+	insecure       insecureRegistries
+
+	mu          sync.Mutex
+	tokens      map[tokenKey]string   // Authorization header values
+	logins      map[string]Credential // Credentials looked up per host
+	plainHTTP   map[string]bool       // Insecure registries that only serve HTTP
+	insecureFor map[string]bool       // Cached insecure registry matches per host
+}
+
+// tokenKey identifies a cached bearer token. Tokens are only valid for the
+// registry that issued them, so the same scope on another host is another token.
+type tokenKey struct {
+	host, scope string
+}
+
+// Options configures a registry client
+type Options struct {
+	Timeout     time.Duration
+	Credentials CredentialStore // Nil for anonymous access only
+	Insecure    []string        // Registries (host, host:port or CIDR) reached over unverified TLS or HTTP
+}
+
+// NewClient creates a registry client with the given options
+func NewClient(options Options) *Client {
+	insecureTransport := http.DefaultTransport.(*http.Transport).Clone()
+	insecureTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // Only used for registries configured as insecure
+
+	return &Client{
+		httpClient:     &http.Client{Timeout: options.Timeout},
+		insecureClient: &http.Client{Timeout: options.Timeout, Transport: insecureTransport},
+		credentials:    options.Credentials,
+		insecure:       parseInsecureRegistries(options.Insecure),
+		tokens:         make(map[tokenKey]string),
+		logins:         make(map[string]Credential),
+		plainHTTP:      make(map[string]bool),
+		insecureFor:    make(map[string]bool),
+	}
+}
+
+// ValidateInsecureRegistry checks that an insecure registry entry is a host, host:port or CIDR
+func ValidateInsecureRegistry(entry string) error {
+	if strings.Contains(entry, "/") {
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return fmt.Errorf("invalid insecure registry CIDR %s", security.SanitizeLogMessage(entry))
+		}
+		return nil
+	}
+	if entry == "" || strings.ContainsAny(entry, " \t@?#") {
+		return fmt.Errorf("invalid insecure registry %s", security.SanitizeLogMessage(entry))
+	}
+	return nil
+}
+
+// Host returns the API host serving the repository of a reference
+func Host(named reference.Named) string {
+	domain := reference.Domain(named)
+	if domain == dockerHubDomain {
+		return dockerHubHost
+	}
+	return domain
+}
+
+// ImageHost returns the API host of an image reference, or an empty string if it cannot be parsed
+func ImageHost(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	return Host(named)
+}
+
+// ListTags returns all tags of the repository of named, following pagination
+func (c *Client) ListTags(ctx context.Context, named reference.Named) ([]string, error) {
+	path := reference.Path(named)
+	next := fmt.Sprintf("%s/v2/%s/tags/list?n=%d", c.baseURL(Host(named)), path, tagPageSize)
+
+	var tags []string
+	for page := 0; next != ""; page++ {
+		if page >= maxTagPages {
+			return nil, fmt.Errorf("tag list of %s exceeds %d pages", security.SanitizeLogMessage(path), maxTagPages)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.do(req, "repository:"+path+":pull")
+		if err != nil {
+			return nil, err
+		}
+
+		var body struct {
+			Tags []string `json:"tags"`
+		}
+		err = decodeJSON(resp, &body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+		tags = append(tags, body.Tags...)
+
+		next, err = nextPage(req.URL, resp.Header.Get("Link"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// manifestMediaTypes are the manifest formats accepted when resolving digests
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ManifestDigest resolves the digest a tag currently points to using a HEAD request,
+// which does not count against Docker Hub pull rate limits
+func (c *Client) ManifestDigest(ctx context.Context, named reference.Named) (string, error) {
+	tag := "latest"
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		tag = digested.Digest().String()
+	}
+
+	path := reference.Path(named)
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL(Host(named)), path, tag)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	resp, err := c.do(req, "repository:"+path+":pull")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return "", err
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a manifest digest")
+	}
+	return digest, nil
+}
+
+// GetManifest fetches the manifest a tag or digest points to
+func (c *Client) GetManifest(ctx context.Context, named reference.Named, tagOrDigest string) ([]byte, error) {
+	path := reference.Path(named)
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL(Host(named)), path, tagOrDigest)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	resp, err := c.do(req, "repository:"+path+":pull")
+	if err != nil {
+		return nil, err
+	}
+	return readBody(resp)
+}
+
+// GetBlob fetches a blob and verifies its content against digest
+func (c *Client) GetBlob(ctx context.Context, named reference.Named, digest string) ([]byte, error) {
+	algorithm, expected, ok := strings.Cut(digest, ":")
+	if !ok || algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported blob digest %s", security.SanitizeLogMessage(digest))
+	}
+
+	path := reference.Path(named)
+	blobURL := fmt.Sprintf("%s/v2/%s/blobs/%s", c.baseURL(Host(named)), path, digest)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, blobURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, "repository:"+path+":pull")
+	if err != nil {
+		return nil, err
+	}
+	data, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != expected {
+		return nil, fmt.Errorf("blob content does not match digest %s", security.SanitizeLogMessage(digest))
+	}
+	return data, nil
+}
+
+// do sends a request, answering a basic or bearer challenge with the credential of the host
+// or an anonymous token for scope if needed
+func (c *Client) do(req *http.Request, scope string) (*http.Response, error) {
+	key := tokenKey{host: req.URL.Host, scope: scope}
+	if authorization := c.cachedToken(key); authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	credential, err := c.credential(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	var authorization string
+	scheme, params := parseChallenge(challenge)
+	switch {
+	case strings.EqualFold(scheme, "basic"):
+		if credential.Username == "" {
+			return nil, fmt.Errorf("registry %s requires credentials", security.SanitizeLogMessage(req.URL.Host))
+		}
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(credential.Username+":"+credential.Password))
+	case strings.EqualFold(scheme, "bearer"):
+		token, err := c.fetchToken(req.Context(), params, scope, req.URL.Scheme, credential)
+		if err != nil {
+			return nil, err
+		}
+		authorization = "Bearer " + token
+	default:
+		return nil, fmt.Errorf("registry requires unsupported authentication: %s", security.SanitizeLogMessage(scheme))
+	}
+
+	c.mu.Lock()
+	c.tokens[key] = authorization
+	c.mu.Unlock()
+
+	retry := req.Clone(req.Context())
+	retry.Header.Set("Authorization", authorization)
+	return c.send(retry)
+}
+
+// send sends a request to a registry. Requests to insecure registries skip TLS verification
+// and fall back to HTTP when HTTPS cannot be reached, like the Docker daemon does.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if !c.isInsecure(req.Context(), req.URL.Host) {
+		return c.httpClient.Do(req)
+	}
+
+	resp, err := c.insecureClient.Do(req)
+	if err == nil || req.URL.Scheme != "https" || req.Context().Err() != nil {
+		return resp, err
+	}
+
+	req.URL.Scheme = "http"
+	resp, httpErr := c.insecureClient.Do(req)
+	if httpErr != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.plainHTTP[req.URL.Host] = true
+	c.mu.Unlock()
+	return resp, nil
+}
+
+// baseURL returns the scheme and host registry API requests to host are sent to
+func (c *Client) baseURL(host string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.plainHTTP[host] {
+		return "http://" + host
+	}
+	return "https://" + host
+}
+
+// cachedToken returns a previously obtained authorization for a host and scope
+func (c *Client) cachedToken(key tokenKey) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens[key]
+}
+
+// credential returns the credential of a host, looking it up once per client
+func (c *Client) credential(ctx context.Context, host string) (Credential, error) {
+	if c.credentials == nil {
+		return Credential{}, nil
+	}
+
+	c.mu.Lock()
+	credential, ok := c.logins[host]
+	c.mu.Unlock()
+	if ok {
+		return credential, nil
+	}
+
+	credential, err := c.credentials.Credential(ctx, host)
+	if err != nil {
+		return Credential{}, fmt.Errorf("cannot look up credentials of %s: %w", security.SanitizeLogMessage(host), err)
+	}
+	c.mu.Lock()
+	c.logins[host] = credential
+	c.mu.Unlock()
+	return credential, nil
+}
+
+// fetchToken obtains a bearer token for scope from the realm advertised in a challenge.
+// Identity tokens are exchanged with an OAuth2 refresh token grant, passwords are sent
+// as basic auth and without a credential the token is anonymous.
+func (c *Client) fetchToken(ctx context.Context, params map[string]string, scope, registryScheme string, credential Credential) (string, error) {
+	if params["realm"] == "" {
+		return "", fmt.Errorf("registry requires unsupported authentication: bearer without realm")
+	}
+
+	realm, err := url.Parse(params["realm"])
+	// A registry served over HTTP may only advertise an HTTP realm
+	if err != nil || (realm.Scheme != "https" && (realm.Scheme != "http" || registryScheme != "http")) {
+		return "", fmt.Errorf("invalid token realm")
+	}
+
+	var req *http.Request
+	if credential.IdentityToken != "" {
+		form := url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", credential.IdentityToken)
+		form.Set("client_id", clientID)
+		form.Set("service", params["service"])
+		form.Set("scope", scope)
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, realm.String(), strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		query := realm.Query()
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		query.Set("scope", scope)
+		realm.RawQuery = query.Encode()
+
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if credential.Username != "" {
+			req.SetBasicAuth(credential.Username, credential.Password)
+		}
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return "", err
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := decodeJSON(resp, &body); err != nil {
+		return "", fmt.Errorf("failed to obtain registry token: %w", err)
+	}
+
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("registry returned an empty token")
+	}
+	return token, nil
+}
+
+// insecureRegistries are the registries reached over unverified TLS or HTTP
+type insecureRegistries struct {
+	hosts map[string]bool
+	cidrs []*net.IPNet
+}
+
+// parseInsecureRegistries splits validated entries into hosts and CIDRs
+func parseInsecureRegistries(entries []string) insecureRegistries {
+	registries := insecureRegistries{hosts: make(map[string]bool)}
+	for _, entry := range entries {
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			registries.cidrs = append(registries.cidrs, cidr)
+			continue
+		}
+		registries.hosts[authKeyHost(entry)] = true
+	}
+	return registries
+}
+
+// isInsecure reports whether host is an insecure registry: listed by name, with or without
+// its port, or resolving into an insecure CIDR
+func (c *Client) isInsecure(ctx context.Context, host string) bool {
+	if len(c.insecure.hosts) == 0 && len(c.insecure.cidrs) == 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	insecure, ok := c.insecureFor[host]
+	c.mu.Unlock()
+	if ok {
+		return insecure
+	}
+
+	hostname := host
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		hostname = name
+	}
+	insecure = c.insecure.hosts[host] || c.insecure.hosts[hostname]
+	if !insecure && len(c.insecure.cidrs) > 0 {
+		var ips []net.IP
+		if ip := net.ParseIP(hostname); ip != nil {
+			ips = []net.IP{ip}
+		} else if addrs, err := net.DefaultResolver.LookupIPAddr(ctx, hostname); err == nil {
+			for _, addr := range addrs {
+				ips = append(ips, addr.IP)
+			}
+		}
+		for _, ip := range ips {
+			for _, cidr := range c.insecure.cidrs {
+				insecure = insecure || cidr.Contains(ip)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.insecureFor[host] = insecure
+	c.mu.Unlock()
+	return insecure
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and parameters
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return scheme, params
+}
+
+// nextPage resolves the next page URL from a RFC 5988 Link header
+func nextPage(current *url.URL, link string) (string, error) {
+	if link == "" {
+		return "", nil
+	}
+	target, _, _ := strings.Cut(link, ";")
+	target = strings.Trim(strings.TrimSpace(target), "<>")
+
+	next, err := current.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid pagination link: %w", err)
+	}
+	if next.Host != current.Host {
+		return "", fmt.Errorf("pagination link points to a different host")
+	}
+	return next.String(), nil
+}
+
+// IsNotFound reports whether err is a 404 response from the registry
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// StatusError is returned for unexpected HTTP status codes
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %s", e.Status)
+}
+
+// checkStatus returns a StatusError for non-2xx responses
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// readBody reads a size-limited response body, failing on non-2xx status codes
+func readBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, security.MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > security.MaxFileSize {
+		return nil, fmt.Errorf("response exceeds size limit")
+	}
+	return data, nil
+}
+
+// decodeJSON decodes a size-limited JSON response body, failing on non-2xx status codes
+func decodeJSON(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, security.MaxFileSize)).Decode(v)
+}
@@ -0,0 +1,346 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/distribution/reference"
+)
+
+// fakeRegistry is a registry that requires an anonymous bearer token issued by itself
+type fakeRegistry struct {
+	*httptest.Server
+	token        string
+	tokenFetches atomic.Int32
+	handler      http.HandlerFunc // Serves authorized /v2/ requests
+	seenTokens   []string
+}
+
+func newFakeRegistry(t *testing.T, token string, handler http.HandlerFunc) *fakeRegistry {
+	t.Helper()
+	r := &fakeRegistry{token: token, handler: handler}
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			r.tokenFetches.Add(1)
+			if req.URL.Query().Get("service") != "fake-registry" || !strings.HasPrefix(req.URL.Query().Get("scope"), "repository:") {
+				t.Errorf("token request with query %s", req.URL.RawQuery)
+			}
+			fmt.Fprintf(w, `{"token":%q}`, r.token)
+			return
+		}
+
+		auth := req.Header.Get("Authorization")
+		r.seenTokens = append(r.seenTokens, strings.TrimPrefix(auth, "Bearer "))
+		if auth != "Bearer "+r.token {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake-registry"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.handler(w, req)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// host returns the host:port the registry is reachable at
+func (r *fakeRegistry) host() string {
+	return strings.TrimPrefix(r.URL, "https://")
+}
+
+func testClient(servers ...*fakeRegistry) *Client {
+	client := NewClient(Options{})
+	client.httpClient = servers[0].Client()
+	return client
+}
+
+func mustParse(t *testing.T, image string) reference.Named {
+	t.Helper()
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return named
+}
+
+func TestListTagsPagination(t *testing.T) {
+	registry := newFakeRegistry(t, "tok", func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Query().Get("last") {
+		case "":
+			if req.URL.Query().Get("n") != "1000" {
+				t.Errorf("page size = %q", req.URL.Query().Get("n"))
+			}
+			w.Header().Set("Link", `</v2/library/nginx/tags/list?n=1000&last=b>; rel="next"`)
+			fmt.Fprint(w, `{"tags":["a","b"]}`)
+		case "b":
+			fmt.Fprint(w, `{"tags":["c"]}`)
+		default:
+			t.Errorf("unexpected page %s", req.URL.RawQuery)
+		}
+	})
+	client := testClient(registry)
+
+	tags, err := client.ListTags(context.Background(), mustParse(t, registry.host()+"/library/nginx"))
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if !slices.Equal(tags, []string{"a", "b", "c"}) {
+		t.Errorf("ListTags() = %v", tags)
+	}
+	if got := registry.tokenFetches.Load(); got != 1 {
+		t.Errorf("fetched %d tokens, want 1 reused across pages", got)
+	}
+}
+
+func TestListTagsPaginationHostPinning(t *testing.T) {
+	registry := newFakeRegistry(t, "tok", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Link", `<https://attacker.example/v2/library/nginx/tags/list?last=b>; rel="next"`)
+		fmt.Fprint(w, `{"tags":["a"]}`)
+	})
+
+	_, err := testClient(registry).ListTags(context.Background(), mustParse(t, registry.host()+"/library/nginx"))
+	if err == nil || !strings.Contains(err.Error(), "different host") {
+		t.Errorf("ListTags() error = %v, want different host", err)
+	}
+}
+
+func TestTokensArePerHost(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) { fmt.Fprint(w, `{"tags":["1"]}`) }
+	first := newFakeRegistry(t, "first-token", handler)
+	second := newFakeRegistry(t, "second-token", handler)
+	// Both test servers share one certificate, so either server's client trusts both
+	client := testClient(first)
+
+	for _, registry := range []*fakeRegistry{first, second, first} {
+		if _, err := client.ListTags(context.Background(), mustParse(t, registry.host()+"/library/nginx")); err != nil {
+			t.Fatalf("ListTags(%s) error = %v", registry.host(), err)
+		}
+	}
+
+	if slices.Contains(second.seenTokens, "first-token") {
+		t.Error("token of the first registry was sent to the second")
+	}
+	if first.tokenFetches.Load() != 1 || second.tokenFetches.Load() != 1 {
+		t.Errorf("token fetches = %d, %d; want one per registry", first.tokenFetches.Load(), second.tokenFetches.Load())
+	}
+}
+
+func TestManifestDigest(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	registry := newFakeRegistry(t, "tok", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodHead {
+			t.Errorf("method = %s, want HEAD", req.Method)
+		}
+		if !strings.Contains(req.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+			t.Errorf("Accept = %q", req.Header.Get("Accept"))
+		}
+		switch req.URL.Path {
+		case "/v2/library/nginx/manifests/latest", "/v2/library/nginx/manifests/1.27":
+			w.Header().Set("Docker-Content-Digest", digest)
+		case "/v2/library/nginx/manifests/no-digest":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	client := testClient(registry)
+
+	tests := []struct {
+		image    string
+		want     string
+		wantErr  bool
+		notFound bool
+	}{
+		{image: "/library/nginx", want: digest},
+		{image: "/library/nginx:1.27", want: digest},
+		{image: "/library/nginx:no-digest", wantErr: true},
+		{image: "/library/nginx:missing", wantErr: true, notFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := client.ManifestDigest(context.Background(), mustParse(t, registry.host()+tt.image))
+			if (err != nil) != tt.wantErr || IsNotFound(err) != tt.notFound {
+				t.Fatalf("ManifestDigest() error = %v, wantErr %v, notFound %v", err, tt.wantErr, tt.notFound)
+			}
+			if got != tt.want {
+				t.Errorf("ManifestDigest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// staticCredentials is a credential store with fixed credentials per host
+type staticCredentials map[string]Credential
+
+func (s staticCredentials) Credential(_ context.Context, host string) (Credential, error) {
+	return s[host], nil
+}
+
+func TestAuthentication(t *testing.T) {
+	tagsHandler := func(w http.ResponseWriter, req *http.Request) { fmt.Fprint(w, `{"tags":["1"]}`) }
+
+	tests := []struct {
+		name       string
+		challenge  string
+		credential Credential
+		token      func(t *testing.T, req *http.Request) bool // Reports whether the token request is authorized
+		wantError  bool
+	}{
+		{
+			name:       "basic",
+			challenge:  `Basic realm="harbor"`,
+			credential: Credential{Username: "robot", Password: "secret"},
+		},
+		{
+			name:      "basic without credentials",
+			challenge: `Basic realm="harbor"`,
+			wantError: true,
+		},
+		{
+			name:       "bearer with password",
+			challenge:  `Bearer realm="%s/token",service="harbor"`,
+			credential: Credential{Username: "robot", Password: "secret"},
+			token: func(t *testing.T, req *http.Request) bool {
+				username, password, ok := req.BasicAuth()
+				return req.Method == http.MethodGet && ok && username == "robot" && password == "secret"
+			},
+		},
+		{
+			name:       "bearer with identity token",
+			challenge:  `Bearer realm="%s/token",service="harbor"`,
+			credential: Credential{IdentityToken: "refresh"},
+			token: func(t *testing.T, req *http.Request) bool {
+				if err := req.ParseForm(); err != nil {
+					t.Error(err)
+				}
+				return req.Method == http.MethodPost && req.PostForm.Get("grant_type") == "refresh_token" &&
+					req.PostForm.Get("refresh_token") == "refresh" && req.PostForm.Get("service") == "harbor" &&
+					strings.HasPrefix(req.PostForm.Get("scope"), "repository:")
+			},
+		},
+		{
+			name:      "bearer with wrong credentials",
+			challenge: `Bearer realm="%s/token",service="harbor"`,
+			token:     func(t *testing.T, req *http.Request) bool { return false },
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/token" {
+					if !tt.token(t, req) {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					fmt.Fprint(w, `{"access_token":"issued"}`)
+					return
+				}
+
+				username, password, ok := req.BasicAuth()
+				basicOK := ok && username == tt.credential.Username && password == tt.credential.Password
+				if req.Header.Get("Authorization") != "Bearer issued" && !basicOK {
+					challenge := tt.challenge
+					if strings.Contains(challenge, "%s") {
+						challenge = fmt.Sprintf(challenge, server.URL)
+					}
+					w.Header().Set("WWW-Authenticate", challenge)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				tagsHandler(w, req)
+			}))
+			defer server.Close()
+
+			host := strings.TrimPrefix(server.URL, "https://")
+			client := NewClient(Options{Credentials: staticCredentials{host: tt.credential}})
+			client.httpClient = server.Client()
+
+			tags, err := client.ListTags(context.Background(), mustParse(t, host+"/library/app"))
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ListTags() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && !slices.Equal(tags, []string{"1"}) {
+				t.Errorf("ListTags() = %v", tags)
+			}
+		})
+	}
+}
+
+func TestInsecureRegistries(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { fmt.Fprint(w, `{"tags":["1"]}`) })
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	selfSigned := httptest.NewTLSServer(handler)
+	defer selfSigned.Close()
+
+	plainHost := strings.TrimPrefix(plain.URL, "http://")
+	selfSignedHost := strings.TrimPrefix(selfSigned.URL, "https://")
+
+	tests := []struct {
+		name      string
+		host      string
+		insecure  []string
+		wantError bool
+	}{
+		{name: "HTTP registry by host", host: plainHost, insecure: []string{plainHost}},
+		{name: "HTTP registry by CIDR", host: plainHost, insecure: []string{"127.0.0.0/8"}},
+		{name: "self-signed registry by host", host: selfSignedHost, insecure: []string{selfSignedHost}},
+		{name: "HTTP registry not insecure", host: plainHost, wantError: true},
+		{name: "self-signed registry not insecure", host: selfSignedHost, insecure: []string{"10.0.0.0/8"}, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(Options{Insecure: tt.insecure})
+			named := mustParse(t, tt.host+"/library/app")
+			for range 2 { // The second request reuses the detected scheme
+				_, err := client.ListTags(context.Background(), named)
+				if (err != nil) != tt.wantError {
+					t.Fatalf("ListTags() error = %v, wantError %v", err, tt.wantError)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateInsecureRegistry(t *testing.T) {
+	for _, entry := range []string{"harbor.local", "harbor.local:5000", "10.0.0.0/8", "::1"} {
+		if err := ValidateInsecureRegistry(entry); err != nil {
+			t.Errorf("ValidateInsecureRegistry(%q) error = %v", entry, err)
+		}
+	}
+	for _, entry := range []string{"", "10.0.0.0/33", "harbor.local/v2", "user@harbor.local"} {
+		if err := ValidateInsecureRegistry(entry); err == nil {
+			t.Errorf("ValidateInsecureRegistry(%q) succeeded", entry)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.docker.io/token" ||
+		params["service"] != "registry.docker.io" || params["scope"] != "repository:library/nginx:pull" {
+		t.Errorf("parseChallenge() = %q, %v", scheme, params)
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                     "registry-1.docker.io",
+		"docker.io/library/nginx:1": "registry-1.docker.io",
+		"ghcr.io/org/app":           "ghcr.io",
+		"localhost:5000/app:1":      "localhost:5000",
+		"not a valid image":         "",
+	}
+	for image, want := range tests {
+		if got := ImageHost(image); got != want {
+			t.Errorf("ImageHost(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
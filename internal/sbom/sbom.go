@@ -0,0 +1,291 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/inventory"
+)
+
+// Formats
+const (
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+)
+
+// toolName identifies this tool as the SBOM creator
+const toolName = "docker-parallel-pull"
+
+// UnscannedProperty names the CycloneDX property listing a package database whose
+// packages are missing from the SBOM because it cannot be read
+const UnscannedProperty = toolName + ":unscanned-package-database"
+
+// Image describes the image an SBOM is generated for
+type Image struct {
+	Name   string // Canonical reference
+	Digest string // Manifest digest, e.g. sha256:...
+}
+
+// Extension returns the file name extension of a format
+func Extension(format string) string {
+	if format == FormatCycloneDX {
+		return ".cdx.json"
+	}
+	return ".spdx.json"
+}
+
+// FileName returns the file name of an image's SBOM, keyed by its digest
+func FileName(digest, format string) string {
+	return strings.ReplaceAll(digest, ":", "-") + Extension(format)
+}
+
+// Generate renders the SBOM of an image in the given format
+func Generate(format string, image Image, inv *inventory.Inventory, created time.Time) ([]byte, error) {
+	var doc interface{}
+	switch format {
+	case FormatSPDX:
+		doc = spdxDocument(image, inv, created)
+	case FormatCycloneDX:
+		doc = cycloneDXDocument(image, inv, created)
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format: %s", format)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SBOM: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// PackageURL returns the purl of an OS package, e.g. pkg:deb/debian/openssl@3.0.11-1?distro=debian-12
+func PackageURL(pkg inventory.Package, distro inventory.Distro) string {
+	namespace := distro.ID
+	if namespace == "" {
+		namespace = map[string]string{inventory.EcosystemDeb: "debian", inventory.EcosystemApk: "alpine"}[pkg.Ecosystem]
+	}
+
+	purl := fmt.Sprintf("pkg:%s/%s/%s@%s", pkg.Ecosystem, escape(namespace), escape(pkg.Name), escape(pkg.Version))
+
+	var qualifiers []string
+	if distro.ID != "" && distro.VersionID != "" {
+		qualifiers = append(qualifiers, "distro="+escape(distro.ID+"-"+distro.VersionID))
+	}
+	if pkg.Source != "" {
+		qualifiers = append(qualifiers, "upstream="+escape(pkg.Source))
+	}
+	if len(qualifiers) > 0 {
+		purl += "?" + strings.Join(qualifiers, "&")
+	}
+	return purl
+}
+
+// escape percent-encodes everything except unreserved characters, as required for purl components
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// spdxDoc is an SPDX 2.3 JSON document
+type spdxDoc struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Comment           string             `json:"comment,omitempty"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	PrimaryPurpose   string            `json:"primaryPackagePurpose,omitempty"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxDocument builds an SPDX document describing the image and the packages it contains
+func spdxDocument(image Image, inv *inventory.Inventory, created time.Time) spdxDoc {
+	imagePackage := spdxPackage{
+		SPDXID:           "SPDXRef-Image",
+		Name:             image.Name,
+		VersionInfo:      image.Digest,
+		DownloadLocation: "NOASSERTION",
+		PrimaryPurpose:   "CONTAINER",
+	}
+	if algorithm, value, ok := strings.Cut(image.Digest, ":"); ok {
+		imagePackage.Checksums = []spdxChecksum{{Algorithm: strings.ToUpper(algorithm), ChecksumValue: value}}
+	}
+
+	doc := spdxDoc{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              image.Name,
+		DocumentNamespace: fmt.Sprintf("https://github.com/guessi/docker-parallel-pull/sbom/%s", strings.ReplaceAll(image.Digest, ":", "-")),
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages: []spdxPackage{imagePackage},
+		Relationships: []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: imagePackage.SPDXID},
+		},
+	}
+	if len(inv.Unsupported) > 0 {
+		doc.Comment = "Packages of these unsupported package databases are not listed: " + strings.Join(inv.Unsupported, ", ")
+	}
+
+	for i, pkg := range inv.Packages {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:           id,
+			Name:             pkg.Name,
+			VersionInfo:      pkg.Version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  PackageURL(pkg, inv.Distro),
+			}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID: imagePackage.SPDXID, RelationshipType: "CONTAINS", RelatedSPDXElement: id,
+		})
+	}
+	return doc
+}
+
+// cdxDoc is a CycloneDX 1.5 JSON document
+type cdxDoc struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber,omitempty"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp  string        `json:"timestamp"`
+	Tools      cdxTools      `json:"tools"`
+	Component  cdxComponent  `json:"component"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type    string    `json:"type"`
+	BOMRef  string    `json:"bom-ref,omitempty"`
+	Name    string    `json:"name"`
+	Version string    `json:"version,omitempty"`
+	PURL    string    `json:"purl,omitempty"`
+	Hashes  []cdxHash `json:"hashes,omitempty"`
+}
+
+type cdxHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+// cycloneDXDocument builds a CycloneDX document with the image as metadata component
+func cycloneDXDocument(image Image, inv *inventory.Inventory, created time.Time) cdxDoc {
+	imageComponent := cdxComponent{
+		Type:    "container",
+		BOMRef:  image.Name + "@" + image.Digest,
+		Name:    image.Name,
+		Version: image.Digest,
+	}
+	if algorithm, value, ok := strings.Cut(image.Digest, ":"); ok && algorithm == "sha256" {
+		imageComponent.Hashes = []cdxHash{{Algorithm: "SHA-256", Content: value}}
+	}
+
+	doc := cdxDoc{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cdxMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: toolName}}},
+			Component: imageComponent,
+		},
+		Components: []cdxComponent{},
+	}
+	if _, value, ok := strings.Cut(image.Digest, ":"); ok && len(value) >= 32 {
+		doc.SerialNumber = "urn:uuid:" + digestUUID(value)
+	}
+	for _, database := range inv.Unsupported {
+		doc.Metadata.Properties = append(doc.Metadata.Properties, cdxProperty{Name: UnscannedProperty, Value: database})
+	}
+
+	seen := make(map[string]bool)
+	for _, pkg := range inv.Packages {
+		// bom-ref values must be unique within a document
+		purl := PackageURL(pkg, inv.Distro)
+		if seen[purl] {
+			continue
+		}
+		seen[purl] = true
+		doc.Components = append(doc.Components, cdxComponent{
+			Type:    "library",
+			BOMRef:  purl,
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			PURL:    purl,
+		})
+	}
+	return doc
+}
+
+// digestUUID derives a stable name-based (version 5 layout) UUID from the hex digest,
+// so regenerated SBOMs of an image share a serial number
+func digestUUID(hex string) string {
+	variant := "89ab"[strings.IndexByte("0123456789abcdef", hex[16])&3]
+	return fmt.Sprintf("%s-%s-5%s-%c%s-%s", hex[0:8], hex[8:12], hex[13:16], variant, hex[17:20], hex[20:32])
+}
@@ -0,0 +1,154 @@
+package sbom
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/inventory"
+)
+
+var testInventory = &inventory.Inventory{
+	Distro: inventory.Distro{ID: "debian", VersionID: "12"},
+	Packages: []inventory.Package{
+		{Name: "libssl3", Version: "3.0.11-1~deb12u1", Source: "openssl", Ecosystem: inventory.EcosystemDeb},
+		{Name: "libc6", Version: "2.36-9+deb12u3", Ecosystem: inventory.EcosystemDeb},
+	},
+}
+
+var testImage = Image{
+	Name:   "docker.io/library/debian:12",
+	Digest: "sha256:" + strings.Repeat("0123456789abcdef", 4),
+}
+
+func TestPackageURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		pkg    inventory.Package
+		distro inventory.Distro
+		want   string
+	}{
+		{
+			name:   "deb with source and distro",
+			pkg:    testInventory.Packages[0],
+			distro: testInventory.Distro,
+			want:   "pkg:deb/debian/libssl3@3.0.11-1~deb12u1?distro=debian-12&upstream=openssl",
+		},
+		{
+			name:   "epoch and plus are escaped",
+			pkg:    inventory.Package{Name: "libc6", Version: "1:2.36-9+deb12u3", Ecosystem: inventory.EcosystemDeb},
+			distro: inventory.Distro{ID: "ubuntu"},
+			want:   "pkg:deb/ubuntu/libc6@1%3A2.36-9%2Bdeb12u3",
+		},
+		{
+			name: "apk without os-release",
+			pkg:  inventory.Package{Name: "musl", Version: "1.2.4-r2", Ecosystem: inventory.EcosystemApk},
+			want: "pkg:apk/alpine/musl@1.2.4-r2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PackageURL(tt.pkg, tt.distro); got != tt.want {
+				t.Errorf("PackageURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSPDX(t *testing.T) {
+	data, err := Generate(FormatSPDX, testImage, testInventory, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var doc spdxDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Generate() produced invalid JSON: %v", err)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.CreationInfo.Created != "2024-01-02T03:04:05Z" {
+		t.Errorf("Generate() header = %s %s", doc.SPDXVersion, doc.CreationInfo.Created)
+	}
+	if len(doc.Packages) != 3 || len(doc.Relationships) != 3 {
+		t.Errorf("Generate() packages = %d, relationships = %d, want 3 and 3", len(doc.Packages), len(doc.Relationships))
+	}
+	if doc.Packages[0].PrimaryPurpose != "CONTAINER" || doc.Packages[0].Checksums[0].Algorithm != "SHA256" {
+		t.Errorf("Generate() image package = %+v", doc.Packages[0])
+	}
+}
+
+func TestGenerateCycloneDX(t *testing.T) {
+	data, err := Generate(FormatCycloneDX, testImage, testInventory, time.Now())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var doc cdxDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Generate() produced invalid JSON: %v", err)
+	}
+	uuid := regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-[1-5][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(doc.SerialNumber) {
+		t.Errorf("Generate() serial number = %q", doc.SerialNumber)
+	}
+	if doc.Metadata.Component.Type != "container" || len(doc.Components) != 2 {
+		t.Errorf("Generate() component = %+v, %d components", doc.Metadata.Component, len(doc.Components))
+	}
+}
+
+func TestGenerateRecordsUnscannedDatabases(t *testing.T) {
+	inv := &inventory.Inventory{
+		Distro:      inventory.Distro{ID: "rhel", VersionID: "9.4"},
+		Unsupported: []string{"var/lib/rpm/rpmdb.sqlite"},
+	}
+
+	data, err := Generate(FormatSPDX, testImage, inv, time.Now())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	var spdx spdxDoc
+	if err := json.Unmarshal(data, &spdx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(spdx.Comment, "var/lib/rpm/rpmdb.sqlite") {
+		t.Errorf("SPDX comment = %q, want the unscanned database", spdx.Comment)
+	}
+
+	data, err = Generate(FormatCycloneDX, testImage, inv, time.Now())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	var cdx cdxDoc
+	if err := json.Unmarshal(data, &cdx); err != nil {
+		t.Fatal(err)
+	}
+	want := []cdxProperty{{Name: UnscannedProperty, Value: "var/lib/rpm/rpmdb.sqlite"}}
+	if !reflect.DeepEqual(cdx.Metadata.Properties, want) {
+		t.Errorf("CycloneDX properties = %+v, want %+v", cdx.Metadata.Properties, want)
+	}
+
+	// Fully scanned images carry neither
+	data, _ = Generate(FormatSPDX, testImage, testInventory, time.Now())
+	if strings.Contains(string(data), `"comment"`) {
+		t.Error("SPDX document of a scanned image has a comment")
+	}
+	data, _ = Generate(FormatCycloneDX, testImage, testInventory, time.Now())
+	if strings.Contains(string(data), `"properties"`) {
+		t.Error("CycloneDX document of a scanned image has properties")
+	}
+}
+
+func TestGenerateUnknownFormat(t *testing.T) {
+	if _, err := Generate("swid", testImage, testInventory, time.Now()); err == nil {
+		t.Error("Generate() accepted an unknown format")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	if got := FileName("sha256:abc", FormatCycloneDX); got != "sha256-abc.cdx.json" {
+		t.Errorf("FileName() = %q", got)
+	}
+}
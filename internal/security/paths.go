@@ -0,0 +1,166 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// AllowedPathsEnv names the environment variable with extra allowed roots (separated like PATH).
+// It applies from startup, so it can be used to allow the config file location itself.
+const AllowedPathsEnv = "DOCKER_PARALLEL_PULL_ALLOWED_PATHS"
+
+// DefaultAllowedRoots are the directories files may be read from and written to by default
+var DefaultAllowedRoots = []string{"/etc/docker-parallel-pull", "/tmp", "/var/tmp", "."}
+
+// PathPolicy controls which files may be read or written.
+// Paths are resolved (including symlinks) before they are checked against the allowed roots.
+type PathPolicy struct {
+	AllowedRoots       []string // Directories files must resolve into
+	CheckOwnership     bool     // Files must be owned by the current user or root
+	AllowWorldWritable bool     // Accept files that any user can modify
+}
+
+var (
+	pathPolicyMu     sync.RWMutex
+	activePathPolicy = DefaultPathPolicy()
+)
+
+// DefaultPathPolicy returns the default roots plus any roots from AllowedPathsEnv
+func DefaultPathPolicy() PathPolicy {
+	roots := append([]string{}, DefaultAllowedRoots...)
+	if extra := os.Getenv(AllowedPathsEnv); extra != "" {
+		for _, root := range filepath.SplitList(extra) {
+			if root != "" {
+				roots = append(roots, root)
+			}
+		}
+	}
+	return PathPolicy{AllowedRoots: roots}
+}
+
+// SetPathPolicy replaces the policy used by ValidateFilePath, SecureReadFile,
+// SecureWalkFiles and WriteFileAtomic
+func SetPathPolicy(policy PathPolicy) error {
+	if len(policy.AllowedRoots) == 0 {
+		return fmt.Errorf("at least one allowed root is required")
+	}
+	for _, root := range policy.AllowedRoots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return fmt.Errorf("invalid allowed root: %w", err)
+		}
+		if abs == filepath.VolumeName(abs)+string(filepath.Separator) {
+			return fmt.Errorf("the filesystem root cannot be an allowed root")
+		}
+	}
+
+	pathPolicyMu.Lock()
+	defer pathPolicyMu.Unlock()
+	activePathPolicy = policy
+	return nil
+}
+
+// currentPathPolicy returns the active path policy
+func currentPathPolicy() PathPolicy {
+	pathPolicyMu.RLock()
+	defer pathPolicyMu.RUnlock()
+	return activePathPolicy
+}
+
+// Resolve returns the absolute, symlink-free form of path after checking that it
+// lies within an allowed root. Paths that do not exist yet are resolved through
+// their parent directory so they can be created.
+func (p PathPolicy) Resolve(path string) (string, error) {
+	resolved, _, err := p.resolve(path)
+	return resolved, err
+}
+
+// OpenRoot resolves path like Resolve and opens the allowed root containing it.
+// It returns the path relative to that root; files opened through the root cannot
+// escape it, even if a directory or symlink on the path is replaced after Resolve.
+func (p PathPolicy) OpenRoot(path string) (*os.Root, string, error) {
+	resolved, rootDir, err := p.resolve(path)
+	if err != nil {
+		return nil, "", err
+	}
+	rel, err := filepath.Rel(rootDir, resolved)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid file path: %s", SanitizeErrorMessage(err))
+	}
+	root, err := os.OpenRoot(rootDir)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot open allowed directory: %s", SanitizeErrorMessage(err))
+	}
+	return root, rel, nil
+}
+
+// resolve returns the resolved form of path and the resolved allowed root containing it
+func (p PathPolicy) resolve(path string) (string, string, error) {
+	for _, element := range strings.Split(filepath.ToSlash(path), "/") {
+		if element == ".." {
+			return "", "", fmt.Errorf("path traversal detected in: %s", SanitizeLogMessage(path))
+		}
+	}
+
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid file path: %s", SanitizeErrorMessage(err))
+	}
+
+	for _, root := range p.AllowedRoots {
+		resolvedRoot, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(resolvedRoot, resolved)
+		if err != nil {
+			continue
+		}
+		if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+			return resolved, resolvedRoot, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("file path not in allowed directories: %s", SanitizeLogMessage(resolved))
+}
+
+// CheckFile verifies ownership and permissions of an opened file against the policy
+func (p PathPolicy) CheckFile(name string, info fs.FileInfo) error {
+	if !p.AllowWorldWritable && info.Mode().Perm()&0o002 != 0 {
+		return fmt.Errorf("file is world-writable: %s", SanitizeLogMessage(name))
+	}
+	if p.CheckOwnership {
+		if err := checkOwner(info); err != nil {
+			return fmt.Errorf("%w: %s", err, SanitizeLogMessage(name))
+		}
+	}
+	return nil
+}
+
+// resolvePath makes path absolute and evaluates symlinks. When path does not exist,
+// its parent directory is resolved instead and the base name is appended.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	parent, err := filepath.EvalSymlinks(filepath.Dir(abs))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(abs)), nil
+}
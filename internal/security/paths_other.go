@@ -0,0 +1,10 @@
+//go:build !unix
+
+package security
+
+import "io/fs"
+
+// checkOwner is a no-op on platforms without Unix file ownership
+func checkOwner(info fs.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,226 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathPolicyResolve(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	sibling := filepath.Join(base, "rootfoo")
+	for _, dir := range []string{root, sibling} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "config.yaml"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sibling, "config.yaml"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(sibling, "config.yaml"), filepath.Join(root, "escape.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := PathPolicy{AllowedRoots: []string{root}}
+
+	tests := []struct {
+		name      string
+		path      string
+		wantError bool
+	}{
+		{
+			name:      "file inside root",
+			path:      filepath.Join(root, "config.yaml"),
+			wantError: false,
+		},
+		{
+			name:      "new file inside root",
+			path:      filepath.Join(root, "lock.yaml"),
+			wantError: false,
+		},
+		{
+			name:      "directory sharing the root prefix",
+			path:      filepath.Join(sibling, "config.yaml"),
+			wantError: true,
+		},
+		{
+			name:      "symlink pointing outside root",
+			path:      filepath.Join(root, "escape.yaml"),
+			wantError: true,
+		},
+		{
+			name:      "path traversal",
+			path:      root + "/../rootfoo/config.yaml",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := policy.Resolve(tt.path)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Resolve() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestPathPolicyCheckFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(name, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(name, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (PathPolicy{}).CheckFile(name, info); err == nil {
+		t.Error("CheckFile() accepted a world-writable file")
+	}
+	if err := (PathPolicy{AllowWorldWritable: true, CheckOwnership: true}).CheckFile(name, info); err != nil {
+		t.Errorf("CheckFile() error = %v", err)
+	}
+}
+
+func TestSetPathPolicyRejectsFilesystemRoot(t *testing.T) {
+	if err := SetPathPolicy(PathPolicy{AllowedRoots: []string{"/"}}); err == nil {
+		t.Error("SetPathPolicy() accepted the filesystem root")
+	}
+	if err := SetPathPolicy(PathPolicy{}); err == nil {
+		t.Error("SetPathPolicy() accepted an empty root list")
+	}
+}
+
+func TestPathPolicyOpenRootAfterSwap(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "configs"), outside} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "config.yaml"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := PathPolicy{AllowedRoots: []string{root}}
+	dirRoot, name, err := policy.OpenRoot(filepath.Join(root, "configs", "config.yaml"))
+	if err != nil {
+		t.Fatalf("OpenRoot() error = %v", err)
+	}
+	defer dirRoot.Close()
+	if name != filepath.Join("configs", "config.yaml") {
+		t.Errorf("OpenRoot() name = %q", name)
+	}
+
+	// Replace the checked directory with a symlink leaving the root
+	if err := os.Remove(filepath.Join(root, "configs")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "configs")); err != nil {
+		t.Fatal(err)
+	}
+
+	if file, err := dirRoot.Open(name); err == nil {
+		file.Close()
+		t.Error("opened a file outside the root after the path was swapped")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	if err := SetPathPolicy(PathPolicy{AllowedRoots: []string{dir}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetPathPolicy(DefaultPathPolicy()) })
+
+	filename := filepath.Join(dir, "nested", "out.yaml")
+	if err := os.Mkdir(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(filename, []byte(content)); err != nil {
+			t.Fatalf("WriteFileAtomic() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil || string(data) != "second" {
+		t.Errorf("file = %q, %v; want second", data, err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, %v; want 0644", info.Mode(), err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(filename))
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the written file", len(entries))
+	}
+
+	if err := WriteFileAtomic(filepath.Join(t.TempDir(), "out.yaml"), []byte("x")); err == nil {
+		t.Error("WriteFileAtomic() outside the allowed roots succeeded")
+	}
+}
+
+func TestSecureMkdirAll(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{root, outside} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPathPolicy(PathPolicy{AllowedRoots: []string{root}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetPathPolicy(DefaultPathPolicy()) })
+
+	tests := []struct {
+		name      string
+		dir       string
+		wantError bool
+	}{
+		{name: "nested", dir: filepath.Join(root, "sboms", "2026")},
+		{name: "existing", dir: filepath.Join(root, "sboms")},
+		{name: "allowed root", dir: root},
+		{name: "file", dir: filepath.Join(root, "file"), wantError: true},
+		{name: "below a file", dir: filepath.Join(root, "file", "sboms"), wantError: true},
+		{name: "symlink leaving the root", dir: filepath.Join(root, "escape", "sboms"), wantError: true},
+		{name: "outside the roots", dir: filepath.Join(outside, "sboms"), wantError: true},
+		{name: "traversal", dir: filepath.Join(root, "sboms") + "/../../outside/sboms", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SecureMkdirAll(tt.dir)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("SecureMkdirAll() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError {
+				if info, err := os.Stat(tt.dir); err != nil || !info.IsDir() {
+					t.Errorf("directory not created: %v", err)
+				}
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(outside, "sboms")); err == nil {
+		t.Error("a directory was created outside the allowed roots")
+	}
+}
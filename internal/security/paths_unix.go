@@ -0,0 +1,22 @@
+//go:build unix
+
+package security
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// checkOwner requires a file to be owned by the current user or root
+func checkOwner(info fs.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return errors.New("cannot determine file owner")
+	}
+	if stat.Uid != 0 && int(stat.Uid) != os.Geteuid() {
+		return errors.New("file is not owned by the current user or root")
+	}
+	return nil
+}
@@ -1,62 +1,59 @@
 package security
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+
+	"github.com/guessi/docker-parallel-pull/internal/redact"
 )
 
 // Security constants
 const (
-	MaxFileSize        = 10 * 1024 * 1024  // 10MB max file size
-	MaxImages          = 1000              // Maximum number of images
-	AllowedConfigPaths = "/tmp,/var/tmp,." // Allowed config file paths
+	MaxFileSize = 10 * 1024 * 1024 // 10MB max file size
+	MaxImages   = 1000             // Maximum number of images
+	MaxDirFiles = 1000             // Maximum number of files read from a directory
 )
 
 // Security validation regex patterns
 var (
 	validImageNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/-]*[a-zA-Z0-9]$`)
 	validTagRegex       = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
-	sensitiveDataRegex  = regexp.MustCompile(`(?i)(password|token|key|secret)=[a-zA-Z0-9]+`)
-	pathRegex           = regexp.MustCompile(`/[a-zA-Z0-9/_.-]+`)
-	ipRegex             = regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)
+	validDigestRegex    = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
 )
 
-// ValidateFilePath ensures the file path is safe and within allowed directories
-func ValidateFilePath(filePath string) error {
-	cleanPath := filepath.Clean(filePath)
-	absPath, err := filepath.Abs(cleanPath)
-	if err != nil {
-		return fmt.Errorf("invalid file path: %w", err)
-	}
-
-	if strings.Contains(filePath, "..") {
-		return fmt.Errorf("path traversal detected in: %s", SanitizeLogMessage(filePath))
-	}
+var (
+	redactorMu     sync.RWMutex
+	activeRedactor = redact.Default()
+)
 
-	allowedPaths := strings.Split(AllowedConfigPaths, ",")
-	allowed := false
-	for _, allowedPath := range allowedPaths {
-		allowedAbs, err := filepath.Abs(strings.TrimSpace(allowedPath))
-		if err != nil {
-			continue
-		}
-		if strings.HasPrefix(absPath, allowedAbs) {
-			allowed = true
-			break
-		}
-	}
+// SetRedactor replaces the redactor used by SanitizeErrorMessage and SanitizeLogMessage
+func SetRedactor(r *redact.Redactor) {
+	redactorMu.Lock()
+	defer redactorMu.Unlock()
+	activeRedactor = r
+}
 
-	if !allowed {
-		return fmt.Errorf("file path not in allowed directories: %s", SanitizeLogMessage(absPath))
-	}
+// currentRedactor returns the active redactor
+func currentRedactor() *redact.Redactor {
+	redactorMu.RLock()
+	defer redactorMu.RUnlock()
+	return activeRedactor
+}
 
-	return nil
+// ValidateFilePath ensures the file path is safe and within allowed directories
+func ValidateFilePath(filePath string) error {
+	_, err := currentPathPolicy().Resolve(filePath)
+	return err
 }
 
 // ValidateImageName validates Docker image names for security
@@ -73,8 +70,14 @@ func ValidateImageName(imageName string) error {
 		return fmt.Errorf("image name contains suspicious characters: %s", SanitizeLogMessage(imageName))
 	}
 
+	// Split off and validate the digest if present
+	name, digest, hasDigest := strings.Cut(imageName, "@")
+	if hasDigest && !validDigestRegex.MatchString(digest) {
+		return fmt.Errorf("invalid image digest: %s", SanitizeLogMessage(digest))
+	}
+
 	// Split by colon to separate name and tag
-	nameTag := strings.Split(imageName, ":")
+	nameTag := strings.Split(name, ":")
 	imageParts := nameTag[0] // The part before the colon (or the whole string if no colon)
 
 	parts := strings.Split(imageParts, "/")
@@ -100,66 +103,309 @@ func ValidateImageName(imageName string) error {
 	return nil
 }
 
-// SecureReadFile reads a file with size limits and validation
+// SecureReadFile reads a file within the allowed directories with size limits and validation
 func SecureReadFile(filename string) ([]byte, error) {
-	// Use only the base filename to prevent path traversal
-	baseName := filepath.Base(filename)
-	if strings.Contains(baseName, "..") || baseName != filename {
-		return nil, fmt.Errorf("path contains traversal sequences")
-	}
-
-	info, err := os.Stat(baseName)
+	file, info, err := SecureOpenFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("cannot access file: %s", SanitizeErrorMessage(err))
+		return nil, err
 	}
+	defer file.Close()
 
 	if info.Size() > MaxFileSize {
 		return nil, fmt.Errorf("file too large: %d bytes (max: %d)", info.Size(), MaxFileSize)
 	}
 
+	limitedReader := io.LimitReader(file, MaxFileSize+1)
+	data, err := io.ReadAll(limitedReader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %s", SanitizeErrorMessage(err))
+	}
+
+	if len(data) > MaxFileSize {
+		return nil, fmt.Errorf("file size exceeds limit during read")
+	}
+
+	return data, nil
+}
+
+// SecureOpenFile opens a regular file within the allowed directories for reading.
+// Callers streaming the file are responsible for their own size limits.
+func SecureOpenFile(filename string) (*os.File, fs.FileInfo, error) {
+	policy := currentPathPolicy()
+	root, name, err := policy.OpenRoot(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer root.Close()
+
+	file, err := root.Open(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open file: %s", SanitizeErrorMessage(err))
+	}
+
+	// Check the opened file so the checks apply to what is actually read
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("cannot access file: %s", SanitizeErrorMessage(err))
+	}
+
 	if !info.Mode().IsRegular() {
-		return nil, fmt.Errorf("not a regular file: %s", SanitizeLogMessage(baseName))
+		file.Close()
+		return nil, nil, fmt.Errorf("not a regular file: %s", SanitizeLogMessage(filename))
 	}
 
-	file, err := os.Open(baseName)
+	if err := policy.CheckFile(filename, info); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return file, info, nil
+}
+
+// SecureAppendFile appends data to a file within the allowed directories,
+// creating it readable only by the current user if it does not exist
+func SecureAppendFile(filename string, data []byte) error {
+	policy := currentPathPolicy()
+	root, name, err := policy.OpenRoot(filename)
 	if err != nil {
-		return nil, fmt.Errorf("cannot open file: %s", SanitizeErrorMessage(err))
+		return err
+	}
+	defer root.Close()
+
+	file, err := root.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot open file: %s", SanitizeErrorMessage(err))
 	}
 	defer file.Close()
 
-	limitedReader := io.LimitReader(file, MaxFileSize+1)
-	data, err := io.ReadAll(limitedReader)
+	info, err := file.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("cannot read file: %s", SanitizeErrorMessage(err))
+		return fmt.Errorf("cannot access file: %s", SanitizeErrorMessage(err))
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file: %s", SanitizeLogMessage(filename))
+	}
+	if err := policy.CheckFile(filename, info); err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("cannot write file: %s", SanitizeErrorMessage(err))
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("cannot sync file: %s", SanitizeErrorMessage(err))
+	}
+	return nil
+}
+
+// SecureOpenFileRW opens a regular file within the allowed directories for reading and
+// writing, creating it readable only by the current user if it does not exist
+func SecureOpenFileRW(filename string) (*os.File, error) {
+	policy := currentPathPolicy()
+	root, name, err := policy.OpenRoot(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	file, err := root.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %s", SanitizeErrorMessage(err))
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("cannot access file: %s", SanitizeErrorMessage(err))
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("not a regular file: %s", SanitizeLogMessage(filename))
+	}
+	if err := policy.CheckFile(filename, info); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// SecureReadStdin reads standard input with the same size limit as files
+func SecureReadStdin() ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(os.Stdin, MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read standard input: %s", SanitizeErrorMessage(err))
 	}
 
 	if len(data) > MaxFileSize {
-		return nil, fmt.Errorf("file size exceeds limit during read")
+		return nil, fmt.Errorf("input size exceeds limit during read")
 	}
 
 	return data, nil
 }
 
+// SecureWalkFiles reads every regular file below dir accepted by match and passes its
+// contents to fn. The walk is confined to dir: symlinks are not followed outside of it.
+func SecureWalkFiles(dir string, match func(name string) bool, fn func(path string, data []byte) error) error {
+	policy := currentPathPolicy()
+	allowedRoot, name, err := policy.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer allowedRoot.Close()
+
+	root, err := allowedRoot.OpenRoot(name)
+	if err != nil {
+		return fmt.Errorf("cannot open directory: %s", SanitizeErrorMessage(err))
+	}
+	defer root.Close()
+
+	fileCount := 0
+	return fs.WalkDir(root.FS(), ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("cannot walk directory: %s", SanitizeErrorMessage(err))
+		}
+		if !entry.Type().IsRegular() || !match(entry.Name()) {
+			return nil
+		}
+
+		fileCount++
+		if fileCount > MaxDirFiles {
+			return fmt.Errorf("too many files in directory, maximum allowed: %d", MaxDirFiles)
+		}
+
+		file, err := root.Open(path)
+		if err != nil {
+			return fmt.Errorf("cannot open file: %s", SanitizeErrorMessage(err))
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("cannot access file: %s", SanitizeErrorMessage(err))
+		}
+		if err := policy.CheckFile(path, info); err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(io.LimitReader(file, MaxFileSize+1))
+		if err != nil {
+			return fmt.Errorf("cannot read file: %s", SanitizeErrorMessage(err))
+		}
+		if len(data) > MaxFileSize {
+			return fmt.Errorf("file too large: %s", SanitizeLogMessage(path))
+		}
+
+		return fn(filepath.Join(dir, path), data)
+	})
+}
+
+// WriteFileAtomic validates the destination path and writes data to a temporary
+// file in the same directory before renaming it into place. The file is created
+// within the allowed root and must still be in place after the rename.
+func WriteFileAtomic(filename string, data []byte) error {
+	root, name, err := currentPathPolicy().OpenRoot(filename)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	tmpName := filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".tmp-"+rand.Text())
+	tmp, err := root.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %s", SanitizeErrorMessage(err))
+	}
+	defer root.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write temporary file: %s", SanitizeErrorMessage(err))
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot set file permissions: %s", SanitizeErrorMessage(err))
+	}
+	written, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot access temporary file: %s", SanitizeErrorMessage(err))
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write temporary file: %s", SanitizeErrorMessage(err))
+	}
+
+	if err := os.Rename(filepath.Join(root.Name(), tmpName), filepath.Join(root.Name(), name)); err != nil {
+		return fmt.Errorf("cannot replace file: %s", SanitizeErrorMessage(err))
+	}
+	// The rename works on paths, so check it replaced the file inside the root
+	if info, err := root.Lstat(name); err != nil || !os.SameFile(info, written) {
+		return fmt.Errorf("file was replaced outside the allowed directories: %s", SanitizeLogMessage(filename))
+	}
+	return nil
+}
+
+// SecureMkdirAll creates a directory and any missing parents within the allowed
+// directories. The directories are created through the allowed root containing the
+// nearest existing parent, so a parent swapped for a symlink cannot redirect them.
+func SecureMkdirAll(dir string) error {
+	for _, element := range strings.Split(filepath.ToSlash(dir), "/") {
+		if element == ".." {
+			return fmt.Errorf("path traversal detected in: %s", SanitizeLogMessage(dir))
+		}
+	}
+	existing, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid file path: %s", SanitizeErrorMessage(err))
+	}
+
+	var missing []string
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("cannot access directory: %s", SanitizeErrorMessage(err))
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+
+	root, name, err := currentPathPolicy().OpenRoot(existing)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	for _, element := range missing {
+		name = filepath.Join(name, element)
+		if err := root.Mkdir(name, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("cannot create directory: %s", SanitizeErrorMessage(err))
+		}
+	}
+	info, err := root.Stat(name)
+	if err != nil {
+		return fmt.Errorf("cannot access directory: %s", SanitizeErrorMessage(err))
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", SanitizeLogMessage(dir))
+	}
+	return nil
+}
+
 // SanitizeErrorMessage removes sensitive information from error messages
 func SanitizeErrorMessage(err error) string {
 	if err == nil {
 		return ""
 	}
 
-	errMsg := err.Error()
-	errMsg = pathRegex.ReplaceAllString(errMsg, "[PATH_REDACTED]")
-	errMsg = sensitiveDataRegex.ReplaceAllString(errMsg, "$1=[REDACTED]")
-	errMsg = ipRegex.ReplaceAllString(errMsg, "[IP_REDACTED]")
-
-	return errMsg
+	return currentRedactor().Redact(err.Error())
 }
 
 // SanitizeLogMessage removes sensitive information from log messages
 func SanitizeLogMessage(message string) string {
-	message = pathRegex.ReplaceAllString(message, "[PATH_REDACTED]")
-	message = sensitiveDataRegex.ReplaceAllString(message, "$1=[REDACTED]")
-	message = ipRegex.ReplaceAllString(message, "[IP_REDACTED]")
-	return message
+	return currentRedactor().Redact(message)
 }
 
 // CalculateImageHash calculates a hash of the pulled image for integrity verification
@@ -1,6 +1,7 @@
 package security
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -20,6 +21,16 @@ func TestValidateImageName(t *testing.T) {
 			imageName: "docker.io/library/alpine:latest",
 			wantError: false,
 		},
+		{
+			name:      "valid image with digest",
+			imageName: "docker.io/library/alpine@sha256:" + strings.Repeat("a", 64),
+			wantError: false,
+		},
+		{
+			name:      "invalid digest",
+			imageName: "alpine@sha256:xyz",
+			wantError: true,
+		},
 		{
 			name:      "empty image name",
 			imageName: "",
@@ -0,0 +1,175 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+)
+
+// Status is the outcome of verifying an image signature
+type Status string
+
+const (
+	StatusVerified Status = "verified" // A signature was verified with a configured key
+	StatusUnsigned Status = "unsigned" // No signature artifact exists for the digest
+	StatusInvalid  Status = "invalid"  // Signatures exist but none verifies
+	StatusError    Status = "error"    // The signature artifact could not be fetched
+)
+
+// signatureAnnotation holds the base64 signature of a cosign signature layer
+const signatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// Fetcher retrieves manifests and blobs from a registry
+type Fetcher interface {
+	GetManifest(ctx context.Context, named reference.Named, tagOrDigest string) ([]byte, error)
+	GetBlob(ctx context.Context, named reference.Named, digest string) ([]byte, error)
+}
+
+// ociManifest is the subset of an OCI image manifest used by cosign signature artifacts
+type ociManifest struct {
+	Layers []struct {
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// simpleSigningPayload is the signed payload format used by cosign
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// LoadPublicKeys reads PEM encoded public keys (ECDSA, RSA or Ed25519)
+func LoadPublicKeys(filenames []string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, filename := range filenames {
+		data, err := security.SecureReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("%s does not contain a PEM public key", security.SanitizeLogMessage(filename))
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", security.SanitizeLogMessage(filename), err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Verify fetches the cosign signature artifact of an image digest and verifies it
+// against the public keys. No transparency log is consulted.
+func Verify(ctx context.Context, fetcher Fetcher, named reference.Named, digest string, keys []crypto.PublicKey) (Status, error) {
+	algorithm, hexDigest, ok := strings.Cut(digest, ":")
+	if !ok {
+		return StatusError, fmt.Errorf("invalid digest %s", security.SanitizeLogMessage(digest))
+	}
+
+	data, err := fetcher.GetManifest(ctx, named, algorithm+"-"+hexDigest+".sig")
+	if err != nil {
+		if registry.IsNotFound(err) {
+			return StatusUnsigned, nil
+		}
+		return StatusError, fmt.Errorf("failed to fetch signature manifest: %w", err)
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return StatusError, fmt.Errorf("failed to parse signature manifest: %w", err)
+	}
+
+	var lastErr error = errors.New("no signatures found")
+	for _, layer := range manifest.Layers {
+		encoded, ok := layer.Annotations[signatureAnnotation]
+		if !ok {
+			continue
+		}
+		payload, err := fetcher.GetBlob(ctx, named, layer.Digest)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to fetch signature payload: %w", err)
+			continue
+		}
+		if err := verifyLayer(payload, encoded, digest, keys); err != nil {
+			lastErr = err
+			continue
+		}
+		return StatusVerified, nil
+	}
+
+	return StatusInvalid, lastErr
+}
+
+// verifyLayer verifies a single signature and checks that its payload refers to digest
+func verifyLayer(payload []byte, encodedSignature, digest string, keys []crypto.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	verified := false
+	for _, key := range keys {
+		if VerifySignature(key, payload, sig) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return errors.New("signature does not match any configured public key")
+	}
+
+	var signed simpleSigningPayload
+	if err := json.Unmarshal(payload, &signed); err != nil {
+		return fmt.Errorf("invalid signature payload: %w", err)
+	}
+	if signed.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("signature is for digest %s, not %s", signed.Critical.Image.DockerManifestDigest, digest)
+	}
+	return nil
+}
+
+// VerifySignature verifies sig over payload with a public key using SHA-256
+func VerifySignature(key crypto.PublicKey, payload, sig []byte) error {
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, hash[:], sig) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil {
+			return nil
+		}
+		return rsa.VerifyPSS(k, crypto.SHA256, hash[:], sig, nil)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, sig) {
+			return errors.New("invalid Ed25519 signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+}
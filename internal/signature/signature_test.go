@@ -0,0 +1,152 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/distribution/reference"
+
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+)
+
+// fakeFetcher serves manifests and blobs from memory
+type fakeFetcher struct {
+	manifests map[string][]byte
+	blobs     map[string][]byte
+}
+
+func (f *fakeFetcher) GetManifest(_ context.Context, _ reference.Named, tagOrDigest string) ([]byte, error) {
+	if data, ok := f.manifests[tagOrDigest]; ok {
+		return data, nil
+	}
+	return nil, &registry.StatusError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+}
+
+func (f *fakeFetcher) GetBlob(_ context.Context, _ reference.Named, digest string) ([]byte, error) {
+	if data, ok := f.blobs[digest]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("blob %s not found", digest)
+}
+
+// signedFetcher returns a fetcher serving a cosign signature of signedDigest made with key
+func signedFetcher(t *testing.T, key *ecdsa.PrivateKey, imageDigest, signedDigest string) *fakeFetcher {
+	t.Helper()
+
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"example.com/app"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, signedDigest))
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatalf("SignASN1() error = %v", err)
+	}
+
+	payloadDigest := "sha256:" + hex.EncodeToString(hash[:])
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"layers":[{"digest":%q,"annotations":{%q:%q}}]}`,
+		payloadDigest, signatureAnnotation, base64.StdEncoding.EncodeToString(sig))
+
+	return &fakeFetcher{
+		manifests: map[string][]byte{strings.Replace(imageDigest, ":", "-", 1) + ".sig": []byte(manifest)},
+		blobs:     map[string][]byte{payloadDigest: payload},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	signer, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	named, _ := reference.ParseNormalizedNamed("example.com/app:1.0")
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		name     string
+		fetcher  *fakeFetcher
+		keys     []crypto.PublicKey
+		expected Status
+	}{
+		{
+			name:     "valid signature",
+			fetcher:  signedFetcher(t, signer, digest, digest),
+			keys:     []crypto.PublicKey{&other.PublicKey, &signer.PublicKey},
+			expected: StatusVerified,
+		},
+		{
+			name:     "signed with unknown key",
+			fetcher:  signedFetcher(t, other, digest, digest),
+			keys:     []crypto.PublicKey{&signer.PublicKey},
+			expected: StatusInvalid,
+		},
+		{
+			name:     "signature for another digest",
+			fetcher:  signedFetcher(t, signer, digest, "sha256:"+strings.Repeat("b", 64)),
+			keys:     []crypto.PublicKey{&signer.PublicKey},
+			expected: StatusInvalid,
+		},
+		{
+			name:     "unsigned image",
+			fetcher:  &fakeFetcher{},
+			keys:     []crypto.PublicKey{&signer.PublicKey},
+			expected: StatusUnsigned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := Verify(context.Background(), tt.fetcher, named, digest, tt.keys)
+			if status != tt.expected {
+				t.Errorf("Verify() = %v (error %v), want %v", status, err, tt.expected)
+			}
+		})
+	}
+}
+
+// robotLogin is a credential store holding one login for every registry
+type robotLogin struct{}
+
+func (robotLogin) Credential(context.Context, string) (registry.Credential, error) {
+	return registry.Credential{Username: "robot", Password: "secret"}, nil
+}
+
+func TestVerifyPrivateRegistry(t *testing.T) {
+	signer, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	digest := "sha256:" + strings.Repeat("a", 64)
+	fetcher := signedFetcher(t, signer, digest, digest)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if username, password, ok := req.BasicAuth(); !ok || username != "robot" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="harbor"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, name := path.Split(req.URL.Path)
+		data, ok := fetcher.manifests[name]
+		if !ok {
+			data, ok = fetcher.blobs[name]
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	named, _ := reference.ParseNormalizedNamed(host + "/app:1.0")
+	client := registry.NewClient(registry.Options{Credentials: robotLogin{}, Insecure: []string{host}})
+
+	status, err := Verify(context.Background(), client, named, digest, []crypto.PublicKey{&signer.PublicKey})
+	if status != StatusVerified || err != nil {
+		t.Errorf("Verify() = %s, %v; want verified", status, err)
+	}
+}
@@ -0,0 +1,199 @@
+package sources
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// ComposeOptions controls how a compose file is interpreted
+type ComposeOptions struct {
+	Profiles []string          // Active profiles
+	Env      map[string]string // Variables from the env file, overridden by the process environment
+}
+
+// composeFile is the subset of the compose specification needed to find images
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Image    string   `yaml:"image"`
+	Profiles []string `yaml:"profiles"`
+}
+
+var (
+	variableRegex     = regexp.MustCompile(`\$(?:\$|\{([^}]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+	variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ParseCompose extracts image references from the services of a compose file.
+// Services disabled by profiles and services that are only built are skipped.
+func ParseCompose(data []byte, source string, opts ComposeOptions) ([]types.ImageSpec, error) {
+	var file composeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	names := make([]string, 0, len(file.Services))
+	for name := range file.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var specs []types.ImageSpec
+	for _, name := range names {
+		service := file.Services[name]
+		if !profileEnabled(service.Profiles, opts.Profiles) {
+			continue
+		}
+		if service.Image == "" {
+			// Build-only service, nothing to pull
+			continue
+		}
+
+		image, err := interpolate(service.Image, opts.Env)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+		specs = append(specs, types.ImageSpec{
+			Name:   image,
+			Source: fmt.Sprintf("%s: service %s", source, name),
+		})
+	}
+
+	return specs, nil
+}
+
+// profileEnabled reports whether a service with the given profiles is enabled
+func profileEnabled(serviceProfiles, activeProfiles []string) bool {
+	if len(serviceProfiles) == 0 {
+		return true
+	}
+	for _, profile := range serviceProfiles {
+		for _, active := range activeProfiles {
+			if profile == active || active == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupVariable resolves a variable from the process environment, then the env file
+func lookupVariable(name string, env map[string]string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := env[name]
+	return value, ok
+}
+
+// interpolate expands $VAR, ${VAR} and the ${VAR:-default}, ${VAR-default},
+// ${VAR:?error}, ${VAR?error}, ${VAR:+alt} and ${VAR+alt} forms; "$$" is a literal "$".
+// Nested forms such as ${VAR:-${OTHER}} are rejected.
+func interpolate(value string, env map[string]string) (string, error) {
+	var firstErr error
+	result := variableRegex.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		groups := variableRegex.FindStringSubmatch(match)
+		if groups[2] != "" {
+			resolved, _ := lookupVariable(groups[2], env)
+			return resolved
+		}
+
+		expr := groups[1]
+		end := strings.IndexAny(expr, ":-?+")
+		if end < 0 {
+			if !variableNameRegex.MatchString(expr) {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("unsupported interpolation %q", match)
+				}
+				return ""
+			}
+			resolved, _ := lookupVariable(expr, env)
+			return resolved
+		}
+
+		name, modifier := expr[:end], expr[end:]
+		if !variableNameRegex.MatchString(name) || strings.Contains(modifier, "$") {
+			// Nested interpolation such as ${A:-${B}} is not supported
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unsupported interpolation %q", match)
+			}
+			return ""
+		}
+		resolved, set := lookupVariable(name, env)
+		emptyIsUnset := strings.HasPrefix(modifier, ":")
+		modifier = strings.TrimPrefix(modifier, ":")
+		if modifier == "" {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid interpolation %q", match)
+			}
+			return ""
+		}
+		operator, argument := modifier[0], modifier[1:]
+		missing := !set || (emptyIsUnset && resolved == "")
+
+		switch operator {
+		case '-':
+			if missing {
+				return argument
+			}
+		case '?':
+			if missing && firstErr == nil {
+				firstErr = fmt.Errorf("required variable %s is missing: %s", name, argument)
+			}
+		case '+':
+			if missing {
+				return ""
+			}
+			return argument
+		default:
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid interpolation %q", match)
+			}
+		}
+		return resolved
+	})
+
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return result, nil
+}
+
+// ParseEnvFile parses KEY=VALUE lines of a compose env file
+func ParseEnvFile(data []byte) (map[string]string, error) {
+	env := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid env file line %d", i+1)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if comment := strings.Index(value, " #"); comment >= 0 {
+			value = strings.TrimSpace(value[:comment])
+		}
+		env[key] = value
+	}
+	return env, nil
+}
@@ -0,0 +1,147 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("DPP_TEST_FROM_ENV", "process")
+	env := map[string]string{
+		"TAG":               "1.27",
+		"EMPTY":             "",
+		"DPP_TEST_FROM_ENV": "file",
+	}
+
+	tests := []struct {
+		value     string
+		expected  string
+		wantError bool
+	}{
+		{value: "nginx:$TAG", expected: "nginx:1.27"},
+		{value: "nginx:${TAG}", expected: "nginx:1.27"},
+		{value: "app:$DPP_TEST_FROM_ENV", expected: "app:process"},
+		{value: "nginx:$UNSET", expected: "nginx:"},
+		{value: "nginx:${UNSET:-stable}", expected: "nginx:stable"},
+		{value: "nginx:${EMPTY:-stable}", expected: "nginx:stable"},
+		{value: "nginx:${TAG:-stable}", expected: "nginx:1.27"},
+		{value: "nginx:${UNSET-stable}", expected: "nginx:stable"},
+		{value: "nginx${EMPTY-:stable}", expected: "nginx"},
+		{value: "nginx:${TAG:?tag is required}", expected: "nginx:1.27"},
+		{value: "nginx:${UNSET:?tag is required}", wantError: true},
+		{value: "nginx:${EMPTY:?tag is required}", wantError: true},
+		{value: "nginx:${EMPTY?tag is required}", expected: "nginx:"},
+		{value: "nginx:${UNSET?tag is required}", wantError: true},
+		{value: "nginx${TAG:+:pinned}", expected: "nginx:pinned"},
+		{value: "nginx${EMPTY:+:pinned}", expected: "nginx"},
+		{value: "nginx${EMPTY+:pinned}", expected: "nginx:pinned"},
+		{value: "nginx${UNSET+:pinned}", expected: "nginx"},
+		{value: "price$$TAG", expected: "price$TAG"},
+		{value: "nginx:${TAG:}", wantError: true},
+		{value: "nginx:${TAG:=x}", wantError: true},
+		{value: "nginx:${UNSET:-${TAG}}", wantError: true},
+		{value: "nginx:${UNSET:-$TAG}", wantError: true},
+		{value: "nginx:${}", wantError: true},
+		{value: "nginx:${NOT VALID}", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := interpolate(tt.value, env)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("interpolate() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.expected {
+				t.Errorf("interpolate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	content := `# comment
+TAG=1.27
+export REGISTRY = ghcr.io
+QUOTED="value # not a comment"
+SINGLE='single'
+TRAILING=value # comment
+EMPTY=
+
+`
+	expected := map[string]string{
+		"TAG":      "1.27",
+		"REGISTRY": "ghcr.io",
+		"QUOTED":   "value # not a comment",
+		"SINGLE":   "single",
+		"TRAILING": "value",
+		"EMPTY":    "",
+	}
+
+	env, err := ParseEnvFile([]byte(content))
+	if err != nil {
+		t.Fatalf("ParseEnvFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("ParseEnvFile() = %v, want %v", env, expected)
+	}
+
+	for _, invalid := range []string{"NOVALUE\n", "=value\n"} {
+		if _, err := ParseEnvFile([]byte(invalid)); err == nil {
+			t.Errorf("ParseEnvFile(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestParseCompose(t *testing.T) {
+	content := `services:
+  web:
+    image: nginx:${TAG:-stable}
+  api:
+    build: ./api
+  worker:
+    build: ./worker
+    image: registry.local/worker:1
+  debug:
+    image: busybox
+    profiles: [debug]
+  metrics:
+    image: prom/prometheus
+    profiles: [monitoring, debug]
+`
+
+	tests := []struct {
+		name     string
+		profiles []string
+		expected []string
+	}{
+		{name: "no active profiles", expected: []string{"nginx:stable", "registry.local/worker:1"}},
+		{name: "one profile", profiles: []string{"monitoring"}, expected: []string{"prom/prometheus", "nginx:stable", "registry.local/worker:1"}},
+		{name: "profile shared by services", profiles: []string{"debug"}, expected: []string{"busybox", "prom/prometheus", "nginx:stable", "registry.local/worker:1"}},
+		{name: "all profiles", profiles: []string{"*"}, expected: []string{"busybox", "prom/prometheus", "nginx:stable", "registry.local/worker:1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := ParseCompose([]byte(content), "compose.yaml", ComposeOptions{Profiles: tt.profiles})
+			if err != nil {
+				t.Fatalf("ParseCompose() error = %v", err)
+			}
+			var images []string
+			for _, spec := range specs {
+				images = append(images, spec.Name)
+			}
+			if !reflect.DeepEqual(images, tt.expected) {
+				t.Errorf("ParseCompose() = %v, want %v", images, tt.expected)
+			}
+		})
+	}
+
+	specs, err := ParseCompose([]byte(content), "compose.yaml", ComposeOptions{})
+	if err != nil || specs[0].Source != "compose.yaml: service web" {
+		t.Errorf("ParseCompose() source = %+v, %v", specs, err)
+	}
+
+	if _, err := ParseCompose([]byte("services:\n  web:\n    image: nginx:${TAG:?set TAG}\n"), "compose.yaml", ComposeOptions{}); err == nil {
+		t.Error("ParseCompose() with a missing required variable succeeded")
+	}
+}
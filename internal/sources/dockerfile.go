@@ -0,0 +1,153 @@
+package sources
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+var argRegex = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)(?::?-([^}]*))?\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// IsDockerfile reports whether a file name looks like a Dockerfile
+// (Dockerfile, Dockerfile.<suffix> or <prefix>.Dockerfile)
+func IsDockerfile(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	return base == "dockerfile" || strings.HasPrefix(base, "dockerfile.") || strings.HasSuffix(base, ".dockerfile")
+}
+
+// ParseDockerfile extracts the base images of a Dockerfile: every FROM image and
+// every COPY --from image. References to earlier build stages and scratch are ignored,
+// and ARG defaults declared before the first FROM are substituted.
+func ParseDockerfile(data []byte, source string) ([]types.ImageSpec, error) {
+	globalArgs := make(map[string]string)
+	stages := make(map[string]bool)
+	stageCount := 0
+
+	var specs []types.ImageSpec
+	addImage := func(image string, line int, what string) error {
+		image = expandArgs(image, globalArgs)
+		if image == "" || strings.Contains(image, "$") {
+			return fmt.Errorf("line %d: cannot resolve image reference in %s", line, what)
+		}
+		if strings.EqualFold(image, "scratch") || stages[strings.ToLower(image)] {
+			return nil
+		}
+		specs = append(specs, types.ImageSpec{
+			Name:   image,
+			Source: fmt.Sprintf("%s:%d (%s)", source, line, what),
+		})
+		return nil
+	}
+
+	for _, instruction := range logicalLines(string(data)) {
+		fields := strings.Fields(instruction.text)
+		if len(fields) == 0 {
+			continue
+		}
+		keyword, args := strings.ToUpper(fields[0]), fields[1:]
+
+		switch keyword {
+		case "ARG":
+			if stageCount > 0 {
+				continue // Stage-local ARGs cannot be used in FROM
+			}
+			for _, arg := range args {
+				name, value, _ := strings.Cut(arg, "=")
+				globalArgs[name] = strings.Trim(value, `"'`)
+			}
+		case "FROM":
+			args = withoutFlags(args)
+			if len(args) == 0 {
+				return nil, fmt.Errorf("line %d: FROM without image", instruction.line)
+			}
+			if err := addImage(args[0], instruction.line, "FROM"); err != nil {
+				return nil, err
+			}
+			if len(args) >= 3 && strings.EqualFold(args[1], "AS") {
+				stages[strings.ToLower(args[2])] = true
+			}
+			stageCount++
+		case "COPY":
+			for _, arg := range args {
+				from, ok := strings.CutPrefix(arg, "--from=")
+				if !ok {
+					continue
+				}
+				if _, err := strconv.Atoi(from); err == nil {
+					continue // Stage index
+				}
+				if err := addImage(from, instruction.line, "COPY --from"); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return specs, nil
+}
+
+// dockerfileLine is an instruction with its continuation lines joined
+type dockerfileLine struct {
+	text string
+	line int // Line number where the instruction starts
+}
+
+// logicalLines joins backslash continuations and drops comments and parser directives
+func logicalLines(content string) []dockerfileLine {
+	var lines []dockerfileLine
+	var current strings.Builder
+	start := 0
+
+	for i, raw := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if current.Len() == 0 {
+			start = i + 1
+		}
+
+		if continued, ok := strings.CutSuffix(trimmed, "\\"); ok {
+			current.WriteString(continued)
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(trimmed)
+		if text := strings.TrimSpace(current.String()); text != "" {
+			lines = append(lines, dockerfileLine{text: text, line: start})
+		}
+		current.Reset()
+	}
+	if text := strings.TrimSpace(current.String()); text != "" {
+		lines = append(lines, dockerfileLine{text: text, line: start})
+	}
+
+	return lines
+}
+
+// withoutFlags removes leading --flag=value arguments such as --platform
+func withoutFlags(args []string) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		args = args[1:]
+	}
+	return args
+}
+
+// expandArgs substitutes $NAME, ${NAME} and ${NAME:-default} using ARG defaults
+func expandArgs(value string, args map[string]string) string {
+	return argRegex.ReplaceAllStringFunc(value, func(match string) string {
+		groups := argRegex.FindStringSubmatch(match)
+		name := groups[1] + groups[3]
+		if resolved, ok := args[name]; ok && resolved != "" {
+			return resolved
+		}
+		if strings.Contains(match, "-") {
+			return groups[2]
+		}
+		return match
+	})
+}
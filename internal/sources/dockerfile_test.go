@@ -0,0 +1,68 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDockerfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expected  []string
+		wantError bool
+	}{
+		{
+			name: "multi-stage build ignores earlier stages",
+			content: `# syntax=docker/dockerfile:1
+FROM --platform=$BUILDPLATFORM golang:1.24 AS build
+RUN go build ./...
+FROM build AS test
+FROM gcr.io/distroless/static:nonroot
+COPY --from=build /out /app
+COPY --from=0 /out /app
+`,
+			expected: []string{"golang:1.24", "gcr.io/distroless/static:nonroot"},
+		},
+		{
+			name: "global ARG defaults are substituted",
+			content: `ARG BASE=alpine
+ARG VERSION="3.20"
+FROM ${BASE}:${VERSION}
+ARG VERSION=9.9
+FROM debian:${DEBIAN:-bookworm}-slim
+`,
+			expected: []string{"alpine:3.20", "debian:bookworm-slim"},
+		},
+		{
+			name: "COPY --from image and line continuation",
+			content: `FROM scratch
+COPY \
+  --from=nginx:stable \
+  /etc/nginx /etc/nginx
+`,
+			expected: []string{"nginx:stable"},
+		},
+		{
+			name:      "unresolved ARG",
+			content:   "FROM $BASE\n",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := ParseDockerfile([]byte(tt.content), "Dockerfile")
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ParseDockerfile() error = %v, wantError %v", err, tt.wantError)
+			}
+			var images []string
+			for _, spec := range specs {
+				images = append(images, spec.Name)
+			}
+			if !reflect.DeepEqual(images, tt.expected) {
+				t.Errorf("ParseDockerfile() = %v, want %v", images, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package sources
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// podSpecPaths maps workload kinds to the location of their pod spec
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"PodTemplate":           {"template", "spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// containerFields lists the pod spec fields holding containers and how they are reported
+var containerFields = []struct {
+	field string
+	label string
+}{
+	{"initContainers", "initContainer"},
+	{"containers", "container"},
+	{"ephemeralContainers", "ephemeralContainer"},
+}
+
+// kubernetesObject is the subset of a Kubernetes object needed to find images
+type kubernetesObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Items []yaml.Node `yaml:"items"` // Set for kind: List
+}
+
+// ParseKubernetes extracts container images from the workloads of a (multi-document)
+// Kubernetes manifest, such as kubectl or Helm-rendered output
+func ParseKubernetes(data []byte, source string) ([]types.ImageSpec, error) {
+	var specs []types.ImageSpec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return specs, nil
+			}
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+
+		found, err := objectImages(&document, source)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, found...)
+	}
+}
+
+// objectImages returns the images of a single object, descending into List items
+func objectImages(node *yaml.Node, source string) ([]types.ImageSpec, error) {
+	var object kubernetesObject
+	if err := node.Decode(&object); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest object: %w", err)
+	}
+	var raw map[string]interface{}
+	if err := node.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest object: %w", err)
+	}
+
+	if object.Kind == "List" || len(object.Items) > 0 {
+		var specs []types.ImageSpec
+		for i := range object.Items {
+			found, err := objectImages(&object.Items[i], source)
+			if err != nil {
+				return nil, err
+			}
+			specs = append(specs, found...)
+		}
+		return specs, nil
+	}
+
+	path, ok := podSpecPaths[object.Kind]
+	if !ok {
+		return nil, nil
+	}
+	podSpec, ok := lookup(raw, path)
+	if !ok {
+		return nil, nil
+	}
+
+	name := object.Metadata.Name
+	if object.Metadata.Namespace != "" {
+		name = object.Metadata.Namespace + "/" + name
+	}
+
+	var specs []types.ImageSpec
+	for _, field := range containerFields {
+		containers, _ := podSpec[field.field].([]interface{})
+		for _, item := range containers {
+			container, _ := item.(map[string]interface{})
+			image, _ := container["image"].(string)
+			if image == "" {
+				continue
+			}
+			containerName, _ := container["name"].(string)
+			specs = append(specs, types.ImageSpec{
+				Name:   image,
+				Source: fmt.Sprintf("%s: %s %s (%s %s)", source, object.Kind, name, field.label, containerName),
+			})
+		}
+	}
+	return specs, nil
+}
+
+// lookup walks nested mappings along path
+func lookup(object map[string]interface{}, path []string) (map[string]interface{}, bool) {
+	current := object
+	for _, key := range path {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
@@ -0,0 +1,173 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKubernetes(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expected  []string
+		sources   []string
+		wantError bool
+	}{
+		{
+			name: "multi-document manifest",
+			content: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  image: not-an-image
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: migrate
+          image: app/migrate:1
+      containers:
+        - name: web
+          image: nginx:1.27
+        - name: sidecar
+          image: envoyproxy/envoy:v1.30
+---
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+    - name: main
+      image: busybox
+  ephemeralContainers:
+    - name: shell
+      image: alpine:3.20
+`,
+			expected: []string{"app/migrate:1", "nginx:1.27", "envoyproxy/envoy:v1.30", "busybox", "alpine:3.20"},
+			sources: []string{
+				"k8s.yaml: Deployment prod/web (initContainer migrate)",
+				"k8s.yaml: Deployment prod/web (container web)",
+				"k8s.yaml: Deployment prod/web (container sidecar)",
+				"k8s.yaml: Pod debug (container main)",
+				"k8s.yaml: Pod debug (ephemeralContainer shell)",
+			},
+		},
+		{
+			name: "List of workloads",
+			content: `apiVersion: v1
+kind: List
+items:
+  - apiVersion: apps/v1
+    kind: StatefulSet
+    metadata:
+      name: db
+    spec:
+      template:
+        spec:
+          containers:
+            - name: postgres
+              image: postgres:16
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: db
+`,
+			expected: []string{"postgres:16"},
+			sources:  []string{"k8s.yaml: StatefulSet db (container postgres)"},
+		},
+		{
+			name: "CronJob",
+			content: `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  schedule: "0 3 * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+            - name: wait
+              image: busybox:1.36
+          containers:
+            - name: backup
+              image: restic/restic:0.16
+`,
+			expected: []string{"busybox:1.36", "restic/restic:0.16"},
+			sources: []string{
+				"k8s.yaml: CronJob backup (initContainer wait)",
+				"k8s.yaml: CronJob backup (container backup)",
+			},
+		},
+		{
+			name: "workload without images",
+			content: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: empty
+spec:
+  replicas: 0
+`,
+		},
+		{
+			name:      "invalid YAML",
+			content:   "apiVersion: v1\nkind: Pod\nspec: [unterminated\n",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := ParseKubernetes([]byte(tt.content), "k8s.yaml")
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ParseKubernetes() error = %v, wantError %v", err, tt.wantError)
+			}
+			var images, sources []string
+			for _, spec := range specs {
+				images = append(images, spec.Name)
+				sources = append(sources, spec.Source)
+			}
+			if !reflect.DeepEqual(images, tt.expected) {
+				t.Errorf("ParseKubernetes() = %v, want %v", images, tt.expected)
+			}
+			if !reflect.DeepEqual(sources, tt.sources) {
+				t.Errorf("ParseKubernetes() sources = %v, want %v", sources, tt.sources)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected Kind
+	}{
+		{name: "image list", content: "images:\n  - name: nginx\n", expected: KindImageList},
+		{name: "compose", content: "services:\n  web:\n    image: nginx\n", expected: KindCompose},
+		{name: "kubernetes", content: "apiVersion: v1\nkind: Pod\n", expected: KindKubernetes},
+		{name: "kubernetes after empty documents", content: "---\n# rendered by helm\n---\napiVersion: v1\nkind: List\nitems: []\n", expected: KindKubernetes},
+		{name: "kind without apiVersion", content: "kind: Pod\n", expected: KindUnknown},
+		{name: "unrelated YAML", content: "name: value\n", expected: KindUnknown},
+		{name: "not a mapping", content: "- nginx\n", expected: KindUnknown},
+		{name: "empty", content: "", expected: KindUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect([]byte(tt.content)); got != tt.expected {
+				t.Errorf("Detect() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
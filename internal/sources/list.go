@@ -0,0 +1,84 @@
+package sources
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// ParseTextList parses a newline-delimited image list. Blank lines are skipped and
+// "#" starts a comment, either on its own line or after whitespace.
+func ParseTextList(data []byte, source string) []types.ImageSpec {
+	var specs []types.ImageSpec
+	for i, line := range strings.Split(string(data), "\n") {
+		if comment := strings.Index(line, "#"); comment == 0 || (comment > 0 && (line[comment-1] == ' ' || line[comment-1] == '\t')) {
+			line = line[:comment]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		specs = append(specs, types.ImageSpec{
+			Name:   line,
+			Source: fmt.Sprintf("%s:%d", source, i+1),
+		})
+	}
+	return specs
+}
+
+// jsonImageEntry is an image entry of a JSON image list
+type jsonImageEntry struct {
+	Name     string `json:"name"`
+	Tags     string `json:"tags"`
+	TagRegex string `json:"tag_regex"`
+	LatestN  int    `json:"latest_n"`
+}
+
+// ParseJSONList parses a JSON array whose items are image names or
+// image entries with the same fields as the YAML image list
+func ParseJSONList(data []byte, source string) ([]types.ImageSpec, error) {
+	var items []json.RawMessage
+	if err := decodeJSON(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON image list: %w", err)
+	}
+
+	specs := make([]types.ImageSpec, 0, len(items))
+	for i, item := range items {
+		var name string
+		if err := json.Unmarshal(item, &name); err == nil {
+			specs = append(specs, types.ImageSpec{Name: name, Source: source})
+			continue
+		}
+
+		var entry jsonImageEntry
+		if err := decodeJSON(item, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON image list item %d: %w", i, err)
+		}
+		specs = append(specs, types.ImageSpec{
+			Name:     entry.Name,
+			Tags:     entry.Tags,
+			TagRegex: entry.TagRegex,
+			LatestN:  entry.LatestN,
+			Source:   source,
+		})
+	}
+	return specs, nil
+}
+
+// decodeJSON decodes a single JSON value, rejecting unknown fields and trailing data
+func decodeJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestParseTextList(t *testing.T) {
+	content := `# base images
+nginx:1.27
+  redis:7   # cache
+
+ghcr.io/org/app#not-a-comment
+	# indented comment
+alpine:3.20	# tab before comment`
+
+	expected := []types.ImageSpec{
+		{Name: "nginx:1.27", Source: "images.txt:2"},
+		{Name: "redis:7", Source: "images.txt:3"},
+		{Name: "ghcr.io/org/app#not-a-comment", Source: "images.txt:5"},
+		{Name: "alpine:3.20", Source: "images.txt:7"},
+	}
+	if got := ParseTextList([]byte(content), "images.txt"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseTextList() = %+v, want %+v", got, expected)
+	}
+	if got := ParseTextList([]byte("\n# only comments\n"), "images.txt"); len(got) != 0 {
+		t.Errorf("ParseTextList() = %+v, want none", got)
+	}
+}
+
+func TestParseJSONList(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expected  []types.ImageSpec
+		wantError bool
+	}{
+		{
+			name:    "names and entries",
+			content: `["nginx:1.27", {"name": "ghcr.io/org/app", "tags": ">=1.2", "tag_regex": "^v", "latest_n": 2}]`,
+			expected: []types.ImageSpec{
+				{Name: "nginx:1.27", Source: "images.json"},
+				{Name: "ghcr.io/org/app", Tags: ">=1.2", TagRegex: "^v", LatestN: 2, Source: "images.json"},
+			},
+		},
+		{name: "empty array", content: `[]`, expected: []types.ImageSpec{}},
+		{name: "unknown field", content: `[{"name": "nginx", "tag": "1.27"}]`, wantError: true},
+		{name: "source is not settable", content: `[{"name": "nginx", "Source": "elsewhere"}]`, wantError: true},
+		{name: "wrong item type", content: `[42]`, wantError: true},
+		{name: "wrong field type", content: `[{"name": "nginx", "latest_n": "2"}]`, wantError: true},
+		{name: "not an array", content: `{"images": ["nginx"]}`, wantError: true},
+		{name: "YAML is not JSON", content: "- nginx\n", wantError: true},
+		{name: "trailing data", content: `["nginx"] ["redis"]`, wantError: true},
+		{name: "unterminated", content: `["nginx"`, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJSONList([]byte(tt.content), "images.json")
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ParseJSONList() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseJSONList() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		expected Kind
+	}{
+		{name: "Dockerfile", filename: "build/Dockerfile", content: "FROM alpine\n", expected: KindDockerfile},
+		{name: "suffixed Dockerfile", filename: "Dockerfile.dev", content: "FROM alpine\n", expected: KindDockerfile},
+		{name: "text extension", filename: "images.txt", content: "nginx\n", expected: KindText},
+		{name: "list extension", filename: "images.list", content: "[not json]\n", expected: KindText},
+		{name: "JSON extension", filename: "images.JSON", content: `["nginx"]`, expected: KindJSON},
+		{name: "YAML image list", filename: "containers.yaml", content: "images:\n  - nginx\n", expected: KindImageList},
+		{name: "YAML compose", filename: "compose.yml", content: "services: {}\n", expected: KindCompose},
+		{name: "YAML manifest", filename: "deploy.yaml", content: "apiVersion: v1\nkind: Pod\n", expected: KindKubernetes},
+		{name: "unrecognized YAML", filename: "values.yaml", content: "replicas: 3\n", expected: KindUnknown},
+		{name: "stdin JSON", filename: "stdin", content: "  [\"nginx\"]", expected: KindJSON},
+		{name: "stdin manifest", filename: "stdin", content: "apiVersion: v1\nkind: Pod\n", expected: KindKubernetes},
+		{name: "stdin text", filename: "stdin", content: "nginx\nredis:7\n", expected: KindText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFile(tt.filename, []byte(tt.content)); got != tt.expected {
+				t.Errorf("DetectFile() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
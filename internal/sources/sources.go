@@ -0,0 +1,75 @@
+package sources
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Kind identifies the format of an image source file
+type Kind int
+
+const (
+	KindUnknown    Kind = iota // Unrecognized content
+	KindImageList              // containers.yaml style list
+	KindCompose                // docker-compose / compose spec file
+	KindKubernetes             // Kubernetes manifests, possibly multi-document
+	KindDockerfile             // Dockerfile
+	KindText                   // Newline-delimited image list
+	KindJSON                   // JSON array of images
+)
+
+// DetectFile determines the format of a source from its file name, falling back
+// to its content for YAML files and for names without a known extension (e.g. stdin)
+func DetectFile(name string, data []byte) Kind {
+	if IsDockerfile(name) {
+		return KindDockerfile
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".txt", ".list":
+		return KindText
+	case ".json":
+		return KindJSON
+	case ".yaml", ".yml":
+		return Detect(data)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return KindJSON
+	}
+	if kind := Detect(data); kind != KindUnknown {
+		return kind
+	}
+	return KindText
+}
+
+// Detect determines the format of a YAML source file from the top-level keys
+// of its first non-empty document
+func Detect(data []byte) Kind {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document map[string]yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			return KindUnknown
+		}
+		if len(document) == 0 {
+			continue
+		}
+
+		if _, ok := document["images"]; ok {
+			return KindImageList
+		}
+		if _, ok := document["services"]; ok {
+			return KindCompose
+		}
+		_, hasAPIVersion := document["apiVersion"]
+		_, hasKind := document["kind"]
+		if hasAPIVersion && hasKind {
+			return KindKubernetes
+		}
+		return KindUnknown
+	}
+}
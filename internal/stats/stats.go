@@ -0,0 +1,189 @@
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/registry"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// Percentile returns the nearest-rank percentile (0-100] of values sorted in ascending order
+func Percentile[T cmp.Ordered](sorted []T, p float64) T {
+	if len(sorted) == 0 {
+		var zero T
+		return zero
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// Summarize returns the percentiles, maximum and mean of a set of durations
+func Summarize(durations []time.Duration) types.DurationStats {
+	if len(durations) == 0 {
+		return types.DurationStats{}
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return types.DurationStats{
+		P50:  Percentile(sorted, 50),
+		P90:  Percentile(sorted, 90),
+		P95:  Percentile(sorted, 95),
+		P99:  Percentile(sorted, 99),
+		Max:  sorted[len(sorted)-1],
+		Mean: total / time.Duration(len(sorted)),
+	}
+}
+
+// Group computes the statistics of a set of pull results. Pull time percentiles
+// only cover attempted pulls, so images rejected before pulling do not skew them.
+func Group(results []types.PullResult) types.GroupMetrics {
+	var group types.GroupMetrics
+	var durations []time.Duration
+	var start, end time.Time
+
+	for _, result := range results {
+		group.Images++
+		if result.Success {
+			group.SuccessCount++
+			group.TotalBytes += result.ImageSize
+		} else {
+			group.FailureCount++
+		}
+		group.Retries += max(result.Attempts-1, 0)
+		group.CumulativeDuration += result.Duration
+		if Attempted(result) {
+			durations = append(durations, result.Duration)
+		}
+
+		if !result.StartedAt.IsZero() && (start.IsZero() || result.StartedAt.Before(start)) {
+			start = result.StartedAt
+		}
+		if result.FinishedAt.After(end) {
+			end = result.FinishedAt
+		}
+	}
+
+	group.Durations = Summarize(durations)
+	if !start.IsZero() && end.After(start) {
+		group.WallDuration = end.Sub(start)
+		group.Throughput = Throughput(group.TotalBytes, group.WallDuration)
+	}
+	return group
+}
+
+// Attempted reports whether an image was pulled at all; images denied by the policy
+// or with an invalid reference fail before a pull starts
+func Attempted(result types.PullResult) bool {
+	return result.Attempts > 0 && result.ErrorCategory != types.ErrorCategoryInvalid
+}
+
+// Registry returns the registry API host an image was pulled from, or "unknown"
+func Registry(result types.PullResult) string {
+	image := result.CanonicalImage
+	if image == "" {
+		image = result.Image
+	}
+	if host := registry.ImageHost(image); host != "" {
+		return host
+	}
+	return "unknown"
+}
+
+// Throughput returns bytes per second over a duration, or zero for an empty duration
+func Throughput(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) / d.Seconds()
+}
+
+// ConfidenceLevels are the supported levels of Estimate confidence intervals
+var ConfidenceLevels = []float64{0.90, 0.95, 0.99}
+
+// tTable holds two-sided Student's t critical values for 1 to 30 degrees of
+// freedom and the normal critical value used for the expansion beyond
+var tTable = map[float64]struct {
+	t []float64
+	z float64
+}{
+	0.90: {z: 1.6449, t: []float64{
+		6.314, 2.920, 2.353, 2.132, 2.015, 1.943, 1.895, 1.860, 1.833, 1.812,
+		1.796, 1.782, 1.771, 1.761, 1.753, 1.746, 1.740, 1.734, 1.729, 1.725,
+		1.721, 1.717, 1.714, 1.711, 1.708, 1.706, 1.703, 1.701, 1.699, 1.697,
+	}},
+	0.95: {z: 1.9600, t: []float64{
+		12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+		2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+		2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+	}},
+	0.99: {z: 2.5758, t: []float64{
+		63.657, 9.925, 5.841, 4.604, 4.032, 3.707, 3.499, 3.355, 3.250, 3.169,
+		3.106, 3.055, 3.012, 2.977, 2.947, 2.921, 2.898, 2.878, 2.861, 2.845,
+		2.831, 2.819, 2.807, 2.797, 2.787, 2.779, 2.771, 2.763, 2.756, 2.750,
+	}},
+}
+
+// TCritical returns the two-sided Student's t critical value of a confidence
+// level. Beyond the table it uses the Cornish-Fisher expansion of the normal
+// critical value, which is accurate to three decimals there.
+func TCritical(confidence float64, df int) (float64, error) {
+	row, ok := tTable[confidence]
+	if !ok {
+		return 0, fmt.Errorf("unsupported confidence level %v, supported: %v", confidence, ConfidenceLevels)
+	}
+	if df < 1 {
+		return 0, fmt.Errorf("degrees of freedom must be positive, got: %d", df)
+	}
+	if df <= len(row.t) {
+		return row.t[df-1], nil
+	}
+	z, n := row.z, float64(df)
+	return z + (math.Pow(z, 3)+z)/(4*n) + (5*math.Pow(z, 5)+16*math.Pow(z, 3)+3*z)/(96*n*n), nil
+}
+
+// Estimate returns the mean, sample standard deviation, range and confidence
+// interval of the mean of repeated measurements
+func Estimate(durations []time.Duration, confidence float64) (types.Estimate, error) {
+	estimate := types.Estimate{Samples: len(durations)}
+	if len(durations) == 0 {
+		return estimate, nil
+	}
+
+	var sum float64
+	estimate.Min, estimate.Max = durations[0], durations[0]
+	for _, d := range durations {
+		sum += float64(d)
+		estimate.Min = min(estimate.Min, d)
+		estimate.Max = max(estimate.Max, d)
+	}
+	mean := sum / float64(len(durations))
+	estimate.Mean = time.Duration(math.Round(mean))
+	if len(durations) < 2 {
+		return estimate, nil
+	}
+
+	var squares float64
+	for _, d := range durations {
+		squares += (float64(d) - mean) * (float64(d) - mean)
+	}
+	stddev := math.Sqrt(squares / float64(len(durations)-1))
+	t, err := TCritical(confidence, len(durations)-1)
+	if err != nil {
+		return types.Estimate{}, err
+	}
+	margin := t * stddev / math.Sqrt(float64(len(durations)))
+
+	estimate.StdDev = time.Duration(math.Round(stddev))
+	estimate.Low = max(time.Duration(math.Round(mean-margin)), 0) // Pull times are never negative
+	estimate.High = time.Duration(math.Round(mean + margin))
+	return estimate, nil
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func TestSummarize(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Second)
+	}
+
+	got := Summarize(durations)
+	want := types.DurationStats{
+		P50: 50 * time.Second, P90: 90 * time.Second, P95: 95 * time.Second,
+		P99: 99 * time.Second, Max: 100 * time.Second, Mean: 50500 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("Summarize() = %+v, want %+v", got, want)
+	}
+	if durations[0] != 100*time.Second {
+		t.Error("Summarize() reordered its input")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{name: "empty", sorted: nil, p: 50, want: 0},
+		{name: "single", sorted: []time.Duration{3}, p: 99, want: 3},
+		{name: "median of four", sorted: []time.Duration{1, 2, 3, 4}, p: 50, want: 2},
+		{name: "p90 of four", sorted: []time.Duration{1, 2, 3, 4}, p: 90, want: 4},
+		{name: "p99 of ten", sorted: []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, p: 99, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("Percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	results := []types.PullResult{
+		{Success: true, Attempts: 1, Duration: 2 * time.Second, ImageSize: 30_000_000,
+			StartedAt: start, FinishedAt: start.Add(2 * time.Second)},
+		{Success: true, Attempts: 3, Duration: 4 * time.Second, ImageSize: 10_000_000,
+			StartedAt: start.Add(time.Second), FinishedAt: start.Add(5 * time.Second)},
+		{Success: false, Attempts: 4, Duration: time.Second, ImageSize: 99,
+			StartedAt: start, FinishedAt: start.Add(time.Second)},
+		// Rejected before pulling, so left out of the percentiles
+		{Success: false, ErrorCategory: types.ErrorCategoryPolicy, StartedAt: start, FinishedAt: start},
+		{Success: false, Attempts: 1, ErrorCategory: types.ErrorCategoryInvalid, StartedAt: start, FinishedAt: start},
+	}
+
+	group := Group(results)
+	if group.Images != 5 || group.SuccessCount != 2 || group.FailureCount != 3 || group.Retries != 5 {
+		t.Errorf("Group() counts = %+v", group)
+	}
+	if group.WallDuration != 5*time.Second || group.CumulativeDuration != 7*time.Second {
+		t.Errorf("Group() durations = wall %v, cumulative %v", group.WallDuration, group.CumulativeDuration)
+	}
+	if group.TotalBytes != 40_000_000 || group.Throughput != 8_000_000 {
+		t.Errorf("Group() bytes = %d, throughput = %v", group.TotalBytes, group.Throughput)
+	}
+	if group.Durations.P50 != 2*time.Second || group.Durations.Max != 4*time.Second || group.Durations.Mean != 7*time.Second/3 {
+		t.Errorf("Group() percentiles = %+v", group.Durations)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	durations := []time.Duration{10 * time.Second, 12 * time.Second, 14 * time.Second, 12 * time.Second, 12 * time.Second}
+
+	got, err := Estimate(durations, 0.95)
+	if err != nil {
+		t.Fatalf("Estimate() error = %v", err)
+	}
+	// Sample variance 2s², t(0.95, 4) = 2.776, margin = 2.776 * sqrt(2) / sqrt(5)
+	margin := time.Duration(2.776 * math.Sqrt(2.0/5) * float64(time.Second))
+	want := types.Estimate{
+		Samples: 5, Mean: 12 * time.Second, StdDev: time.Duration(math.Sqrt(2) * float64(time.Second)),
+		Min: 10 * time.Second, Max: 14 * time.Second, Low: 12*time.Second - margin, High: 12*time.Second + margin,
+	}
+	for name, pair := range map[string][2]time.Duration{
+		"mean": {got.Mean, want.Mean}, "stddev": {got.StdDev, want.StdDev}, "min": {got.Min, want.Min},
+		"max": {got.Max, want.Max}, "low": {got.Low, want.Low}, "high": {got.High, want.High},
+	} {
+		if diff := pair[0] - pair[1]; diff < -time.Microsecond || diff > time.Microsecond {
+			t.Errorf("Estimate() %s = %v, want %v", name, pair[0], pair[1])
+		}
+	}
+
+	single, err := Estimate(durations[:1], 0.95)
+	if err != nil || single != (types.Estimate{Samples: 1, Mean: 10 * time.Second, Min: 10 * time.Second, Max: 10 * time.Second}) {
+		t.Errorf("Estimate() of one sample = %+v, %v", single, err)
+	}
+
+	noisy, _ := Estimate([]time.Duration{time.Second, 30 * time.Second}, 0.99)
+	if noisy.Low != 0 {
+		t.Errorf("Estimate() low = %v, want clamped to 0", noisy.Low)
+	}
+
+	if _, err := Estimate(durations, 0.5); err == nil {
+		t.Error("Estimate() accepted an unsupported confidence level")
+	}
+}
+
+func TestTCritical(t *testing.T) {
+	tests := []struct {
+		confidence float64
+		df         int
+		want       float64
+	}{
+		{confidence: 0.95, df: 1, want: 12.706},
+		{confidence: 0.95, df: 30, want: 2.042},
+		{confidence: 0.95, df: 40, want: 2.021},
+		{confidence: 0.95, df: 120, want: 1.980},
+		{confidence: 0.99, df: 60, want: 2.660},
+		{confidence: 0.90, df: 1000, want: 1.646},
+	}
+	for _, tt := range tests {
+		got, err := TCritical(tt.confidence, tt.df)
+		if err != nil || math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("TCritical(%v, %d) = %.4f, %v; want %.3f", tt.confidence, tt.df, got, err, tt.want)
+		}
+	}
+	if _, err := TCritical(0.95, 0); err == nil {
+		t.Error("TCritical() accepted zero degrees of freedom")
+	}
+}
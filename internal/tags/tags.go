@@ -0,0 +1,188 @@
+package tags
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// version is a numeric tag version such as 1.25.3, with an optional variant suffix (e.g. "alpine")
+type version struct {
+	parts  [3]int
+	suffix string
+}
+
+var versionRegex = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:[-_+](.+))?$`)
+
+// parseVersion parses a tag into a version; missing minor/patch components default to 0
+func parseVersion(tag string) (version, bool) {
+	m := versionRegex.FindStringSubmatch(tag)
+	if m == nil {
+		return version{}, false
+	}
+	var v version
+	for i := 0; i < 3; i++ {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return version{}, false
+		}
+		v.parts[i] = n
+	}
+	v.suffix = m[4]
+	return v, true
+}
+
+// compare returns -1, 0 or 1 comparing the numeric parts of two versions
+func (v version) compare(other version) int {
+	for i := range v.parts {
+		if v.parts[i] != other.parts[i] {
+			if v.parts[i] < other.parts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// comparator is a single "<op><version>" term of a constraint
+type comparator struct {
+	op      string
+	version version
+}
+
+// Constraint is a version range such as ">=1.25 <1.27 || >=2.0".
+// Terms separated by whitespace must all match; "||" separates alternatives.
+type Constraint struct {
+	alternatives [][]comparator
+}
+
+// ParseConstraint parses a version range expression
+func ParseConstraint(expr string) (*Constraint, error) {
+	c := &Constraint{}
+	for _, alternative := range strings.Split(expr, "||") {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty version range in %q", expr)
+		}
+
+		var terms []comparator
+		for _, field := range fields {
+			op := "="
+			for _, candidate := range []string{">=", "<=", "!=", ">", "<", "="} {
+				if strings.HasPrefix(field, candidate) {
+					op = candidate
+					break
+				}
+			}
+			v, ok := parseVersion(strings.TrimPrefix(field, op))
+			if !ok || v.suffix != "" {
+				return nil, fmt.Errorf("invalid version %q in range %q", field, expr)
+			}
+			terms = append(terms, comparator{op: op, version: v})
+		}
+		c.alternatives = append(c.alternatives, terms)
+	}
+	return c, nil
+}
+
+// matches reports whether v satisfies the constraint
+func (c *Constraint) matches(v version) bool {
+	for _, terms := range c.alternatives {
+		ok := true
+		for _, term := range terms {
+			cmp := v.compare(term.version)
+			switch term.op {
+			case ">=":
+				ok = cmp >= 0
+			case "<=":
+				ok = cmp <= 0
+			case ">":
+				ok = cmp > 0
+			case "<":
+				ok = cmp < 0
+			case "!=":
+				ok = cmp != 0
+			default:
+				ok = cmp == 0
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter describes how to select tags from a repository's tag list
+type Filter struct {
+	Range   *Constraint    // Optional version range
+	Regex   *regexp.Regexp // Optional tag pattern
+	LatestN int            // Keep only the N highest versions (0 keeps all)
+}
+
+// Select returns the tags matching the filter, highest version first.
+// Without a regex only plain version tags (no variant suffix) are considered
+// for ranges and latest_n; with a regex the variant suffix is allowed.
+func (f Filter) Select(available []string) []string {
+	type candidate struct {
+		tag       string
+		version   version
+		versioned bool
+	}
+
+	var selected []candidate
+	for _, tag := range available {
+		if f.Regex != nil && !f.Regex.MatchString(tag) {
+			continue
+		}
+
+		v, versioned := parseVersion(tag)
+		if f.Regex == nil && v.suffix != "" {
+			versioned = false
+		}
+
+		if (f.Range != nil || f.Regex == nil) && !versioned {
+			continue
+		}
+		if f.Range != nil && !f.Range.matches(v) {
+			continue
+		}
+		selected = append(selected, candidate{tag: tag, version: v, versioned: versioned})
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		a, b := selected[i], selected[j]
+		if a.versioned != b.versioned {
+			return a.versioned
+		}
+		if a.versioned {
+			if cmp := a.version.compare(b.version); cmp != 0 {
+				return cmp > 0
+			}
+			if (a.version.suffix == "") != (b.version.suffix == "") {
+				return a.version.suffix == ""
+			}
+			return a.tag < b.tag
+		}
+		return a.tag > b.tag
+	})
+
+	if f.LatestN > 0 && len(selected) > f.LatestN {
+		selected = selected[:f.LatestN]
+	}
+
+	result := make([]string, 0, len(selected))
+	for _, c := range selected {
+		result = append(result, c.tag)
+	}
+	return result
+}
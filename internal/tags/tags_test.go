@@ -0,0 +1,68 @@
+package tags
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestFilterSelect(t *testing.T) {
+	available := []string{"latest", "1.24", "1.25", "1.25.3", "1.26.1", "1.26.1-alpine", "1.27", "3.18-alpine", "3.19-alpine", "3.19", "stable"}
+
+	mustRange := func(expr string) *Constraint {
+		c, err := ParseConstraint(expr)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q) error = %v", expr, err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{
+			name:     "version range excludes variants",
+			filter:   Filter{Range: mustRange(">=1.25 <1.27")},
+			expected: []string{"1.26.1", "1.25.3", "1.25"},
+		},
+		{
+			name:     "range alternatives",
+			filter:   Filter{Range: mustRange("1.24 || >=3")},
+			expected: []string{"3.19", "1.24"},
+		},
+		{
+			name:     "regex allows variants",
+			filter:   Filter{Regex: regexp.MustCompile(`^3\.[0-9]+-alpine$`)},
+			expected: []string{"3.19-alpine", "3.18-alpine"},
+		},
+		{
+			name:     "latest n",
+			filter:   Filter{LatestN: 2},
+			expected: []string{"3.19", "1.27"},
+		},
+		{
+			name:     "range with regex and latest n",
+			filter:   Filter{Range: mustRange(">=1.25"), Regex: regexp.MustCompile(`^1\.`), LatestN: 3},
+			expected: []string{"1.27", "1.26.1", "1.26.1-alpine"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.filter.Select(available)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Select() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, expr := range []string{"", ">=abc", "1.2 ||", ">=1.2-alpine"} {
+		if _, err := ParseConstraint(expr); err == nil {
+			t.Errorf("ParseConstraint(%q) expected error", expr)
+		}
+	}
+}
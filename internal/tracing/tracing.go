@@ -0,0 +1,299 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/stats"
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+// scopeName identifies the instrumentation that produced the spans
+const scopeName = "github.com/guessi/docker-parallel-pull"
+
+// tracesPath is appended to the collector endpoint, as for OTEL_EXPORTER_OTLP_ENDPOINT
+const tracesPath = "/v1/traces"
+
+// Span kinds and status codes of the OTLP protocol
+const (
+	kindInternal    = 1
+	statusOK        = 1
+	statusError     = 2
+	maxResponseSize = 64 * 1024
+)
+
+// Options configure the OTLP/HTTP exporter
+type Options struct {
+	Endpoint    string // Collector base URL, e.g. http://localhost:4318
+	ServiceName string
+	Timeout     time.Duration
+}
+
+// Run is a finished pull run to be exported as a trace
+type Run struct {
+	Start, End time.Time
+	Metrics    types.PullMetrics
+	Results    []types.PullResult
+}
+
+// Trace is the span tree of one run
+type Trace struct {
+	ID    string
+	Spans []Span
+}
+
+// Span is a span in the OTLP JSON encoding
+type Span struct {
+	TraceID      string     `json:"traceId"`
+	SpanID       string     `json:"spanId"`
+	ParentSpanID string     `json:"parentSpanId,omitempty"`
+	Name         string     `json:"name"`
+	Kind         int        `json:"kind"`
+	Start        string     `json:"startTimeUnixNano"`
+	End          string     `json:"endTimeUnixNano"`
+	Attributes   []KeyValue `json:"attributes,omitempty"`
+	Status       Status     `json:"status"`
+}
+
+// KeyValue is a span or resource attribute
+type KeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// Status is the outcome of a span
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// exportRequest is an OTLP ExportTraceServiceRequest
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+// NewTrace builds the trace of a run: a root span for the run with a child per
+// image, which in turn has a child per pull attempt with a child per layer
+func NewTrace(run Run) *Trace {
+	trace := &Trace{ID: newID(16)}
+
+	root := trace.span("", "pull run", run.Start, run.End, []KeyValue{
+		intAttr("images.total", int64(run.Metrics.TotalImages)),
+		intAttr("images.succeeded", int64(run.Metrics.SuccessCount)),
+		intAttr("images.failed", int64(run.Metrics.FailureCount)),
+		intAttr("pull.retries", int64(run.Metrics.TotalRetries)),
+		intAttr("pull.concurrency", int64(run.Metrics.Concurrency)),
+		intAttr("pull.bytes", run.Metrics.TotalBytes),
+	}, failureStatus(run.Metrics.FailureCount > 0, fmt.Sprintf("%d images failed", run.Metrics.FailureCount)))
+
+	for _, result := range run.Results {
+		trace.addImage(root, result, run)
+	}
+	return trace
+}
+
+// addImage adds the spans of one image below the run span
+func (t *Trace) addImage(parent string, result types.PullResult, run Run) {
+	start, end := result.StartedAt, result.FinishedAt
+	if start.IsZero() || end.IsZero() {
+		start, end = run.Start, run.End
+	}
+
+	attributes := []KeyValue{
+		stringAttr("image.name", security.SanitizeLogMessage(result.Image)),
+		stringAttr("registry.host", stats.Registry(result)),
+		intAttr("pull.attempts", int64(result.Attempts)),
+	}
+	if result.CanonicalImage != "" {
+		attributes = append(attributes, stringAttr("image.canonical", security.SanitizeLogMessage(result.CanonicalImage)))
+	}
+	if result.Digest != "" {
+		attributes = append(attributes, stringAttr("image.digest", result.Digest))
+	}
+	if result.ImageSize > 0 {
+		attributes = append(attributes, intAttr("image.bytes", result.ImageSize))
+	}
+	if result.Worker > 0 {
+		attributes = append(attributes, intAttr("pull.worker", int64(result.Worker)))
+	}
+	if !result.Success {
+		category := result.ErrorCategory
+		if category == "" {
+			category = types.ErrorCategoryUnknown
+		}
+		attributes = append(attributes, stringAttr("error.category", string(category)))
+	}
+
+	image := t.span(parent, "pull "+security.SanitizeLogMessage(result.Image), start, end, attributes,
+		failureStatus(!result.Success, result.Error))
+
+	for _, attempt := range result.Timeline {
+		attemptSpan := t.span(image, fmt.Sprintf("attempt %d", attempt.Number), attempt.StartedAt, attempt.FinishedAt, []KeyValue{
+			intAttr("pull.attempt", int64(attempt.Number)),
+			intAttr("pull.layers", int64(len(attempt.Layers))),
+		}, failureStatus(attempt.Error != "", attempt.Error))
+
+		for _, layer := range attempt.Layers {
+			layerEnd := layer.FinishedAt
+			if layerEnd.IsZero() {
+				layerEnd = attempt.FinishedAt
+			}
+			t.span(attemptSpan, "layer "+layer.ID, layer.StartedAt, layerEnd, []KeyValue{
+				stringAttr("layer.id", layer.ID),
+				intAttr("layer.bytes", layer.Bytes),
+				boolAttr("layer.cached", layer.Cached),
+				boolAttr("layer.complete", !layer.FinishedAt.IsZero()),
+			}, Status{})
+		}
+	}
+
+	// Digest resolution, policy, signature, vulnerability and SBOM steps after the last attempt
+	if n := len(result.Timeline); n > 0 && end.After(result.Timeline[n-1].FinishedAt) {
+		pulled := result.Timeline[n-1].Error == ""
+		t.span(image, "post-pull checks", result.Timeline[n-1].FinishedAt, end, nil,
+			failureStatus(pulled && !result.Success, result.Error))
+	}
+}
+
+// span appends a span and returns its ID
+func (t *Trace) span(parent, name string, start, end time.Time, attributes []KeyValue, status Status) string {
+	id := newID(8)
+	t.Spans = append(t.Spans, Span{
+		TraceID:      t.ID,
+		SpanID:       id,
+		ParentSpanID: parent,
+		Name:         name,
+		Kind:         kindInternal,
+		Start:        strconv.FormatInt(start.UnixNano(), 10),
+		End:          strconv.FormatInt(end.UnixNano(), 10),
+		Attributes:   attributes,
+		Status:       status,
+	})
+	return id
+}
+
+// Export sends a trace to an OTLP/HTTP collector using the JSON encoding
+func Export(ctx context.Context, options Options, trace *Trace) error {
+	endpoint, err := TracesURL(options.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	resourceAttributes := []KeyValue{stringAttr("service.name", options.ServiceName)}
+	if hostname, err := os.Hostname(); err == nil {
+		resourceAttributes = append(resourceAttributes, stringAttr("host.name", hostname))
+	}
+	request := exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: resourceAttributes},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: scopeName}, Spans: trace.Spans}},
+	}}}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode trace: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create trace export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export trace: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	// A collector may accept the request but reject some of its spans
+	var response struct {
+		PartialSuccess struct {
+			RejectedSpans json.Number `json:"rejectedSpans"`
+			ErrorMessage  string      `json:"errorMessage"`
+		} `json:"partialSuccess"`
+	}
+	if json.Unmarshal(data, &response) == nil {
+		if rejected, _ := response.PartialSuccess.RejectedSpans.Int64(); rejected > 0 {
+			return fmt.Errorf("collector rejected %d spans: %s", rejected, response.PartialSuccess.ErrorMessage)
+		}
+	}
+	return nil
+}
+
+// TracesURL returns the traces endpoint of a collector base URL
+func TracesURL(base string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid collector endpoint: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + tracesPath
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// failureStatus returns an error status with a message if failed, or an OK status
+func failureStatus(failed bool, message string) Status {
+	if failed {
+		return Status{Code: statusError, Message: message}
+	}
+	return Status{Code: statusOK}
+}
+
+// newID returns a random trace or span ID of n bytes, hex encoded
+func newID(n int) string {
+	id := make([]byte, n)
+	if _, err := rand.Read(id); err != nil {
+		panic(fmt.Sprintf("cannot generate trace ID: %v", err))
+	}
+	return hex.EncodeToString(id)
+}
+
+func stringAttr(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: map[string]any{"stringValue": value}}
+}
+
+// intAttr encodes an integer attribute; 64-bit integers are strings in OTLP JSON
+func intAttr(key string, value int64) KeyValue {
+	return KeyValue{Key: key, Value: map[string]any{"intValue": strconv.FormatInt(value, 10)}}
+}
+
+func boolAttr(key string, value bool) KeyValue {
+	return KeyValue{Key: key, Value: map[string]any{"boolValue": value}}
+}
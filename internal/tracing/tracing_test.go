@@ -0,0 +1,224 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guessi/docker-parallel-pull/internal/types"
+)
+
+func testRun() Run {
+	start := time.Unix(1714564800, 0)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	return Run{
+		Start: start,
+		End:   at(30),
+		Metrics: types.PullMetrics{
+			TotalImages: 2, SuccessCount: 1, FailureCount: 1, TotalRetries: 1, Concurrency: 2,
+		},
+		Results: []types.PullResult{
+			{
+				Image: "nginx:latest", CanonicalImage: "docker.io/library/nginx:latest", Success: true,
+				Attempts: 2, Digest: "sha256:abc", ImageSize: 60_000_000, Worker: 1,
+				StartedAt: at(0), FinishedAt: at(20),
+				Timeline: []types.Attempt{
+					{Number: 1, StartedAt: at(0), FinishedAt: at(5), Error: "connection reset",
+						Layers: []types.Layer{{ID: "aaa", StartedAt: at(1), Bytes: 100}}},
+					{Number: 2, StartedAt: at(7), FinishedAt: at(18), Layers: []types.Layer{
+						{ID: "aaa", StartedAt: at(7), FinishedAt: at(15), Bytes: 1000},
+						{ID: "bbb", StartedAt: at(7), FinishedAt: at(7), Cached: true},
+					}},
+				},
+			},
+			{
+				Image: "ghcr.io/org/app:1", Attempts: 1, Worker: 2, Error: "not found",
+				ErrorCategory: types.ErrorCategoryNotFound, StartedAt: at(0), FinishedAt: at(2),
+				Timeline: []types.Attempt{{Number: 1, StartedAt: at(0), FinishedAt: at(2), Error: "not found"}},
+			},
+		},
+	}
+}
+
+func attribute(span Span, key string) any {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			for _, v := range kv.Value {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func TestNewTrace(t *testing.T) {
+	trace := NewTrace(testRun())
+
+	if len(trace.ID) != 32 {
+		t.Fatalf("trace ID %q is not 16 bytes hex encoded", trace.ID)
+	}
+	spans := make(map[string]Span)
+	byName := make(map[string]Span)
+	for _, span := range trace.Spans {
+		if span.TraceID != trace.ID || len(span.SpanID) != 16 {
+			t.Errorf("span %q has trace ID %q and span ID %q", span.Name, span.TraceID, span.SpanID)
+		}
+		spans[span.SpanID] = span
+		byName[span.Name] = span
+	}
+	// 1 run + 2 images + 3 attempts + 3 layers + 1 post-pull checks
+	if len(trace.Spans) != 10 {
+		t.Fatalf("NewTrace() created %d spans, want 10", len(trace.Spans))
+	}
+
+	parent := func(name string) string {
+		span, ok := byName[name]
+		if !ok {
+			t.Fatalf("missing span %q", name)
+		}
+		return spans[span.ParentSpanID].Name
+	}
+	for child, want := range map[string]string{
+		"pull nginx:latest":      "pull run",
+		"pull ghcr.io/org/app:1": "pull run",
+		"post-pull checks":       "pull nginx:latest",
+		"layer bbb":              "attempt 2",
+	} {
+		if got := parent(child); got != want {
+			t.Errorf("parent of %q = %q, want %q", child, got, want)
+		}
+	}
+	if byName["pull run"].ParentSpanID != "" {
+		t.Error("run span has a parent")
+	}
+
+	run := byName["pull run"]
+	if run.Start != "1714564800000000000" || run.End != "1714564830000000000" {
+		t.Errorf("run span = %s..%s", run.Start, run.End)
+	}
+	if run.Status.Code != statusError {
+		t.Errorf("run span status = %+v, want error", run.Status)
+	}
+
+	image := byName["pull nginx:latest"]
+	for key, want := range map[string]any{
+		"registry.host":   "registry-1.docker.io",
+		"image.canonical": "docker.io/library/nginx:latest",
+		"image.digest":    "sha256:abc",
+		"image.bytes":     "60000000",
+		"pull.attempts":   "2",
+	} {
+		if got := attribute(image, key); got != want {
+			t.Errorf("image attribute %s = %v, want %v", key, got, want)
+		}
+	}
+	if attribute(image, "error.category") != nil || image.Status.Code != statusOK {
+		t.Errorf("successful image span has status %+v", image.Status)
+	}
+
+	failed := byName["pull ghcr.io/org/app:1"]
+	if attribute(failed, "error.category") != "not_found" || failed.Status != (Status{Code: statusError, Message: "not found"}) {
+		t.Errorf("failed image span = %+v", failed)
+	}
+
+	var retried, interrupted Span
+	for _, span := range trace.Spans {
+		if span.Name == "attempt 1" && spans[span.ParentSpanID].Name == "pull nginx:latest" {
+			retried = span
+		}
+		if span.Name == "layer aaa" && spans[span.ParentSpanID].SpanID == retried.SpanID {
+			interrupted = span
+		}
+	}
+	if retried.Status.Message != "connection reset" {
+		t.Errorf("failed attempt status = %+v", retried.Status)
+	}
+	if interrupted.End != retried.End || attribute(interrupted, "layer.complete") != false {
+		t.Errorf("interrupted layer = %+v, want to end with its attempt", interrupted)
+	}
+	if attribute(byName["layer bbb"], "layer.cached") != true {
+		t.Error("cached layer not marked as cached")
+	}
+}
+
+func TestTracesURL(t *testing.T) {
+	tests := []struct {
+		base, want string
+	}{
+		{base: "http://localhost:4318", want: "http://localhost:4318/v1/traces"},
+		{base: "http://localhost:4318/", want: "http://localhost:4318/v1/traces"},
+		{base: "https://collector.example.com/otlp", want: "https://collector.example.com/otlp/v1/traces"},
+	}
+	for _, tt := range tests {
+		got, err := TracesURL(tt.base)
+		if err != nil || got != tt.want {
+			t.Errorf("TracesURL(%q) = %q, %v; want %q", tt.base, got, err, tt.want)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	var received exportRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("collector could not decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer collector.Close()
+
+	trace := NewTrace(testRun())
+	options := Options{Endpoint: collector.URL, ServiceName: "pull-bench", Timeout: 5 * time.Second}
+	if err := Export(context.Background(), options, trace); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(received.ResourceSpans) != 1 || len(received.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("collector received %+v", received)
+	}
+	resource := received.ResourceSpans[0].Resource
+	if len(resource.Attributes) == 0 || resource.Attributes[0].Key != "service.name" || resource.Attributes[0].Value["stringValue"] != "pull-bench" {
+		t.Errorf("resource attributes = %+v", resource.Attributes)
+	}
+	if got := len(received.ResourceSpans[0].ScopeSpans[0].Spans); got != len(trace.Spans) {
+		t.Errorf("collector received %d spans, want %d", got, len(trace.Spans))
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "rejected request", status: http.StatusBadRequest, body: "bad span", wantErr: "400 Bad Request: bad span"},
+		{name: "partial success", status: http.StatusOK, body: `{"partialSuccess":{"rejectedSpans":"2","errorMessage":"too old"}}`, wantErr: "rejected 2 spans: too old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer collector.Close()
+
+			err := Export(context.Background(), Options{Endpoint: collector.URL, Timeout: time.Second}, NewTrace(testRun()))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Export() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,30 +1,268 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
 
 // PullResult contains the result of a single image pull operation
 type PullResult struct {
-	Image     string        `json:"image"`
-	Success   bool          `json:"success"`
-	Error     string        `json:"error,omitempty"` // String for security (no error details)
-	Duration  time.Duration `json:"duration"`
-	Attempts  int           `json:"attempts"`
-	Size      int64         `json:"size,omitempty"`
-	ImageHash string        `json:"image_hash,omitempty"`
+	Image            string               `json:"image"`             // Reference as written in the image list
+	CanonicalImage   string               `json:"canonical_image"`   // Fully-qualified reference that was pulled
+	Aliases          []string             `json:"aliases,omitempty"` // Other spellings collapsed into this pull
+	Success          bool                 `json:"success"`
+	Error            string               `json:"error,omitempty"` // String for security (no error details)
+	ErrorCategory    ErrorCategory        `json:"error_category,omitempty"`
+	Duration         time.Duration        `json:"duration"`
+	StartedAt        time.Time            `json:"started_at"`  // When the image was picked up by a worker
+	FinishedAt       time.Time            `json:"finished_at"` // When pulling and all verification finished
+	Attempts         int                  `json:"attempts"`
+	Timeline         []Attempt            `json:"timeline,omitempty"` // Start and end of every pull attempt
+	Worker           int                  `json:"worker,omitempty"`   // Worker slot (1..max_concurrency) that handled the image
+	Size             int64                `json:"size,omitempty"`
+	ImageHash        string               `json:"image_hash,omitempty"`
+	ImageSize        int64                `json:"image_size,omitempty"`    // Size of the pulled image as reported by the daemon
+	Digest           string               `json:"digest,omitempty"`        // Resolved manifest digest
+	LockedDigest     string               `json:"locked_digest,omitempty"` // Digest pinned by the lockfile
+	Drifted          bool                 `json:"drifted,omitempty"`       // Tag now resolves to a different digest than locked
+	Sources          []string             `json:"sources,omitempty"`
+	PolicyViolations []string             `json:"policy_violations,omitempty"`
+	Signature        string               `json:"signature,omitempty"`       // Signature verification outcome
+	SignatureError   string               `json:"signature_error,omitempty"` // Why verification did not succeed
+	Vulnerabilities  *VulnerabilityReport `json:"vulnerabilities,omitempty"`
+	SBOM             string               `json:"sbom,omitempty"`           // Path of the SBOM written for the image
+	SBOMUnscanned    []string             `json:"sbom_unscanned,omitempty"` // Package databases whose packages the SBOM cannot list
+	Push             *PushResult          `json:"push,omitempty"`
+}
+
+// Attempt records the timing of one pull attempt
+type Attempt struct {
+	Number     int       `json:"number"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+	Layers     []Layer   `json:"layers,omitempty"` // Layers reported by the daemon's pull progress
+}
+
+// Layer records the download of one image layer during a pull attempt
+type Layer struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"` // Zero if the attempt ended before the layer completed
+	Bytes      int64     `json:"bytes,omitempty"`      // Compressed size reported while downloading
+	Cached     bool      `json:"cached,omitempty"`     // Layer already existed locally
+}
+
+// VulnerabilityReport summarizes the vulnerability gate findings of a pulled image
+type VulnerabilityReport struct {
+	Packages    int            `json:"packages"`              // Installed packages found in the image
+	Counts      map[string]int `json:"counts,omitempty"`      // Findings per severity
+	Blocking    []string       `json:"blocking,omitempty"`    // Findings at or above the fail_on severity
+	Unsupported []string       `json:"unsupported,omitempty"` // Package databases that could not be read
+}
+
+// ErrorCategory groups pull failures by cause for reports
+type ErrorCategory string
+
+const (
+	ErrorCategoryNotFound      ErrorCategory = "not_found"         // Image or tag does not exist
+	ErrorCategoryAuth          ErrorCategory = "auth"              // Missing or insufficient credentials
+	ErrorCategoryRateLimit     ErrorCategory = "rate_limit"        // Registry rejected the request as too many
+	ErrorCategoryTimeout       ErrorCategory = "timeout"           // Pull or registry request timed out
+	ErrorCategoryNetwork       ErrorCategory = "network"           // Daemon or registry unreachable
+	ErrorCategoryInvalid       ErrorCategory = "invalid_reference" // Image reference rejected
+	ErrorCategoryDaemon        ErrorCategory = "daemon"            // Docker daemon reported an internal error
+	ErrorCategoryPolicy        ErrorCategory = "policy"            // Image policy violation
+	ErrorCategorySignature     ErrorCategory = "signature"         // Signature verification failed
+	ErrorCategoryVulnerability ErrorCategory = "vulnerability"     // Vulnerability gate failed
+	ErrorCategoryDrift         ErrorCategory = "drift"             // Tag drifted from the lockfile
+	ErrorCategoryInventory     ErrorCategory = "inventory"         // Package inventory or SBOM could not be produced
+	ErrorCategoryUnknown       ErrorCategory = "unknown"
+)
+
+// PushResult contains the result of retagging and pushing an image to a mirror registry
+type PushResult struct {
+	Target   string        `json:"target"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Attempts int           `json:"attempts"`
 }
 
 // PullMetrics contains overall statistics for the pull operation
 type PullMetrics struct {
-	TotalImages     int           `json:"total_images"`
-	SuccessCount    int           `json:"success_count"`
-	FailureCount    int           `json:"failure_count"`
-	TotalDuration   time.Duration `json:"total_duration"`
-	AverageDuration time.Duration `json:"average_duration"`
-	TotalRetries    int           `json:"total_retries"`
-	Concurrency     int           `json:"concurrency"`
+	TotalImages          int           `json:"total_images"`
+	SuccessCount         int           `json:"success_count"`
+	FailureCount         int           `json:"failure_count"`
+	TotalDuration        time.Duration `json:"total_duration"`      // Wall clock time of the run
+	CumulativeDuration   time.Duration `json:"cumulative_duration"` // Sum of the pull times of all images
+	AverageDuration      time.Duration `json:"average_duration"`
+	Durations            DurationStats `json:"durations"`
+	TotalBytes           int64         `json:"total_bytes"`                 // Size of the pulled images
+	Throughput           float64       `json:"throughput_bytes_per_second"` // TotalBytes over the time from the first pull start to the last finish
+	TotalRetries         int           `json:"total_retries"`
+	Concurrency          int           `json:"concurrency"`
+	DriftedCount         int           `json:"drifted_count,omitempty"`
+	PolicyViolationCount int           `json:"policy_violation_count,omitempty"`
+	UnverifiedCount      int           `json:"unverified_count,omitempty"`
+	PushedCount          int           `json:"pushed_count,omitempty"`
+	PushFailedCount      int           `json:"push_failed_count,omitempty"`
+	VulnerableCount      int           `json:"vulnerable_count,omitempty"` // Images failed by the vulnerability gate
+
+	ByRegistry      map[string]GroupMetrics        `json:"by_registry,omitempty"`       // Keyed by registry API host
+	ByErrorCategory map[ErrorCategory]GroupMetrics `json:"by_error_category,omitempty"` // Failed images only
+}
+
+// DurationStats summarizes the distribution of pull times
+type DurationStats struct {
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+	Mean time.Duration `json:"mean"`
+}
+
+// GroupMetrics are the statistics of the images of one registry or error category
+type GroupMetrics struct {
+	Images             int           `json:"images"`
+	SuccessCount       int           `json:"success_count"`
+	FailureCount       int           `json:"failure_count"`
+	Retries            int           `json:"retries"`
+	WallDuration       time.Duration `json:"wall_duration"`       // From the first start to the last finish in the group
+	CumulativeDuration time.Duration `json:"cumulative_duration"` // Sum of the pull times
+	Durations          DurationStats `json:"durations"`
+	TotalBytes         int64         `json:"total_bytes"`
+	Throughput         float64       `json:"throughput_bytes_per_second"` // TotalBytes over WallDuration
+}
+
+// BenchmarkReport summarizes the pull times of repeated runs of the same images
+type BenchmarkReport struct {
+	Iterations int              `json:"iterations"`
+	Warmup     int              `json:"warmup_iterations,omitempty"` // Iterations run before measuring
+	Cold       bool             `json:"cold"`                        // Images were removed before every iteration
+	Confidence float64          `json:"confidence"`                  // Level of the confidence intervals, e.g. 0.95
+	Total      Estimate         `json:"total"`                       // Wall clock time of an iteration
+	Failures   int              `json:"failures"`                    // Failed pulls over all measured iterations
+	Images     []ImageBenchmark `json:"images"`
+}
+
+// ImageBenchmark summarizes the pull times of one image over all iterations
+type ImageBenchmark struct {
+	Image    string   `json:"image"`
+	Failures int      `json:"failures"`
+	Duration Estimate `json:"duration"` // Successful pulls only
+}
+
+// Estimate describes repeated measurements of a duration. The confidence
+// interval of the mean is zero with fewer than two samples.
+type Estimate struct {
+	Samples int           `json:"samples"`
+	Mean    time.Duration `json:"mean"`
+	StdDev  time.Duration `json:"stddev"` // Sample standard deviation
+	Min     time.Duration `json:"min"`
+	Max     time.Duration `json:"max"`
+	Low     time.Duration `json:"ci_low"`
+	High    time.Duration `json:"ci_high"`
 }
 
 // ImageList represents the structure of the YAML configuration file
 type ImageList struct {
-	Images []string `yaml:"images,omitempty"`
+	Images []ImageSpec `yaml:"images,omitempty"`
+}
+
+// ImageSpec is a single image list entry: either a plain reference or
+// a repository whose tags are expanded from the registry tag list
+type ImageSpec struct {
+	Name     string `yaml:"name"`
+	Tags     string `yaml:"tags,omitempty"`      // Version range, e.g. ">=1.25 <1.27"
+	TagRegex string `yaml:"tag_regex,omitempty"` // Regular expression tags must match
+	LatestN  int    `yaml:"latest_n,omitempty"`  // Keep only the N highest matching versions
+	Source   string `yaml:"-"`                   // Where the entry was read from
+}
+
+// imageSpecFields lists the keys accepted in an image entry mapping
+var imageSpecFields = map[string]bool{"name": true, "tags": true, "tag_regex": true, "latest_n": true}
+
+// UnmarshalYAML accepts both a plain string and a mapping
+func (s *ImageSpec) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		s.Name = value.Value
+		return nil
+	}
+
+	// Decode does not inherit KnownFields from the parent decoder, so unknown keys are rejected here
+	if value.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key := value.Content[i]
+			if !imageSpecFields[key.Value] {
+				return fmt.Errorf("line %d: field %s not found in image entry", key.Line, key.Value)
+			}
+		}
+	}
+
+	type plain ImageSpec
+	var decoded plain
+	if err := value.Decode(&decoded); err != nil {
+		return err
+	}
+	*s = ImageSpec(decoded)
+	return nil
+}
+
+// IsExpansion reports whether the entry requires a registry tag lookup
+func (s ImageSpec) IsExpansion() bool {
+	return s.Tags != "" || s.TagRegex != "" || s.LatestN > 0
+}
+
+// String describes the entry for logs and reports
+func (s ImageSpec) String() string {
+	if !s.IsExpansion() {
+		return s.Name
+	}
+	var filters []string
+	if s.Tags != "" {
+		filters = append(filters, fmt.Sprintf("tags %q", s.Tags))
+	}
+	if s.TagRegex != "" {
+		filters = append(filters, fmt.Sprintf("tag_regex %q", s.TagRegex))
+	}
+	if s.LatestN > 0 {
+		filters = append(filters, fmt.Sprintf("latest_n %d", s.LatestN))
+	}
+	return fmt.Sprintf("%s (%s)", s.Name, strings.Join(filters, ", "))
+}
+
+// ImageRef is a resolved image reference scheduled for pulling
+type ImageRef struct {
+	Name             string   `json:"name"`                        // Reference as first written
+	Canonical        string   `json:"canonical"`                   // Fully-qualified reference, e.g. docker.io/library/nginx:latest
+	Aliases          []string `json:"aliases,omitempty"`           // Other spellings of the same reference
+	LockedDigest     string   `json:"locked_digest,omitempty"`     // Digest to pull in locked mode
+	PolicyViolations []string `json:"policy_violations,omitempty"` // Pre-pull policy violations; the image is not pulled
+	Sources          []string `json:"sources,omitempty"`
+}
+
+// CheckStatus describes how a local image compares to its remote tag
+type CheckStatus string
+
+const (
+	CheckStatusUpToDate CheckStatus = "up-to-date" // Local image matches the remote digest
+	CheckStatusStale    CheckStatus = "stale"      // Remote tag points to a different digest
+	CheckStatusMissing  CheckStatus = "missing"    // Image is not present locally
+	CheckStatusError    CheckStatus = "error"      // Remote digest could not be resolved
+)
+
+// CheckResult contains the result of comparing a local image to its remote tag
+type CheckResult struct {
+	Image          string      `json:"image"`
+	CanonicalImage string      `json:"canonical_image"`
+	Status         CheckStatus `json:"status"`
+	LocalDigest    string      `json:"local_digest,omitempty"`
+	RemoteDigest   string      `json:"remote_digest,omitempty"`
+	Error          string      `json:"error,omitempty"`
+	Sources        []string    `json:"sources,omitempty"`
 }
@@ -0,0 +1,202 @@
+package vuln
+
+import (
+	"strings"
+
+	"github.com/guessi/docker-parallel-pull/internal/inventory"
+)
+
+// CompareVersions compares two package versions using the rules of the
+// ecosystem's package manager. It returns -1, 0 or 1.
+func CompareVersions(ecosystem, a, b string) int {
+	if ecosystem == inventory.EcosystemApk {
+		return compareApk(a, b)
+	}
+	return compareDpkg(a, b)
+}
+
+// compareDpkg compares Debian versions of the form [epoch:]upstream[-revision]
+func compareDpkg(a, b string) int {
+	epochA, upstreamA, revisionA := splitDpkg(a)
+	epochB, upstreamB, revisionB := splitDpkg(b)
+
+	if c := compareNumeric(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := compareDpkgPart(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareDpkgPart(revisionA, revisionB)
+}
+
+// splitDpkg splits a Debian version into epoch, upstream version and revision
+func splitDpkg(version string) (string, string, string) {
+	epoch := "0"
+	if e, rest, ok := strings.Cut(version, ":"); ok {
+		epoch, version = e, rest
+	}
+	revision := ""
+	if i := strings.LastIndex(version, "-"); i >= 0 {
+		version, revision = version[:i], version[i+1:]
+	}
+	return epoch, version, revision
+}
+
+// compareDpkgPart implements the dpkg algorithm: alternating non-digit parts,
+// compared with letters before non-letters and "~" before everything, and
+// digit parts compared numerically
+func compareDpkgPart(a, b string) int {
+	for a != "" || b != "" {
+		var textA, textB string
+		textA, a = splitLeading(a, false)
+		textB, b = splitLeading(b, false)
+		if c := compareDpkgText(textA, textB); c != 0 {
+			return c
+		}
+
+		var numA, numB string
+		numA, a = splitLeading(a, true)
+		numB, b = splitLeading(b, true)
+		if c := compareNumeric(numA, numB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareDpkgText compares the non-digit parts of two Debian versions
+func compareDpkgText(a, b string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var ca, cb int
+		if i < len(a) {
+			ca = dpkgOrder(a[i])
+		}
+		if i < len(b) {
+			cb = dpkgOrder(b[i])
+		}
+		if ca != cb {
+			if ca < cb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// dpkgOrder returns the sort weight of a character in a Debian version.
+// The end of a string weighs 0, so "~" sorts before it.
+func dpkgOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+// apkSuffixes orders the pre- and post-release suffixes of Alpine versions
+var apkSuffixes = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+// compareApk compares Alpine versions of the form 1.2.3[letter][_suffixN...][-rN]
+func compareApk(a, b string) int {
+	versionA, releaseA, _ := strings.Cut(a, "-r")
+	versionB, releaseB, _ := strings.Cut(b, "-r")
+
+	mainA, suffixesA, _ := strings.Cut(versionA, "_")
+	mainB, suffixesB, _ := strings.Cut(versionB, "_")
+
+	if c := compareApkMain(mainA, mainB); c != 0 {
+		return c
+	}
+	if c := compareApkSuffixes(suffixesA, suffixesB); c != 0 {
+		return c
+	}
+	return compareNumeric(releaseA, releaseB)
+}
+
+// compareApkMain compares the dotted numeric part, with an optional trailing letter
+func compareApkMain(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var partA, partB string
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		numA, letterA := splitLeading(partA, true)
+		numB, letterB := splitLeading(partB, true)
+		if c := compareNumeric(numA, numB); c != 0 {
+			return c
+		}
+		if c := strings.Compare(letterA, letterB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareApkSuffixes compares "_"-separated suffixes such as "rc1" or "p2"
+func compareApkSuffixes(a, b string) int {
+	var listA, listB []string
+	if a != "" {
+		listA = strings.Split(a, "_")
+	}
+	if b != "" {
+		listB = strings.Split(b, "_")
+	}
+	for i := 0; i < len(listA) || i < len(listB); i++ {
+		var nameA, nameB, numA, numB string
+		if i < len(listA) {
+			nameA, numA = splitLeading(listA[i], false)
+		}
+		if i < len(listB) {
+			nameB, numB = splitLeading(listB[i], false)
+		}
+		if c := compareInts(apkSuffixes[nameA], apkSuffixes[nameB]); c != 0 {
+			return c
+		}
+		if c := compareNumeric(numA, numB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// splitLeading splits s after its leading run of digits (digits true) or non-digits
+func splitLeading(s string, digits bool) (string, string) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9') == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// compareNumeric compares two digit strings of any length numerically; empty is zero
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return compareInts(len(a), len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+// compareInts returns -1, 0 or 1
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+package vuln
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guessi/docker-parallel-pull/internal/inventory"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+)
+
+// Severities from lowest to highest
+var severities = []string{"unknown", "negligible", "low", "medium", "high", "critical"}
+
+// SeverityRank returns the position of a severity in the ordering, or -1 if it is not known
+func SeverityRank(severity string) int {
+	for i, s := range severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// Vulnerability is a feed entry affecting one package of one ecosystem, optionally
+// limited to one distribution and release since their packages are built separately.
+// Versions from Introduced (inclusive) up to Fixed (exclusive) are affected;
+// an empty Introduced means all earlier versions and an empty Fixed means no fix exists.
+type Vulnerability struct {
+	ID         string `json:"id"`
+	Ecosystem  string `json:"ecosystem"`         // "deb" or "apk"
+	Distro     string `json:"distro,omitempty"`  // os-release ID, e.g. "debian"; empty for every distribution
+	Release    string `json:"release,omitempty"` // os-release VERSION_ID or its prefix, e.g. "12" or "3.19"
+	Package    string `json:"package"`           // Binary or source package name
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+	Severity   string `json:"severity"`
+}
+
+// Feed is a locally stored vulnerability database
+type Feed struct {
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+
+	index map[string][]Vulnerability // Keyed by ecosystem and package
+}
+
+// Finding is a vulnerability affecting an installed package
+type Finding struct {
+	ID       string `json:"id"`
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	Fixed    string `json:"fixed,omitempty"`
+	Severity string `json:"severity"`
+}
+
+// String describes the finding for reports
+func (f Finding) String() string {
+	if f.Fixed == "" {
+		return fmt.Sprintf("%s (%s) in %s %s, no fix available", f.ID, f.Severity, f.Package, f.Version)
+	}
+	return fmt.Sprintf("%s (%s) in %s %s, fixed in %s", f.ID, f.Severity, f.Package, f.Version, f.Fixed)
+}
+
+// LoadFeed reads and validates a vulnerability feed file
+func LoadFeed(filename string) (*Feed, error) {
+	file, _, err := security.SecureOpenFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vulnerability feed: %w", err)
+	}
+	defer file.Close()
+
+	var feed Feed
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields() // Reject unknown fields for security
+	if err := decoder.Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to parse vulnerability feed: %w", err)
+	}
+
+	if err := feed.buildIndex(); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// NewFeed creates a feed from a list of vulnerabilities
+func NewFeed(vulnerabilities []Vulnerability) (*Feed, error) {
+	feed := &Feed{Vulnerabilities: vulnerabilities}
+	if err := feed.buildIndex(); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// buildIndex validates the entries and indexes them by ecosystem and package
+func (f *Feed) buildIndex() error {
+	f.index = make(map[string][]Vulnerability)
+	for i, v := range f.Vulnerabilities {
+		if v.ID == "" || v.Package == "" {
+			return fmt.Errorf("vulnerability %d: id and package are required", i)
+		}
+		if v.Ecosystem != inventory.EcosystemDeb && v.Ecosystem != inventory.EcosystemApk {
+			return fmt.Errorf("vulnerability %s: unsupported ecosystem %q", v.ID, v.Ecosystem)
+		}
+		v.Severity = strings.ToLower(v.Severity)
+		if v.Severity == "" {
+			v.Severity = "unknown"
+		}
+		if SeverityRank(v.Severity) < 0 {
+			return fmt.Errorf("vulnerability %s: unknown severity %q", v.ID, v.Severity)
+		}
+		if v.Release != "" && v.Distro == "" {
+			return fmt.Errorf("vulnerability %s: release requires a distro", v.ID)
+		}
+		key := v.Ecosystem + "/" + v.Package
+		f.index[key] = append(f.index[key], v)
+	}
+	return nil
+}
+
+// Match returns the vulnerabilities affecting the packages of an inventory, most severe first.
+// Entries for another distribution or release than the image's are skipped; when the image
+// has no os-release, entries of every distribution apply.
+func (f *Feed) Match(inv *inventory.Inventory) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+
+	for _, pkg := range inv.Packages {
+		candidates := append([]Vulnerability{}, f.index[pkg.Ecosystem+"/"+pkg.Name]...)
+		if pkg.Source != "" && pkg.Source != pkg.Name {
+			candidates = append(candidates, f.index[pkg.Ecosystem+"/"+pkg.Source]...)
+		}
+
+		for _, v := range candidates {
+			if !appliesTo(v, inv.Distro) {
+				continue
+			}
+			// Source package entries are compared against the source version
+			version := pkg.Version
+			if v.Package == pkg.Source && pkg.SourceVersion != "" {
+				version = pkg.SourceVersion
+			}
+			if !affects(v, pkg.Ecosystem, version) {
+				continue
+			}
+
+			key := v.ID + "/" + pkg.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			findings = append(findings, Finding{
+				ID:       v.ID,
+				Package:  pkg.Name,
+				Version:  pkg.Version,
+				Fixed:    v.Fixed,
+				Severity: v.Severity,
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		ri, rj := SeverityRank(findings[i].Severity), SeverityRank(findings[j].Severity)
+		if ri != rj {
+			return ri > rj
+		}
+		if findings[i].ID != findings[j].ID {
+			return findings[i].ID < findings[j].ID
+		}
+		return findings[i].Package < findings[j].Package
+	})
+	return findings
+}
+
+// appliesTo reports whether a vulnerability entry is for the distribution and release of an image
+func appliesTo(v Vulnerability, distro inventory.Distro) bool {
+	if v.Distro == "" || distro.ID == "" {
+		return true
+	}
+	if v.Distro != distro.ID {
+		return false
+	}
+	return v.Release == "" || distro.VersionID == v.Release || strings.HasPrefix(distro.VersionID, v.Release+".")
+}
+
+// affects reports whether a vulnerability applies to an installed version
+func affects(v Vulnerability, ecosystem, version string) bool {
+	if v.Introduced != "" && CompareVersions(ecosystem, version, v.Introduced) < 0 {
+		return false
+	}
+	if v.Fixed != "" && CompareVersions(ecosystem, version, v.Fixed) >= 0 {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package vuln
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/guessi/docker-parallel-pull/internal/inventory"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		ecosystem string
+		a, b      string
+		want      int
+	}{
+		{inventory.EcosystemDeb, "1.0", "1.0", 0},
+		{inventory.EcosystemDeb, "1.0", "1.1", -1},
+		{inventory.EcosystemDeb, "1.10", "1.9", 1},
+		{inventory.EcosystemDeb, "1.0~rc1", "1.0", -1},
+		{inventory.EcosystemDeb, "1.0", "1.0+deb12u1", -1},
+		{inventory.EcosystemDeb, "1:0.9", "2.0", 1},
+		{inventory.EcosystemDeb, "3.0.11-1~deb12u1", "3.0.11-1", -1},
+		{inventory.EcosystemDeb, "3.0.11-1~deb12u2", "3.0.11-1~deb12u1", 1},
+		{inventory.EcosystemDeb, "2.36-9+deb12u3", "2.36-9+deb12u10", -1},
+		{inventory.EcosystemApk, "1.2.4-r2", "1.2.4-r10", -1},
+		{inventory.EcosystemApk, "1.2.4", "1.2.4-r0", 0},
+		{inventory.EcosystemApk, "1.2.4_rc1-r0", "1.2.4-r0", -1},
+		{inventory.EcosystemApk, "1.2.4_p1-r0", "1.2.4-r0", 1},
+		{inventory.EcosystemApk, "1.2.4a", "1.2.4", 1},
+		{inventory.EcosystemApk, "3.1.10-r0", "3.1.4-r1", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ecosystem+" "+tt.a+" "+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.ecosystem, tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := CompareVersions(tt.ecosystem, tt.b, tt.a); got != -tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	feed, err := NewFeed([]Vulnerability{
+		{ID: "CVE-1", Ecosystem: "deb", Package: "openssl", Fixed: "3.0.13-1~deb12u1", Severity: "HIGH"},
+		{ID: "CVE-2", Ecosystem: "deb", Package: "openssl", Fixed: "3.0.9-1", Severity: "critical"},
+		{ID: "CVE-3", Ecosystem: "deb", Package: "bash", Severity: "low"},
+		{ID: "CVE-4", Ecosystem: "apk", Package: "openssl", Introduced: "3.1.0-r0", Fixed: "3.1.4-r5", Severity: "medium"},
+		{ID: "CVE-5", Ecosystem: "apk", Package: "musl", Introduced: "1.2.5-r0", Severity: "critical"},
+	})
+	if err != nil {
+		t.Fatalf("NewFeed() error = %v", err)
+	}
+
+	inv := &inventory.Inventory{Packages: []inventory.Package{
+		{Name: "libssl3", Version: "3.0.11-1~deb12u1", Source: "openssl", Ecosystem: "deb"},
+		{Name: "bash", Version: "5.2.15-2+b2", Ecosystem: "deb"},
+		{Name: "libcrypto3", Version: "3.1.4-r1", Source: "openssl", Ecosystem: "apk"},
+		{Name: "musl", Version: "1.2.4-r2", Ecosystem: "apk"},
+	}}
+
+	findings := feed.Match(inv)
+	want := []string{"CVE-1", "CVE-4", "CVE-3"}
+	if len(findings) != len(want) {
+		t.Fatalf("Match() = %v, want %v", findings, want)
+	}
+	for i, finding := range findings {
+		if finding.ID != want[i] {
+			t.Errorf("Match()[%d] = %s, want %s", i, finding.ID, want[i])
+		}
+	}
+	if findings[0].Severity != "high" {
+		t.Errorf("Match() severity = %q, want normalized \"high\"", findings[0].Severity)
+	}
+}
+
+func TestNewFeedRejectsInvalidEntries(t *testing.T) {
+	tests := []Vulnerability{
+		{ID: "CVE-1", Ecosystem: "rpm", Package: "openssl"},
+		{ID: "CVE-1", Ecosystem: "deb", Package: "openssl", Severity: "urgent"},
+		{Ecosystem: "deb", Package: "openssl"},
+		{ID: "CVE-1", Ecosystem: "deb", Release: "12", Package: "openssl"},
+	}
+	for _, v := range tests {
+		if _, err := NewFeed([]Vulnerability{v}); err == nil {
+			t.Errorf("NewFeed(%+v) accepted an invalid entry", v)
+		}
+	}
+}
+
+func TestMatchDistroRelease(t *testing.T) {
+	feed, err := NewFeed([]Vulnerability{
+		{ID: "DSA-12", Ecosystem: "deb", Distro: "debian", Release: "12", Package: "openssl", Fixed: "3.0.13-1~deb12u1", Severity: "high"},
+		{ID: "USN-22", Ecosystem: "deb", Distro: "ubuntu", Release: "22.04", Package: "openssl", Fixed: "3.0.2-0ubuntu1.15", Severity: "high"},
+		{ID: "ALPINE-319", Ecosystem: "apk", Distro: "alpine", Release: "3.19", Package: "openssl", Fixed: "3.1.4-r5", Severity: "high"},
+		{ID: "ALPINE-ANY", Ecosystem: "apk", Distro: "alpine", Package: "openssl", Fixed: "3.1.4-r3", Severity: "low"},
+		{ID: "CVE-ANY", Ecosystem: "apk", Package: "openssl", Fixed: "3.1.4-r2", Severity: "low"},
+	})
+	if err != nil {
+		t.Fatalf("NewFeed() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		distro   inventory.Distro
+		packages []inventory.Package
+		expected []string
+	}{
+		{
+			name:     "matching release",
+			distro:   inventory.Distro{ID: "debian", VersionID: "12"},
+			packages: []inventory.Package{{Name: "libssl3", Version: "3.0.11-1~deb12u1", Source: "openssl", Ecosystem: "deb"}},
+			expected: []string{"DSA-12"},
+		},
+		{
+			name:     "other distribution",
+			distro:   inventory.Distro{ID: "ubuntu", VersionID: "24.04"},
+			packages: []inventory.Package{{Name: "libssl3", Version: "3.0.2-0ubuntu1.10", Source: "openssl", Ecosystem: "deb"}},
+		},
+		{
+			name:     "release prefix",
+			distro:   inventory.Distro{ID: "alpine", VersionID: "3.19.1"},
+			packages: []inventory.Package{{Name: "libcrypto3", Version: "3.1.4-r1", Source: "openssl", Ecosystem: "apk"}},
+			expected: []string{"ALPINE-319", "ALPINE-ANY", "CVE-ANY"},
+		},
+		{
+			name:     "release is not a string prefix",
+			distro:   inventory.Distro{ID: "alpine", VersionID: "3.190"},
+			packages: []inventory.Package{{Name: "libcrypto3", Version: "3.1.4-r1", Source: "openssl", Ecosystem: "apk"}},
+			expected: []string{"ALPINE-ANY", "CVE-ANY"},
+		},
+		{
+			name:     "unknown distribution",
+			packages: []inventory.Package{{Name: "libcrypto3", Version: "3.1.4-r1", Source: "openssl", Ecosystem: "apk"}},
+			expected: []string{"ALPINE-319", "ALPINE-ANY", "CVE-ANY"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, finding := range feed.Match(&inventory.Inventory{Distro: tt.distro, Packages: tt.packages}) {
+				got = append(got, finding.ID)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Match() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
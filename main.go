@@ -2,21 +2,53 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"time"
 
+	"github.com/docker/docker/client"
+
+	"github.com/guessi/docker-parallel-pull/internal/audit"
 	"github.com/guessi/docker-parallel-pull/internal/config"
 	"github.com/guessi/docker-parallel-pull/internal/docker"
+	"github.com/guessi/docker-parallel-pull/internal/history"
 	"github.com/guessi/docker-parallel-pull/internal/output"
+	"github.com/guessi/docker-parallel-pull/internal/prometheus"
+	"github.com/guessi/docker-parallel-pull/internal/security"
+	"github.com/guessi/docker-parallel-pull/internal/tracing"
+	"github.com/guessi/docker-parallel-pull/internal/types"
 )
 
 func main() {
-	// Check for config file argument
+	locked := flag.Bool("locked", false, "pull the digests recorded in the lockfile and fail on drift")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [check|benchmark|compare|verify-audit] [config.yaml]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Flags may also follow the command and the config file argument
+	var args []string
+	for remaining := flag.Args(); len(remaining) > 0; remaining = flag.Args() {
+		args = append(args, remaining[0])
+		_ = flag.CommandLine.Parse(remaining[1:]) // Exits on invalid flags
+	}
+
+	// Check for an optional command and config file argument
+	command := "pull"
+	if len(args) > 0 && (args[0] == "check" || args[0] == "benchmark" || args[0] == "compare" || args[0] == "verify-audit") {
+		command = args[0]
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
 	configFile := "config.yaml"
-	if len(os.Args) > 1 {
-		configFile = os.Args[1]
+	if len(args) > 0 {
+		configFile = args[0]
 	}
 
 	// Load configuration from file
@@ -25,32 +57,77 @@ func main() {
 		log.Fatalf("Failed to load config file: %v", err)
 	}
 
+	// Apply the configured redaction rules before anything else is logged
+	redactor, err := finalConfig.Redaction.Redactor()
+	if err != nil {
+		log.Fatalf("Invalid redaction configuration: %v", err)
+	}
+	security.SetRedactor(redactor)
+
+	// Apply the configured path policy before any other file is validated or read
+	if err := security.SetPathPolicy(finalConfig.PathPolicy.Policy()); err != nil {
+		log.Fatalf("Invalid path policy: %v", err)
+	}
+
+	if *locked {
+		finalConfig.Locked = true
+	}
+
 	// Validate configuration
 	if err := finalConfig.Validate(); err != nil {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
+	if command != "pull" || finalConfig.DryRun {
+		if err := finalConfig.ValidateSummaryFormat(); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+	}
+
+	switch command {
+	case "check":
+		os.Exit(runCheck(finalConfig))
+	case "benchmark":
+		os.Exit(runBenchmark(finalConfig))
+	case "compare":
+		os.Exit(runCompare(finalConfig))
+	case "verify-audit":
+		os.Exit(runVerifyAudit(finalConfig))
+	default:
+		os.Exit(runPull(finalConfig))
+	}
+}
 
+// runPull pulls every configured image and returns the process exit code
+func runPull(finalConfig *config.Config) int {
 	// Create context with timeout
 	totalTimeout := finalConfig.Timeout * time.Duration(finalConfig.MaxRetries+1) * 2
+	if finalConfig.Mirror.Enabled {
+		totalTimeout += finalConfig.Mirror.Timeout * time.Duration(finalConfig.Mirror.MaxRetries+1) * 2
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), totalTimeout)
 	defer cancel()
 
-	// Create Docker client
-	cli, err := docker.CreateDockerClient()
-	if err != nil {
-		log.Fatalf("Failed to create Docker client: %v", err)
+	gates := loadGates(finalConfig)
+
+	if finalConfig.DryRun {
+		// Dry runs do not need the daemon, so only the configured insecure registries apply
+		output.OutputImagePlan(loadImages(ctx, gates, finalConfig), finalConfig)
+		return 0
 	}
+
+	cli := connectDocker(ctx, finalConfig)
 	defer cli.Close()
 
-	// Test Docker connection
-	if _, err := cli.Ping(ctx); err != nil {
-		log.Fatalf("Cannot connect to Docker daemon: %v\nPlease ensure Docker is running and accessible.", err)
-	}
+	images := loadImages(ctx, gates, finalConfig)
+	auditLog := openAuditLog(finalConfig)
 
-	// Load container images from file
-	images, err := docker.LoadContainerImages(finalConfig.ContainerFile)
-	if err != nil {
-		log.Fatalf("Failed to load container images: %v", err)
+	// Skip images that are already up to date
+	if finalConfig.SkipUpToDate {
+		images = docker.FilterChanged(ctx, cli, images, finalConfig)
+		if len(images) == 0 {
+			output.SecureLogMessage(finalConfig, "INFO", "All images are up to date, nothing to pull")
+			return finishAudit(auditLog, nil, 0, finalConfig)
+		}
 	}
 
 	output.SecureLogMessage(finalConfig, "INFO",
@@ -59,20 +136,386 @@ func main() {
 
 	// Pull images
 	startTime := time.Now()
-	results := docker.PullImages(ctx, cli, images, finalConfig)
+	results := docker.PullImages(ctx, cli, images, gates, auditPulls(auditLog, finalConfig), finalConfig)
 	totalDuration := time.Since(startTime)
 
+	// Record resolved digests
+	if err := docker.WriteLockfile(results, finalConfig); err != nil {
+		output.SecureLogMessage(finalConfig, "WARN", err.Error())
+	}
+
+	// Push pulled images to the mirror registry if configured
+	docker.PushImages(ctx, cli, results, finalConfig)
+
 	// Calculate and output metrics
 	metrics := output.CalculateMetrics(results, finalConfig, totalDuration)
 	output.OutputResults(metrics, results, finalConfig)
+	exportPrometheus(ctx, metrics, results, finalConfig)
+	exportTrace(ctx, startTime, metrics, results, finalConfig)
+	recordHistory(auditLog, startTime, metrics, results, finalConfig)
 
 	// Cleanup if requested
 	if finalConfig.CleanupAfterTest {
-		docker.CleanupImages(ctx, cli, images, finalConfig)
+		docker.CleanupImages(ctx, cli, append(docker.ImageNames(images), docker.MirrorTargets(results)...), finalConfig)
 	}
 
-	// Exit with error code if any pulls failed
-	if metrics.FailureCount > 0 {
-		os.Exit(1)
+	// Exit with error code if any pulls or pushes failed
+	exitCode := 0
+	if metrics.FailureCount > 0 || metrics.PushFailedCount > 0 {
+		exitCode = 1
 	}
+	return finishAudit(auditLog, results, exitCode, finalConfig)
+}
+
+// runBenchmark pulls every configured image repeatedly, optionally removing the
+// images before each iteration, and returns the process exit code
+func runBenchmark(finalConfig *config.Config) int {
+	benchmark := finalConfig.Benchmark
+	iterationTimeout := finalConfig.Timeout * time.Duration(finalConfig.MaxRetries+1) * 2
+	ctx, cancel := context.WithTimeout(context.Background(), iterationTimeout*time.Duration(benchmark.Warmup+benchmark.Iterations))
+	defer cancel()
+
+	gates := loadGates(finalConfig)
+
+	cli := connectDocker(ctx, finalConfig)
+	defer cli.Close()
+
+	images := loadImages(ctx, gates, finalConfig)
+	auditLog := openAuditLog(finalConfig)
+
+	var allResults []types.PullResult
+	var iterations [][]types.PullResult
+	var durations []time.Duration
+	for i := 1; i <= benchmark.Warmup+benchmark.Iterations; i++ {
+		if benchmark.Cold {
+			docker.CleanupImages(ctx, cli, docker.ImageNames(images), finalConfig)
+		}
+
+		warmup := i <= benchmark.Warmup
+		if warmup {
+			output.SecureLogMessage(finalConfig, "INFO", fmt.Sprintf("Warmup iteration %d/%d", i, benchmark.Warmup))
+		} else {
+			output.SecureLogMessage(finalConfig, "INFO", fmt.Sprintf("Benchmark iteration %d/%d", i-benchmark.Warmup, benchmark.Iterations))
+		}
+
+		startTime := time.Now()
+		onResult := auditPulls(auditLog, finalConfig)
+		if warmup {
+			onResult = auditWarmupPulls(auditLog, finalConfig)
+		}
+		results := docker.PullImages(ctx, cli, images, gates, onResult, finalConfig)
+		duration := time.Since(startTime)
+
+		allResults = append(allResults, results...)
+		if !warmup {
+			iterations = append(iterations, results)
+			durations = append(durations, duration)
+		}
+	}
+
+	report, err := output.CalculateBenchmark(iterations, durations, finalConfig)
+	if err != nil {
+		log.Fatalf("Failed to summarize benchmark: %v", err)
+	}
+	output.OutputBenchmark(report, finalConfig)
+
+	if finalConfig.CleanupAfterTest {
+		docker.CleanupImages(ctx, cli, docker.ImageNames(images), finalConfig)
+	}
+
+	// Exit with error code if any pull of any iteration failed
+	exitCode := 0
+	if report.Failures > 0 {
+		exitCode = 1
+	}
+	return finishAudit(auditLog, allResults, exitCode, finalConfig)
+}
+
+// exportPrometheus writes the run metrics to the configured textfile and Pushgateway.
+// Export failures are logged but do not fail the run.
+func exportPrometheus(ctx context.Context, metrics types.PullMetrics, results []types.PullResult, finalConfig *config.Config) {
+	promConfig := finalConfig.Prometheus
+	if promConfig.Textfile == "" && promConfig.Pushgateway.URL == "" {
+		return
+	}
+
+	data := prometheus.Render(metrics, results, time.Now())
+
+	if promConfig.Textfile != "" {
+		if err := prometheus.WriteTextfile(promConfig.Textfile, data); err != nil {
+			output.SecureLogMessage(finalConfig, "ERROR", err.Error())
+		} else {
+			output.SecureLogMessage(finalConfig, "INFO", fmt.Sprintf("Wrote metrics to %s", promConfig.Textfile))
+		}
+	}
+
+	if gateway := promConfig.Pushgateway; gateway.URL != "" {
+		options := prometheus.PushOptions{
+			URL:      gateway.URL,
+			Job:      gateway.Job,
+			Instance: gateway.Instance,
+			Username: gateway.Username,
+			Timeout:  gateway.Timeout,
+		}
+		if options.Instance == "" {
+			// Keep the metrics of runs on different hosts in separate groups
+			options.Instance, _ = os.Hostname()
+		}
+		if gateway.PasswordEnv != "" {
+			options.Password = os.Getenv(gateway.PasswordEnv)
+		}
+		if gateway.PasswordEnv != "" && options.Password == "" {
+			output.SecureLogMessage(finalConfig, "ERROR", fmt.Sprintf("Not pushing metrics: environment variable %s is empty", gateway.PasswordEnv))
+		} else if err := prometheus.Push(ctx, options, data); err != nil {
+			output.SecureLogMessage(finalConfig, "ERROR", err.Error())
+		} else {
+			output.SecureLogMessage(finalConfig, "INFO", fmt.Sprintf("Pushed metrics to %s", gateway.URL))
+		}
+	}
+}
+
+// exportTrace sends the run to the configured OpenTelemetry collector as a trace.
+// Export failures are logged but do not fail the run.
+func exportTrace(ctx context.Context, startTime time.Time, metrics types.PullMetrics, results []types.PullResult, finalConfig *config.Config) {
+	tracingConfig := finalConfig.Tracing
+	if !tracingConfig.Enabled {
+		return
+	}
+
+	trace := tracing.NewTrace(tracing.Run{
+		Start:   startTime,
+		End:     startTime.Add(metrics.TotalDuration),
+		Metrics: metrics,
+		Results: results,
+	})
+	options := tracing.Options{
+		Endpoint:    tracingConfig.Endpoint,
+		ServiceName: tracingConfig.ServiceName,
+		Timeout:     tracingConfig.Timeout,
+	}
+	if err := tracing.Export(ctx, options, trace); err != nil {
+		output.SecureLogMessage(finalConfig, "ERROR", err.Error())
+		return
+	}
+	output.SecureLogMessage(finalConfig, "INFO", fmt.Sprintf("Exported trace %s with %d spans", trace.ID, len(trace.Spans)))
+}
+
+// recordHistory appends the run to the configured history file, using the audit
+// log's run ID when there is one. Failures are logged but do not fail the run.
+func recordHistory(auditLog *audit.Log, startTime time.Time, metrics types.PullMetrics, results []types.PullResult, finalConfig *config.Config) {
+	if finalConfig.History.File == "" {
+		return
+	}
+
+	var runID string
+	if auditLog != nil {
+		runID = auditLog.RunID()
+	} else {
+		id, err := history.NewRunID()
+		if err != nil {
+			output.SecureLogMessage(finalConfig, "ERROR", err.Error())
+			return
+		}
+		runID = id
+	}
+
+	record := history.NewRecord(runID, startTime, metrics.TotalDuration, results)
+	if err := history.Append(finalConfig.History.File, record); err != nil {
+		output.SecureLogMessage(finalConfig, "ERROR", err.Error())
+		return
+	}
+	output.SecureLogMessage(finalConfig, "INFO", fmt.Sprintf("Recorded run %s in %s", runID, finalConfig.History.File))
+}
+
+// runCompare compares the latest run of the history file to its baseline runs and
+// returns the process exit code, which is non-zero if anything regressed
+func runCompare(finalConfig *config.Config) int {
+	if finalConfig.History.File == "" {
+		log.Fatalf("No history file configured")
+	}
+
+	records, err := history.Load(finalConfig.History.File)
+	if err != nil {
+		log.Fatalf("Failed to load history: %v", err)
+	}
+
+	comparison, err := history.Compare(records, history.Thresholds{
+		BaselineRuns:     finalConfig.History.BaselineRuns,
+		DurationPercent:  finalConfig.History.DurationThresholdPercent,
+		SizePercent:      finalConfig.History.SizeThresholdPercent,
+		MinDurationDelta: finalConfig.History.MinDurationDelta,
+	})
+	if err != nil {
+		log.Fatalf("Failed to compare runs: %v", err)
+	}
+
+	output.OutputComparison(comparison, finalConfig)
+	if comparison.HasRegressions() {
+		return 1
+	}
+	return 0
+}
+
+// openAuditLog verifies the configured audit log and records the start of the run.
+// It returns nil when no audit log is configured.
+func openAuditLog(finalConfig *config.Config) *audit.Log {
+	if finalConfig.AuditLog == "" {
+		return nil
+	}
+
+	auditLog, err := audit.Open(finalConfig.AuditLog)
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+
+	configHash, err := audit.HashConfig(finalConfig)
+	if err != nil {
+		log.Fatalf("Failed to hash configuration: %v", err)
+	}
+
+	if err := auditLog.Append(audit.Entry{Event: audit.EventRunStart, ConfigHash: configHash}); err != nil {
+		log.Fatalf("Failed to write audit log: %v", err)
+	}
+	return auditLog
+}
+
+// auditPulls returns a callback recording each pull in the audit log as it completes,
+// or nil when no audit log is configured. Write errors fail the run in finishAudit.
+func auditPulls(auditLog *audit.Log, finalConfig *config.Config) func(types.PullResult) {
+	if auditLog == nil {
+		return nil
+	}
+	return func(result types.PullResult) {
+		if err := auditLog.RecordPull(result); err != nil {
+			output.SecureLogMessage(finalConfig, "ERROR", fmt.Sprintf("Failed to write audit log: %v", err))
+		}
+	}
+}
+
+// auditWarmupPulls returns a callback recording each pull of a benchmark warmup iteration
+// in the audit log, marked as a warmup, or nil when no audit log is configured
+func auditWarmupPulls(auditLog *audit.Log, finalConfig *config.Config) func(types.PullResult) {
+	if auditLog == nil {
+		return nil
+	}
+	return func(result types.PullResult) {
+		if err := auditLog.RecordWarmupPull(result); err != nil {
+			output.SecureLogMessage(finalConfig, "ERROR", fmt.Sprintf("Failed to write audit log: %v", err))
+		}
+	}
+}
+
+// finishAudit records the pushes and the end of the run, releases the audit log and
+// returns the exit code, which is non-zero if the audit log could not be written
+func finishAudit(auditLog *audit.Log, results []types.PullResult, exitCode int, finalConfig *config.Config) int {
+	if auditLog == nil {
+		return exitCode
+	}
+	defer auditLog.Close()
+
+	outcome := audit.OutcomeSuccess
+	if exitCode != 0 {
+		outcome = audit.OutcomeFailure
+	}
+
+	err := auditLog.RecordPushes(results)
+	if err == nil {
+		err = auditLog.Append(audit.Entry{Event: audit.EventRunEnd, Outcome: outcome})
+	}
+	if err != nil {
+		output.SecureLogMessage(finalConfig, "ERROR", fmt.Sprintf("Failed to write audit log: %v", err))
+		return 1
+	}
+	return exitCode
+}
+
+// runVerifyAudit checks the hash chain of the configured audit log and returns the process exit code
+func runVerifyAudit(finalConfig *config.Config) int {
+	if finalConfig.AuditLog == "" {
+		log.Fatalf("No audit_log configured")
+	}
+
+	head, err := audit.Verify(finalConfig.AuditLog)
+	output.OutputAuditVerification(head, err, finalConfig)
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// runCheck compares local images to their remote tags without pulling and returns the process exit code
+func runCheck(finalConfig *config.Config) int {
+	ctx, cancel := context.WithTimeout(context.Background(), finalConfig.Timeout*2)
+	defer cancel()
+
+	gates := loadGates(finalConfig)
+
+	cli := connectDocker(ctx, finalConfig)
+	defer cli.Close()
+
+	images := loadImages(ctx, gates, finalConfig)
+
+	results := docker.CheckImages(ctx, cli, images, finalConfig)
+	output.OutputCheckResults(results, finalConfig)
+
+	// Exit with error code if any image could not be checked
+	for _, result := range results {
+		if result.Status == types.CheckStatusError {
+			return 1
+		}
+	}
+	return 0
+}
+
+// loadGates loads the image policy, signature keys and vulnerability feed. A gate that is
+// enabled but cannot be loaded is fatal, so a run never silently skips its checks.
+func loadGates(finalConfig *config.Config) docker.Gates {
+	gates, err := docker.LoadGates(finalConfig)
+	if err != nil {
+		log.Fatalf("Failed to load image checks: %v", err)
+	}
+	return gates
+}
+
+// loadImages loads, expands, pins and evaluates the configured images
+func loadImages(ctx context.Context, gates docker.Gates, finalConfig *config.Config) []types.ImageRef {
+	// Load container images from file
+	specs, err := docker.LoadContainerImages(finalConfig)
+	if err != nil {
+		log.Fatalf("Failed to load container images: %v", err)
+	}
+
+	// Expand tag selections from the registry
+	images, err := docker.ResolveImages(ctx, specs, finalConfig)
+	if err != nil {
+		log.Fatalf("Failed to resolve container images: %v", err)
+	}
+
+	// Pin images to their locked digests
+	if err := docker.ApplyLockfile(images, finalConfig); err != nil {
+		log.Fatalf("Failed to apply lockfile: %v", err)
+	}
+
+	// Evaluate the image policy before any pull
+	docker.ApplyPolicy(images, gates.Policy, finalConfig)
+
+	return images
+}
+
+// connectDocker creates a Docker client, verifies the daemon is reachable and
+// adds the daemon's insecure registries to the registry configuration
+func connectDocker(ctx context.Context, finalConfig *config.Config) *client.Client {
+	// Create Docker client
+	cli, err := docker.CreateDockerClient()
+	if err != nil {
+		log.Fatalf("Failed to create Docker client: %v", err)
+	}
+
+	// Test Docker connection
+	if _, err := cli.Ping(ctx); err != nil {
+		log.Fatalf("Cannot connect to Docker daemon: %v\nPlease ensure Docker is running and accessible.", err)
+	}
+	docker.ApplyDaemonRegistryConfig(ctx, cli, finalConfig)
+
+	return cli
 }